@@ -1,9 +1,33 @@
 package config
 
+import "time"
+
 const (
 	//gin监听地址和端口
 	ListenAddr = "0.0.0.0:9090"
-	KubeConfig = "C:\\Users\\init\\.kube\\config"
+	//允许建立websocket连接的前端地址，多个用逗号分隔，同源请求和非浏览器请求总是允许
+	AllowedOrigins = "http://localhost:8080"
+	//多集群kubeconfig配置，key为集群名，value为kubeconfig文件路径
+	KubeConfigs = `{"TST-1":"C:\\Users\\init\\.kube\\config"}`
 	//查看日志的行数
 	PodLogTailLine = 2000
+	//登录使用的身份后端，目前支持local
+	IdentityProvider = "local"
+	//local身份后端的用户文件，不存在时自动创建admin用户，初始密码写入同目录下权限为0600的<UserFile>.admin-password文件
+	UserFile = "users.json"
+	//jwt签名密钥，为空时启动时随机生成，重启后已签发的token失效
+	JWTSecret = ""
+	//token有效期
+	TokenExpire = 2 * time.Hour
+	//token签发后多久内可以刷新，超过后需要重新登录
+	TokenRefreshExpire = 24 * time.Hour
+	//授权策略文件，不存在时自动创建默认策略
+	RBACFile = "rbac.json"
+	//是否模拟当前用户访问集群，开启后以平台用户名和组作为k8s用户访问apiserver，由k8s rbac鉴权并记录审计日志
+	//kubeconfig中的用户需要有impersonate users和groups的权限，列表接口不再使用informer缓存
+	Impersonate = false
+	//审计记录的存储，目前支持file
+	AuditStore = "file"
+	//file审计存储的文件，每行一条json格式的记录
+	AuditFile = "audit.log"
 )
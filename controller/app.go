@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wonderivan/logger"
+	"k8s-platform/service"
+	"net/http"
+)
+
+var App app
+
+type app struct{}
+
+// 创建应用，一次创建deployment、service和可选的ingress
+func (a *app) CreateApp(ctx *gin.Context) {
+	appCreate := service.AppCreate{}
+	if err := ctx.ShouldBindJSON(&appCreate); err != nil {
+		logger.Error("绑定参数失败", err.Error())
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	//deployment和service由授权中间件校验，ingress是可选的，指定时再校验
+	if appCreate.Ingress != nil {
+		if err := service.RBAC.Authorize(currentUser(ctx), appCreate.Cluster, appCreate.Namespace, service.Ingress.Name, service.VerbCreate); err != nil {
+			ctx.JSON(http.StatusForbidden, gin.H{
+				"msg":  err.Error(),
+				"data": nil,
+			})
+			return
+		}
+	}
+	if err := service.App.CreateApp(ctx.Request.Context(), appCreate.Cluster, appCreate); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"msg":  "创建应用成功",
+		"data": nil,
+	})
+}
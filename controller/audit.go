@@ -0,0 +1,185 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/wonderivan/logger"
+	"k8s-platform/service"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var AuditLog auditLog
+
+type auditLog struct{}
+
+// 审计记录中保存的响应内容上限，流式响应只保留开头部分
+const maxAuditResponse = 4096
+
+// 审计中间件，对变更操作授权并记录审计日志，被拒绝和参数错误的请求同样记录
+// 参数与Authorize相同，没有资源类型时只记录不授权，由controller自行授权
+func Audit(verb string, kinds ...string) gin.HandlerFunc {
+	authorize := Authorize(verb, kinds...)
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		body, err := requestBody(ctx)
+		if err != nil {
+			logger.Error("读取请求参数失败", err)
+		}
+		writer := &auditWriter{ResponseWriter: ctx.Writer}
+		ctx.Writer = writer
+		authorize(ctx)
+		record := &service.AuditRecord{
+			Time:     start,
+			IP:       ctx.ClientIP(),
+			Action:   verb,
+			Method:   ctx.Request.Method,
+			Path:     ctx.Request.URL.Path,
+			Status:   writer.Status(),
+			Success:  writer.Status() < http.StatusBadRequest,
+			Duration: time.Since(start).Milliseconds(),
+		}
+		if claims := currentUser(ctx); claims != nil {
+			record.User = claims.Subject
+		}
+		var resolved []string
+		scope := new(requestScopeParams)
+		json.Unmarshal(body, scope)
+		record.Cluster = scope.Cluster
+		for i, kind := range kinds {
+			//解析失败时记录原始类型
+			if resolved, err := scope.resolve(kind); err == nil {
+				kind = resolved.Kind
+				if i == 0 {
+					record.Namespace = resolved.Namespace
+				}
+			}
+			resolved = append(resolved, kind)
+		}
+		if len(kinds) == 0 {
+			record.Namespace = scope.Namespace
+		}
+		record.Kind = strings.Join(resolved, ",")
+		//按解析后的资源类型脱敏，crd接口操作secret时同样脱敏
+		record.Payload = service.RedactPayload(body, resolved)
+		record.Name = auditName(body, kinds)
+		//json响应取msg，流式响应保留开头部分
+		resp := new(struct {
+			Msg string `json:"msg"`
+		})
+		if err = json.Unmarshal(writer.body.Bytes(), resp); err == nil {
+			record.Msg = resp.Msg
+		} else {
+			record.Msg = writer.body.String()
+		}
+		service.Audit.Record(record)
+	}
+}
+
+// 从请求参数中获取操作的资源名
+// 依次查找<资源类型>_name、name、node_name、node_names，都没有时取content中对象的名称
+func auditName(body []byte, kinds []string) string {
+	params := map[string]interface{}{}
+	if err := json.Unmarshal(body, &params); err != nil {
+		return ""
+	}
+	var keys []string
+	if len(kinds) > 0 {
+		keys = append(keys, kinds[0]+"_name")
+	}
+	for _, key := range append(keys, "name", "node_name") {
+		if name, ok := params[key].(string); ok && name != "" {
+			return name
+		}
+	}
+	if list, ok := params["node_names"].([]interface{}); ok {
+		names := make([]string, 0, len(list))
+		for _, item := range list {
+			if name, ok := item.(string); ok {
+				names = append(names, name)
+			}
+		}
+		return strings.Join(names, ",")
+	}
+	if content, ok := params["content"].(string); ok {
+		return service.ContentNames(content)
+	}
+	return ""
+}
+
+// 记录响应内容的ResponseWriter，只保留开头部分
+type auditWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *auditWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *auditWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *auditWriter) capture(data []byte) {
+	if remain := maxAuditResponse - w.body.Len(); remain > 0 {
+		if len(data) > remain {
+			data = data[:remain]
+		}
+		w.body.Write(data)
+	}
+}
+
+// 查询审计记录，按时间倒序分页，只返回用户有权限的命名空间的记录
+func (a *auditLog) List(ctx *gin.Context) {
+	params := new(struct {
+		User      string    `form:"user"`
+		Cluster   string    `form:"cluster"`
+		Namespace string    `form:"namespace"`
+		Kind      string    `form:"kind"`
+		Name      string    `form:"name"`
+		Action    string    `form:"action"`
+		Result    string    `form:"result"`
+		StartTime time.Time `form:"start_time" time_format:"2006-01-02T15:04:05Z07:00"`
+		EndTime   time.Time `form:"end_time" time_format:"2006-01-02T15:04:05Z07:00"`
+		Page      int       `form:"page"`
+		Limit     int       `form:"limit"`
+	})
+	if err := ctx.Bind(params); err != nil {
+		logger.Error("绑定参数失败", err.Error())
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	data, err := service.Audit.Query(&service.AuditQuery{
+		User:      params.User,
+		Cluster:   params.Cluster,
+		Namespace: params.Namespace,
+		Kind:      params.Kind,
+		Name:      params.Name,
+		Action:    params.Action,
+		Result:    params.Result,
+		StartTime: params.StartTime,
+		EndTime:   params.EndTime,
+		Allowed:   accessFilter(ctx),
+		Limit:     params.Limit,
+		Page:      params.Page,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"msg":  "获取审计记录成功",
+		"data": data,
+	})
+}
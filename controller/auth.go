@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wonderivan/logger"
+	"k8s-platform/service"
+	"net/http"
+	"strings"
+)
+
+var Auth auth
+
+type auth struct{}
+
+// 中间件保存当前用户使用的key
+const claimsKey = "claims"
+
+// 登录，返回jwt
+func (a *auth) Login(ctx *gin.Context) {
+	params := new(struct {
+		Username string `json:"username" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	})
+	if err := ctx.ShouldBindJSON(params); err != nil {
+		logger.Error("绑定参数失败", err.Error())
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	data, err := service.Auth.Login(params.Username, params.Password)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"msg":  "登录成功",
+		"data": data,
+	})
+}
+
+// 刷新token，请求头中携带当前token，已过期但在刷新期限内的token也可以刷新
+func (a *auth) Refresh(ctx *gin.Context) {
+	data, err := service.Auth.Refresh(requestToken(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"msg":  "刷新token成功",
+		"data": data,
+	})
+}
+
+// 获取当前登录用户
+func (a *auth) UserInfo(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{
+		"msg":  "获取用户信息成功",
+		"data": currentUser(ctx),
+	})
+}
+
+// 认证中间件，校验token并将用户信息保存到context中
+func (a *auth) JWTAuth(ctx *gin.Context) {
+	token := requestToken(ctx)
+	if token == "" {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"msg":  "请先登录",
+			"data": nil,
+		})
+		return
+	}
+	claims, err := service.Auth.Verify(token)
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	ctx.Set(claimsKey, claims)
+	//service层通过请求的context获取当前用户，用于模拟用户访问集群
+	ctx.Request = ctx.Request.WithContext(service.WithClaims(ctx.Request.Context(), claims))
+	ctx.Next()
+}
+
+// 可以通过token查询参数传递token的接口，websocket和EventSource无法设置请求头
+// 查询参数会出现在访问日志中，其他接口只接受请求头
+var queryTokenRoutes = map[string]bool{
+	"/api/k8s/pod/terminal":   true,
+	"/api/k8s/pod/log/stream": true,
+}
+
+// 从Authorization请求头获取token，queryTokenRoutes中的接口也支持token查询参数
+func requestToken(ctx *gin.Context) string {
+	if header := ctx.GetHeader("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		return strings.TrimPrefix(header, "Bearer ")
+	}
+	if queryTokenRoutes[ctx.FullPath()] {
+		return ctx.Query("token")
+	}
+	return ""
+}
+
+// 获取当前登录用户，只能在认证中间件之后调用
+func currentUser(ctx *gin.Context) *service.Claims {
+	claims, _ := ctx.MustGet(claimsKey).(*service.Claims)
+	return claims
+}
@@ -0,0 +1,19 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"k8s-platform/service"
+	"net/http"
+)
+
+var Cluster cluster
+
+type cluster struct{}
+
+// 获取已注册的集群列表，前端据此选择cluster参数
+func (c *cluster) GetClusters(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{
+		"msg":  "获取集群列表成功",
+		"data": service.K8s.GetClusters(),
+	})
+}
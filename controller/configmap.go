@@ -1,141 +0,0 @@
-package controller
-
-import (
-	"github.com/gin-gonic/gin"
-	"github.com/wonderivan/logger"
-	"k8s-platform/service"
-	"net/http"
-)
-
-var Configmap configmap
-
-type configmap struct{}
-
-// configmap列表支持过滤。排序。分页
-func (p *configmap) GetConfigmap(ctx *gin.Context) {
-	//匿名结构体用于定义入参,get请求为from格式其他请求为json格式
-	params := new(struct {
-		FilterName string `form:"filter_name"`
-		Namespace  string `form:"namespace"`
-		Page       int    `form:"page"`
-		Limit      int    `form:"limit"'`
-	})
-	//绑定参数给匿名结构体的属性赋值
-	//from格式使用ctx.bind方法
-	//json格式使用ctx.ShouldBind
-	if err := ctx.Bind(params); err != nil {
-		logger.Error("绑定参数失败", err.Error())
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"msg":  err.Error(),
-			"data": nil,
-		})
-		return
-	}
-	//调用service方法获取数据
-	data, err := service.Configmap.GetConfigmap(params.FilterName, params.Namespace, params.Limit, params.Page)
-	if err != nil {
-		logger.Error("获取数据错误" + err.Error())
-	}
-	ctx.JSON(http.StatusOK, gin.H{
-		"msg":  "获取configmap列表成功",
-		"data": data,
-	})
-
-}
-
-// Configmap详情
-func (p *configmap) GetConfigmapDetail(ctx *gin.Context) {
-	//匿名结构体用于定义入参,get请求为from格式其他请求为json格式
-	params := new(struct {
-		ConfigmapName string `from:"configmap_name"`
-		Namespace     string `form:"namespace"`
-	})
-	//绑定参数给匿名结构体的属性赋值
-	//from格式使用ctx.bind方法
-	//json格式使用ctx.ShouldBind
-	if err := ctx.Bind(params); err != nil {
-		logger.Error("绑定参数失败", err.Error())
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"msg":  err.Error(),
-			"data": nil,
-		})
-		return
-	}
-	//调用service方法获取数据
-	data, err := service.Configmap.GetConfigmapDetail(params.ConfigmapName, params.Namespace)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"msg":  err.Error(),
-			"data": data,
-		})
-
-	}
-	ctx.JSON(http.StatusOK, gin.H{
-		"msg":  "获取Configmap详情成功",
-		"data": data,
-	})
-
-}
-
-// 删除configmap
-func (p *configmap) DeleteConfigmap(ctx *gin.Context) {
-	//匿名结构体用于定义入参,get请求为from格式其他请求为json格式
-	params := new(struct {
-		ConfigmapName string `json:"configmap_name"`
-		Namespace     string `json:"namespace"`
-	})
-	//绑定参数给匿名结构体的属性赋值
-	//from格式使用ctx.bind方法
-	//json格式使用ctx.ShouldBind
-	if err := ctx.ShouldBindJSON(params); err != nil {
-		logger.Error("删除configmap失败", err.Error())
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"msg":  err.Error(),
-			"data": nil,
-		})
-		return
-	}
-	//调用service方法获取数据
-	err := service.Configmap.DeleteConfigmap(params.ConfigmapName, params.Namespace)
-	if err != nil {
-		logger.Error("删除configmap失败" + err.Error())
-	}
-	ctx.JSON(http.StatusOK, gin.H{
-		"msg":  "删除configmap成功",
-		"data": nil,
-	})
-
-}
-
-// 更新configmap
-func (p *configmap) UpdateConfigmap(ctx *gin.Context) {
-	//匿名结构体用于定义入参,get请求为from格式其他请求为json格式
-	params := new(struct {
-		Content   string `json:"content"`
-		Namespace string `json:"namespace"`
-	})
-	//绑定参数给匿名结构体的属性赋值
-	//from格式使用ctx.bind方法
-	//json格式使用ctx.ShouldBind
-	if err := ctx.ShouldBindJSON(params); err != nil {
-		logger.Error("绑定参数失败", err.Error())
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"msg":  err.Error(),
-			"data": nil,
-		})
-		return
-	}
-	//调用service方法获取数据
-	err := service.Configmap.UpdateConfigmap(params.Content, params.Namespace)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"msg":  "更新configmap失败",
-			"data": nil,
-		})
-	}
-	ctx.JSON(http.StatusOK, gin.H{
-		"msg":  "更新configmap成功",
-		"data": nil,
-	})
-
-}
@@ -0,0 +1,230 @@
+package controller
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/wonderivan/logger"
+	"k8s-platform/service"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"net/http"
+)
+
+var Crd crd
+
+type crd struct{}
+
+// 获取集群中所有的api资源类型，包括CRD
+func (c *crd) GetApiResources(ctx *gin.Context) {
+	//匿名结构体用于定义入参,get请求为from格式其他请求为json格式
+	params := new(struct {
+		Cluster string `form:"cluster"`
+	})
+	if err := ctx.Bind(params); err != nil {
+		logger.Error("绑定参数失败", err.Error())
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	data, err := service.Crd.GetApiResources(ctx.Request.Context(), params.Cluster)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"msg":  "获取api资源成功",
+		"data": data,
+	})
+}
+
+// 资源列表支持过滤。排序。分页
+func (c *crd) GetCrds(ctx *gin.Context) {
+	params := new(struct {
+		listParams
+		Group     string `form:"group"`
+		Version   string `form:"version"`
+		Resource  string `form:"resource"`
+		Namespace string `form:"namespace"`
+		Cluster   string `form:"cluster"`
+	})
+	if err := ctx.Bind(params); err != nil {
+		logger.Error("绑定参数失败", err.Error())
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	gvr := schema.GroupVersionResource{Group: params.Group, Version: params.Version, Resource: params.Resource}
+	data, err := service.Crd.GetCrds(ctx.Request.Context(), params.Cluster, gvr, params.Namespace, params.dataSelect(ctx))
+	if err != nil {
+		ctx.JSON(crdStatus(err), gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"msg":  "获取" + params.Resource + "列表成功",
+		"data": data,
+	})
+}
+
+// 资源详情
+func (c *crd) GetCrdDetail(ctx *gin.Context) {
+	params := new(struct {
+		Group     string `form:"group"`
+		Version   string `form:"version"`
+		Resource  string `form:"resource"`
+		Name      string `form:"name"`
+		Namespace string `form:"namespace"`
+		Cluster   string `form:"cluster"`
+	})
+	if err := ctx.Bind(params); err != nil {
+		logger.Error("绑定参数失败", err.Error())
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	gvr := schema.GroupVersionResource{Group: params.Group, Version: params.Version, Resource: params.Resource}
+	data, err := service.Crd.GetCrdDetail(ctx.Request.Context(), params.Cluster, gvr, params.Name, params.Namespace)
+	if err != nil {
+		ctx.JSON(crdStatus(err), gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"msg":  "获取" + params.Resource + "详情成功",
+		"data": data,
+	})
+}
+
+// 删除资源
+func (c *crd) DeleteCrd(ctx *gin.Context) {
+	params := new(struct {
+		Group     string `json:"group"`
+		Version   string `json:"version"`
+		Resource  string `json:"resource"`
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+		Cluster   string `json:"cluster"`
+	})
+	if err := ctx.ShouldBindJSON(params); err != nil {
+		logger.Error("绑定参数失败", err.Error())
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	gvr := schema.GroupVersionResource{Group: params.Group, Version: params.Version, Resource: params.Resource}
+	if err := service.Crd.DeleteCrd(ctx.Request.Context(), params.Cluster, gvr, params.Name, params.Namespace); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"msg":  "删除" + params.Resource + "成功",
+		"data": nil,
+	})
+}
+
+// 更新资源
+func (c *crd) UpdateCrd(ctx *gin.Context) {
+	params := new(struct {
+		Group     string `json:"group"`
+		Version   string `json:"version"`
+		Resource  string `json:"resource"`
+		Content   string `json:"content"`
+		Namespace string `json:"namespace"`
+		Cluster   string `json:"cluster"`
+		Apply     bool   `json:"apply"`
+		Force     bool   `json:"force"`
+		DryRun    bool   `json:"dry_run"`
+	})
+	if err := ctx.ShouldBindJSON(params); err != nil {
+		logger.Error("绑定参数失败", err.Error())
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	gvr := schema.GroupVersionResource{Group: params.Group, Version: params.Version, Resource: params.Resource}
+	data, err := service.Crd.UpdateCrd(ctx.Request.Context(), params.Cluster, gvr, params.Namespace, params.Content, service.UpdateOptions{
+		Apply:  params.Apply,
+		Force:  params.Force,
+		DryRun: params.DryRun,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	msg := "更新" + params.Resource + "成功"
+	if params.DryRun {
+		msg = "预览" + params.Resource + "更新成功"
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"msg":  msg,
+		"data": data,
+	})
+}
+
+// 对比线上对象和编辑后的内容，入参与更新接口相同
+func (c *crd) DiffCrd(ctx *gin.Context) {
+	params := new(struct {
+		Group     string `json:"group"`
+		Version   string `json:"version"`
+		Resource  string `json:"resource"`
+		Content   string `json:"content"`
+		Namespace string `json:"namespace"`
+		Cluster   string `json:"cluster"`
+	})
+	if err := ctx.ShouldBindJSON(params); err != nil {
+		logger.Error("绑定参数失败", err.Error())
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	gvr := schema.GroupVersionResource{Group: params.Group, Version: params.Version, Resource: params.Resource}
+	data, err := service.Crd.DiffCrd(ctx.Request.Context(), params.Cluster, gvr, params.Namespace, params.Content)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrDiffTooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		ctx.JSON(status, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"msg":  "对比" + params.Resource + "成功",
+		"data": data,
+	})
+}
+
+// 资源类型不存在时返回400，其他错误返回500
+func crdStatus(err error) int {
+	var unknown *service.UnknownResourceError
+	if errors.As(err, &unknown) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
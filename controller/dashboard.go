@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wonderivan/logger"
+	"k8s-platform/service"
+	"net/http"
+)
+
+var Dashboard dashboard
+
+type dashboard struct{}
+
+// 获取集群总览，namespace为空时返回所有命名空间的统计
+func (d *dashboard) GetDashboard(ctx *gin.Context) {
+	params := new(struct {
+		Namespace string `form:"namespace"`
+		Cluster   string `form:"cluster"`
+	})
+	if err := ctx.Bind(params); err != nil {
+		logger.Error("绑定参数失败", err.Error())
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	//节点信息是集群级的，只返回给有node列表权限的用户
+	withNodes := service.RBAC.Authorize(currentUser(ctx), params.Cluster, "", service.Node.Name, service.VerbList) == nil
+	data, err := service.Dashboard.GetDashboard(ctx.Request.Context(), params.Cluster, params.Namespace, accessFilter(ctx), withNodes)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"msg":  "获取集群总览成功",
+		"data": data,
+	})
+}
@@ -4,194 +4,201 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/wonderivan/logger"
 	"k8s-platform/service"
+	appsv1 "k8s.io/api/apps/v1"
 	"net/http"
 )
 
-var Deployment deployment
+// deployment的列表、详情、删除、更新、创建由通用资源控制器提供
+var Deployment = deployment{newResource(service.Deployment.Resource)}
 
-type deployment struct{}
+type deployment struct {
+	*resource[appsv1.Deployment, *appsv1.Deployment]
+}
 
-// deloyment列表支持过滤。排序。分页
-func (p *deployment) GetDeployment(ctx *gin.Context) {
+// 修改deployment副本数
+func (p *deployment) ScaleDeployment(ctx *gin.Context) {
 	//匿名结构体用于定义入参,get请求为from格式其他请求为json格式
 	params := new(struct {
-		FilterName string `form:"filter_name"`
-		Namespace  string `form:"namespace"`
-		Page       int    `form:"page"`
-		Limit      int    `form:"limit"'`
+		ScaleNum       int    `json:"scale_num"`
+		DeploymentName string `json:"deployment_name"`
+		Namespace      string `json:"namespace"`
+		Cluster        string `json:"cluster"`
 	})
 	//绑定参数给匿名结构体的属性赋值
 	//from格式使用ctx.bind方法
 	//json格式使用ctx.ShouldBind
-	if err := ctx.Bind(params); err != nil {
+	if err := ctx.ShouldBindJSON(params); err != nil {
 		logger.Error("绑定参数失败", err.Error())
-		ctx.JSON(http.StatusBadRequest, gin.H{
+		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"msg":  err.Error(),
 			"data": nil,
 		})
 		return
 	}
 	//调用service方法获取数据
-	data, err := service.Deployment.GetDeployments(params.FilterName, params.Namespace, params.Limit, params.Page)
+	replicas, err := service.Deployment.ScaleDeployment(ctx.Request.Context(), params.Cluster, params.DeploymentName, params.Namespace, params.ScaleNum)
 	if err != nil {
-		logger.Error("获取数据错误" + err.Error())
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": replicas,
+		})
+		return
 	}
 	ctx.JSON(http.StatusOK, gin.H{
-		"msg":  "获取deloyment列表成功",
-		"data": data,
+		"msg":  "修改deployment成功",
+		"data": replicas,
 	})
+	return
 
 }
 
-// Deloyment详情
-func (p *deployment) GetDeloymentDetail(ctx *gin.Context) {
+// 重启deployment
+func (p *deployment) RestartDeployment(ctx *gin.Context) {
 	//匿名结构体用于定义入参,get请求为from格式其他请求为json格式
 	params := new(struct {
-		DeloymentName string `form:"deloyment_name"`
-		Namespace     string `form:"namespace"`
+		DeploymentName string `json:"deployment_name"`
+		Namespace      string `json:"namespace"`
+		Cluster        string `json:"cluster"`
 	})
 	//绑定参数给匿名结构体的属性赋值
 	//from格式使用ctx.bind方法
 	//json格式使用ctx.ShouldBind
-	if err := ctx.Bind(params); err != nil {
+	if err := ctx.ShouldBindJSON(params); err != nil {
 		logger.Error("绑定参数失败", err.Error())
-		ctx.JSON(http.StatusBadRequest, gin.H{
+		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"msg":  err.Error(),
 			"data": nil,
 		})
 		return
 	}
 	//调用service方法获取数据
-	data, err := service.Deployment.GetDeploymentDetail(params.DeloymentName, params.Namespace)
+	err := service.Deployment.RestartDeployment(ctx.Request.Context(), params.Cluster, params.DeploymentName, params.Namespace)
 	if err != nil {
-		logger.Error("获取deloyment详情错误" + err.Error())
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
 	}
 	ctx.JSON(http.StatusOK, gin.H{
-		"msg":  "获取deloyment详情成功",
-		"data": data,
+		"msg":  "重启deployment成功",
+		"data": nil,
 	})
+	return
 
 }
 
-// 删除deloyment
-func (p *deployment) DeleteDeloyment(ctx *gin.Context) {
+// 创建deployment
+func (p *deployment) CreateDeployment(ctx *gin.Context) {
 	//匿名结构体用于定义入参,get请求为from格式其他请求为json格式
-	params := new(struct {
-		DeloymentName string `json:"deloyment_name"`
-		Namespace     string `json:"namespace"`
-	})
+	deployCreate := service.DeployCreate{}
 	//绑定参数给匿名结构体的属性赋值
 	//from格式使用ctx.bind方法
 	//json格式使用ctx.ShouldBind
-	if err := ctx.ShouldBindJSON(params); err != nil {
-		logger.Error("删除deloyment失败", err.Error())
-		ctx.JSON(http.StatusInternalServerError, gin.H{
+	if err := ctx.ShouldBindJSON(&deployCreate); err != nil {
+		logger.Error("绑定参数失败", err.Error())
+		ctx.JSON(http.StatusBadRequest, gin.H{
 			"msg":  err.Error(),
 			"data": nil,
 		})
 		return
 	}
 	//调用service方法获取数据
-	err := service.Deployment.DeleteDeployment(params.DeloymentName, params.Namespace)
+	err := service.Deployment.CreateDeployment(ctx.Request.Context(), deployCreate.Cluster, deployCreate)
 	if err != nil {
-		logger.Error("删除deloyment失败" + err.Error())
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
 	}
 	ctx.JSON(http.StatusOK, gin.H{
-		"msg":  "删除deloyment成功",
+		"msg":  "创建deployment成功",
 		"data": nil,
 	})
+	return
 
 }
 
-// 更新deloyment
-func (p *deployment) UpdateDeloyment(ctx *gin.Context) {
+// 获取deloyment每个名称空间数量
+func (p *deployment) GetDeloymentNumPerNs(ctx *gin.Context) {
 	//匿名结构体用于定义入参,get请求为from格式其他请求为json格式
 	params := new(struct {
-		Content   string `json:"content"`
-		Namespace string `json:"namespace"`
+		Cluster string `form:"cluster"`
 	})
 	//绑定参数给匿名结构体的属性赋值
 	//from格式使用ctx.bind方法
 	//json格式使用ctx.ShouldBind
-	if err := ctx.ShouldBindJSON(params); err != nil {
+	if err := ctx.Bind(params); err != nil {
 		logger.Error("绑定参数失败", err.Error())
-		ctx.JSON(http.StatusInternalServerError, gin.H{
+		ctx.JSON(http.StatusBadRequest, gin.H{
 			"msg":  err.Error(),
 			"data": nil,
 		})
 		return
 	}
 	//调用service方法获取数据
-	err := service.Deployment.UpdateDeployment(params.Content, params.Namespace)
+	data, err := service.Deployment.GetDeploymentNumPerNs(ctx.Request.Context(), params.Cluster, accessFilter(ctx))
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"msg":  "更新deployment失败",
+			"msg":  err.Error(),
 			"data": nil,
 		})
+		return
 	}
 	ctx.JSON(http.StatusOK, gin.H{
-		"msg":  "更新deloyment成功",
-		"data": nil,
+		"msg":  "获取deloyment数量成功",
+		"data": data,
 	})
 
 }
 
-// 修改deployment副本数
-func (p *deployment) ScaleDeployment(ctx *gin.Context) {
-	//匿名结构体用于定义入参,get请求为from格式其他请求为json格式
+// 获取deployment的历史版本
+func (p *deployment) GetRolloutHistory(ctx *gin.Context) {
 	params := new(struct {
-		ScaleNum       int    `json:"scale_num"`
-		DeploymentName string `json:"deployment_name"`
-		Namespace      string `json:"namespace"`
+		DeploymentName string `form:"deployment_name"`
+		Namespace      string `form:"namespace"`
+		Cluster        string `form:"cluster"`
 	})
-	//绑定参数给匿名结构体的属性赋值
-	//from格式使用ctx.bind方法
-	//json格式使用ctx.ShouldBind
-	if err := ctx.ShouldBindJSON(params); err != nil {
+	if err := ctx.Bind(params); err != nil {
 		logger.Error("绑定参数失败", err.Error())
-		ctx.JSON(http.StatusInternalServerError, gin.H{
+		ctx.JSON(http.StatusBadRequest, gin.H{
 			"msg":  err.Error(),
 			"data": nil,
 		})
 		return
 	}
-	//调用service方法获取数据
-	replicas, err := service.Deployment.ScaleDeployment(params.DeploymentName, params.Namespace, params.ScaleNum)
+	data, err := service.Deployment.GetRolloutHistory(ctx.Request.Context(), params.Cluster, params.DeploymentName, params.Namespace)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"msg":  err.Error(),
-			"data": replicas,
+			"data": nil,
 		})
 		return
 	}
-	ctx.JSON(http.StatusInternalServerError, gin.H{
-		"msg":  "修改deployment成功",
-		"data": replicas,
+	ctx.JSON(http.StatusOK, gin.H{
+		"msg":  "获取deployment历史版本成功",
+		"data": data,
 	})
-	return
-
 }
 
-// 重启deployment
-func (p *deployment) RestartDeployment(ctx *gin.Context) {
-	//匿名结构体用于定义入参,get请求为from格式其他请求为json格式
+// 回滚deployment，revision为0时回滚到上一个版本
+func (p *deployment) RollbackDeployment(ctx *gin.Context) {
 	params := new(struct {
 		DeploymentName string `json:"deployment_name"`
 		Namespace      string `json:"namespace"`
+		Revision       int64  `json:"revision"`
+		Cluster        string `json:"cluster"`
 	})
-	//绑定参数给匿名结构体的属性赋值
-	//from格式使用ctx.bind方法
-	//json格式使用ctx.ShouldBind
 	if err := ctx.ShouldBindJSON(params); err != nil {
 		logger.Error("绑定参数失败", err.Error())
-		ctx.JSON(http.StatusInternalServerError, gin.H{
+		ctx.JSON(http.StatusBadRequest, gin.H{
 			"msg":  err.Error(),
 			"data": nil,
 		})
 		return
 	}
-	//调用service方法获取数据
-	err := service.Deployment.RestartDeployment(params.DeploymentName, params.Namespace)
+	err := service.Deployment.RollbackDeployment(ctx.Request.Context(), params.Cluster, params.DeploymentName, params.Namespace, params.Revision)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"msg":  err.Error(),
@@ -199,51 +206,69 @@ func (p *deployment) RestartDeployment(ctx *gin.Context) {
 		})
 		return
 	}
-	ctx.JSON(http.StatusInternalServerError, gin.H{
-		"msg":  "重启deployment成功",
+	ctx.JSON(http.StatusOK, gin.H{
+		"msg":  "回滚deployment成功",
 		"data": nil,
 	})
-	return
+}
 
+// 暂停deployment的滚动更新
+func (p *deployment) PauseDeployment(ctx *gin.Context) {
+	p.setPaused(ctx, true)
 }
 
-// 创建deployment
-func (p *deployment) CreateDeployment(ctx *gin.Context) {
-	//匿名结构体用于定义入参,get请求为from格式其他请求为json格式
-	deployCreate := service.DeployCreate{}
-	//绑定参数给匿名结构体的属性赋值
-	//from格式使用ctx.bind方法
-	//json格式使用ctx.ShouldBind
-	if err := ctx.ShouldBindJSON(deployCreate); err != nil {
+// 恢复deployment的滚动更新
+func (p *deployment) ResumeDeployment(ctx *gin.Context) {
+	p.setPaused(ctx, false)
+}
+
+func (p *deployment) setPaused(ctx *gin.Context, paused bool) {
+	params := new(struct {
+		DeploymentName string `json:"deployment_name"`
+		Namespace      string `json:"namespace"`
+		Cluster        string `json:"cluster"`
+	})
+	if err := ctx.ShouldBindJSON(params); err != nil {
 		logger.Error("绑定参数失败", err.Error())
-		ctx.JSON(http.StatusInternalServerError, gin.H{
+		ctx.JSON(http.StatusBadRequest, gin.H{
 			"msg":  err.Error(),
 			"data": nil,
 		})
 		return
 	}
-	//调用service方法获取数据
-	err := service.Deployment.CreateDeployment(deployCreate)
-	if err != nil {
+	action, set := "恢复", service.Deployment.ResumeDeployment
+	if paused {
+		action, set = "暂停", service.Deployment.PauseDeployment
+	}
+	if err := set(ctx.Request.Context(), params.Cluster, params.DeploymentName, params.Namespace); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"msg":  err.Error(),
 			"data": nil,
 		})
 		return
 	}
-	ctx.JSON(http.StatusInternalServerError, gin.H{
-		"msg":  "创建deployment成功",
+	ctx.JSON(http.StatusOK, gin.H{
+		"msg":  action + "deployment成功",
 		"data": nil,
 	})
-	return
-
 }
 
-// 获取deloyment每个名称空间数量
-func (p *deployment) GetDeloymentNumPerNs(ctx *gin.Context) {
-
-	//调用service方法获取数据
-	data, err := service.Deployment.GetDeploymentNumPerNs()
+// 获取deployment的滚动更新状态
+func (p *deployment) GetRolloutStatus(ctx *gin.Context) {
+	params := new(struct {
+		DeploymentName string `form:"deployment_name"`
+		Namespace      string `form:"namespace"`
+		Cluster        string `form:"cluster"`
+	})
+	if err := ctx.Bind(params); err != nil {
+		logger.Error("绑定参数失败", err.Error())
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	data, err := service.Deployment.GetRolloutStatus(ctx.Request.Context(), params.Cluster, params.DeploymentName, params.Namespace)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"msg":  err.Error(),
@@ -252,8 +277,7 @@ func (p *deployment) GetDeloymentNumPerNs(ctx *gin.Context) {
 		return
 	}
 	ctx.JSON(http.StatusOK, gin.H{
-		"msg":  "获取deloyment数量成功",
+		"msg":  "获取deployment滚动更新状态成功",
 		"data": data,
 	})
-
 }
@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wonderivan/logger"
+	"k8s-platform/service"
+	"net/http"
+)
+
+var Event event
+
+type event struct{}
+
+// 获取事件列表，namespace为空时获取所有命名空间，支持按类型、原因和关联对象过滤
+func (e *event) GetEvents(ctx *gin.Context) {
+	params := new(struct {
+		Cluster   string `form:"cluster"`
+		Namespace string `form:"namespace"`
+		Type      string `form:"type"`
+		Reason    string `form:"reason"`
+		Kind      string `form:"kind"`
+		Name      string `form:"name"`
+		Page      int    `form:"page"`
+		Limit     int    `form:"limit"`
+	})
+	if err := ctx.Bind(params); err != nil {
+		logger.Error("绑定参数失败", err.Error())
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	data, err := service.Event.GetEvents(ctx.Request.Context(), params.Cluster, params.Namespace, &service.EventQuery{
+		Type:   params.Type,
+		Reason: params.Reason,
+		Kind:   params.Kind,
+		Name:   params.Name,
+		Limit:  params.Limit,
+		Page:   params.Page,
+	}, accessFilter(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"msg":  "获取事件列表成功",
+		"data": data,
+	})
+}
@@ -1,140 +0,0 @@
-package controller
-
-import (
-	"github.com/gin-gonic/gin"
-	"github.com/wonderivan/logger"
-	"k8s-platform/service"
-	"net/http"
-)
-
-var Ingress ingress
-
-type ingress struct{}
-
-func (p *ingress) GetIngress(ctx *gin.Context) {
-	//匿名结构体用于定义入参,get请求为from格式其他请求为json格式
-	params := new(struct {
-		FilterName string `form:"filter_name"`
-		Namespace  string `form:"namespace"`
-		Page       int    `form:"page"`
-		Limit      int    `form:"limit"'`
-	})
-	//绑定参数给匿名结构体的属性赋值
-	//from格式使用ctx.bind方法
-	//json格式使用ctx.ShouldBind
-	if err := ctx.Bind(params); err != nil {
-		logger.Error("绑定参数失败", err.Error())
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"msg":  err.Error(),
-			"data": nil,
-		})
-		return
-	}
-	//调用service方法获取数据
-	data, err := service.Ingress.GetIngress(params.FilterName, params.Namespace, params.Limit, params.Page)
-	if err != nil {
-		logger.Error("获取数据错误" + err.Error())
-	}
-	ctx.JSON(http.StatusOK, gin.H{
-		"msg":  "获取ingress列表成功",
-		"data": data,
-	})
-
-}
-
-// ingress详情
-func (p *ingress) GetIngressDetail(ctx *gin.Context) {
-	//匿名结构体用于定义入参,get请求为from格式其他请求为json格式
-	params := new(struct {
-		IngressName string `from:"ingress_name"`
-		Namespace   string `form:"namespace"`
-	})
-	//绑定参数给匿名结构体的属性赋值
-	//from格式使用ctx.bind方法
-	//json格式使用ctx.ShouldBind
-	if err := ctx.Bind(params); err != nil {
-		logger.Error("绑定参数失败", err.Error())
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"msg":  err.Error(),
-			"data": nil,
-		})
-		return
-	}
-	//调用service方法获取数据
-	data, err := service.Ingress.GetIngressDetail(params.IngressName, params.Namespace)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"msg":  err.Error(),
-			"data": data,
-		})
-
-	}
-	ctx.JSON(http.StatusOK, gin.H{
-		"msg":  "获取Ingress详情成功",
-		"data": data,
-	})
-
-}
-
-// 删除ingress
-func (p *ingress) DeleteIngress(ctx *gin.Context) {
-	//匿名结构体用于定义入参,get请求为from格式其他请求为json格式
-	params := new(struct {
-		IngressName string `json:"ingress_name"`
-		Namespace   string `json:"namespace"`
-	})
-	//绑定参数给匿名结构体的属性赋值
-	//from格式使用ctx.bind方法
-	//json格式使用ctx.ShouldBind
-	if err := ctx.ShouldBindJSON(params); err != nil {
-		logger.Error("删除ingress失败", err.Error())
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"msg":  err.Error(),
-			"data": nil,
-		})
-		return
-	}
-	//调用service方法获取数据
-	err := service.Ingress.DeleteIngress(params.IngressName, params.Namespace)
-	if err != nil {
-		logger.Error("删除ingress失败" + err.Error())
-	}
-	ctx.JSON(http.StatusOK, gin.H{
-		"msg":  "删除ingress成功",
-		"data": nil,
-	})
-
-}
-
-// 更新ingress
-func (p *ingress) UpdateIngress(ctx *gin.Context) {
-	//匿名结构体用于定义入参,get请求为from格式其他请求为json格式
-	params := new(struct {
-		Content   string `json:"content"`
-		Namespace string `json:"namespace"`
-	})
-	//绑定参数给匿名结构体的属性赋值
-	//from格式使用ctx.bind方法
-	//json格式使用ctx.ShouldBind
-	if err := ctx.ShouldBindJSON(params); err != nil {
-		logger.Error("绑定参数失败", err.Error())
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"msg":  err.Error(),
-			"data": nil,
-		})
-		return
-	}
-	//调用service方法获取数据
-	err := service.Ingress.UpdateIngress(params.Content, params.Namespace)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"msg":  "更新ingress失败",
-			"data": nil,
-		})
-	}
-	ctx.JSON(http.StatusOK, gin.H{
-		"msg":  "更新ingress成功",
-		"data": nil,
-	})
-
-}
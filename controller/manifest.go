@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wonderivan/logger"
+	"k8s-platform/service"
+	"net/http"
+)
+
+var Manifest manifest
+
+type manifest struct{}
+
+// 按顺序创建清单中的所有对象，支持多文档yaml或json，返回每个对象的创建结果
+func (m *manifest) CreateManifest(ctx *gin.Context) {
+	params := new(struct {
+		Content   string `json:"content"`
+		Namespace string `json:"namespace"`
+		Cluster   string `json:"cluster"`
+	})
+	if err := ctx.ShouldBindJSON(params); err != nil {
+		logger.Error("绑定参数失败", err.Error())
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	//清单中的对象类型和命名空间在解析后才能确定，逐个校验创建权限
+	claims := currentUser(ctx)
+	data, err := service.Manifest.CreateManifest(ctx.Request.Context(), params.Cluster, params.Namespace, params.Content, func(kind, namespace string) error {
+		return service.RBAC.Authorize(claims, params.Cluster, namespace, kind, service.VerbCreate)
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	//全部成功返回200，部分失败返回207，全部失败返回500，都带有每个对象的结果
+	succeeded := 0
+	for _, result := range data {
+		if result.Success {
+			succeeded++
+		}
+	}
+	status, msg := http.StatusOK, "创建清单成功"
+	switch {
+	case succeeded == 0:
+		status, msg = http.StatusInternalServerError, "清单中的资源全部创建失败"
+	case succeeded < len(data):
+		status, msg = http.StatusMultiStatus, "清单中部分资源创建失败"
+	}
+	ctx.JSON(status, gin.H{
+		"msg":  msg,
+		"data": data,
+	})
+}
@@ -1,105 +0,0 @@
-package controller
-
-import (
-	"github.com/gin-gonic/gin"
-	"github.com/wonderivan/logger"
-	"k8s-platform/service"
-	"net/http"
-)
-
-var Namespace namespace
-
-type namespace struct{}
-
-func (p *namespace) GetNamespace(ctx *gin.Context) {
-	//匿名结构体用于定义入参,get请求为from格式其他请求为json格式
-	params := new(struct {
-		FilterName string `form:"filter_name"`
-		Page       int    `form:"page"`
-		Limit      int    `form:"limit"'`
-	})
-	//绑定参数给匿名结构体的属性赋值
-	//from格式使用ctx.bind方法
-	//json格式使用ctx.ShouldBind
-	if err := ctx.Bind(params); err != nil {
-		logger.Error("绑定参数失败", err.Error())
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"msg":  err.Error(),
-			"data": nil,
-		})
-		return
-	}
-	//调用service方法获取数据
-	data, err := service.Namespace.GetNamespace(params.FilterName, params.Limit, params.Page)
-	if err != nil {
-		logger.Error("获取数据错误" + err.Error())
-	}
-	ctx.JSON(http.StatusOK, gin.H{
-		"msg":  "获取namespace列表成功",
-		"data": data,
-	})
-
-}
-
-// namepsace详情
-func (p *namespace) GetNamespaceDetail(ctx *gin.Context) {
-	//匿名结构体用于定义入参,get请求为from格式其他请求为json格式
-	params := new(struct {
-		NamespaceName string `from:"namespace_name"`
-		Namespace     string `form:"namespace"`
-	})
-	//绑定参数给匿名结构体的属性赋值
-	//from格式使用ctx.bind方法
-	//json格式使用ctx.ShouldBind
-	if err := ctx.Bind(params); err != nil {
-		logger.Error("绑定参数失败", err.Error())
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"msg":  err.Error(),
-			"data": nil,
-		})
-		return
-	}
-	//调用service方法获取数据
-	data, err := service.Namespace.GetNamespaceDetail(params.Namespace)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"msg":  err.Error(),
-			"data": data,
-		})
-
-	}
-	ctx.JSON(http.StatusOK, gin.H{
-		"msg":  "获取Namespace详情成功",
-		"data": data,
-	})
-
-}
-
-// 删除namepsace
-func (p *namespace) DeleteNamespace(ctx *gin.Context) {
-	//匿名结构体用于定义入参,get请求为from格式其他请求为json格式
-	params := new(struct {
-		NamespaceName string `json:"namespace_name"`
-	})
-	//绑定参数给匿名结构体的属性赋值
-	//from格式使用ctx.bind方法
-	//json格式使用ctx.ShouldBind
-	if err := ctx.ShouldBindJSON(params); err != nil {
-		logger.Error("删除deloyment失败", err.Error())
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"msg":  err.Error(),
-			"data": nil,
-		})
-		return
-	}
-	//调用service方法获取数据
-	err := service.Namespace.DeleteNamespace(params.NamespaceName)
-	if err != nil {
-		logger.Error("删除namespace失败" + err.Error())
-	}
-	ctx.JSON(http.StatusOK, gin.H{
-		"msg":  "删除namespace成功",
-		"data": nil,
-	})
-
-}
@@ -3,26 +3,55 @@ package controller
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/wonderivan/logger"
+	"io"
 	"k8s-platform/service"
+	corev1 "k8s.io/api/core/v1"
 	"net/http"
 )
 
-var Node node
+// node的列表、更新由通用资源控制器提供
+var Node = node{newResource(service.Node.Resource)}
 
-type node struct{}
+type node struct {
+	*resource[corev1.Node, *corev1.Node]
+}
+
+// node详情，包括节点上的pod、资源分配、状态和事件
+func (n *node) Detail(ctx *gin.Context) {
+	params, ok := n.mustBind(ctx)
+	if !ok {
+		return
+	}
+	data, err := service.Node.GetNodeDetail(ctx.Request.Context(), params.Cluster, params.Name)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"msg":  "获取node详情成功",
+		"data": data,
+	})
+}
+
+// 禁止调度
+func (n *node) CordonNode(ctx *gin.Context) {
+	n.setUnschedulable(ctx, true)
+}
+
+// 恢复调度
+func (n *node) UncordonNode(ctx *gin.Context) {
+	n.setUnschedulable(ctx, false)
+}
 
-// deloyment列表支持过滤。排序。分页
-func (p *node) GetNode(ctx *gin.Context) {
-	//匿名结构体用于定义入参,get请求为from格式其他请求为json格式
+func (n *node) setUnschedulable(ctx *gin.Context, unschedulable bool) {
 	params := new(struct {
-		FilterName string `form:"filter_name"`
-		Page       int    `form:"page"`
-		Limit      int    `form:"limit"'`
+		NodeName string `json:"node_name"`
+		Cluster  string `json:"cluster"`
 	})
-	//绑定参数给匿名结构体的属性赋值
-	//from格式使用ctx.bind方法
-	//json格式使用ctx.ShouldBind
-	if err := ctx.Bind(params); err != nil {
+	if err := ctx.ShouldBindJSON(params); err != nil {
 		logger.Error("绑定参数失败", err.Error())
 		ctx.JSON(http.StatusBadRequest, gin.H{
 			"msg":  err.Error(),
@@ -30,28 +59,67 @@ func (p *node) GetNode(ctx *gin.Context) {
 		})
 		return
 	}
-	//调用service方法获取数据
-	data, err := service.Node.GetNodes(params.FilterName, params.Limit, params.Page)
+	var err error
+	msg := "禁止调度成功"
+	if unschedulable {
+		err = service.Node.CordonNode(ctx.Request.Context(), params.Cluster, params.NodeName)
+	} else {
+		err = service.Node.UncordonNode(ctx.Request.Context(), params.Cluster, params.NodeName)
+		msg = "恢复调度成功"
+	}
 	if err != nil {
-		logger.Error("获取数据错误" + err.Error())
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
 	}
 	ctx.JSON(http.StatusOK, gin.H{
-		"msg":  "获取node列表成功",
-		"data": data,
+		"msg":  msg,
+		"data": nil,
 	})
-
 }
 
-// Deloyment详情
-func (p *node) GetNodeDetail(ctx *gin.Context) {
-	//匿名结构体用于定义入参,get请求为from格式其他请求为json格式
-	params := new(struct {
-		NodeName string `from:"node_name"`
+// 驱逐node上的pod，每个pod的进度推送一个progress事件，全部完成后推送end事件
+// end事件的数据为驱逐失败的pod数
+func (n *node) DrainNode(ctx *gin.Context) {
+	params := new(service.NodeDrain)
+	if err := ctx.ShouldBindJSON(params); err != nil {
+		logger.Error("绑定参数失败", err.Error())
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	//使用请求的context，客户端断开后停止驱逐
+	progress, err := service.Node.DrainNode(ctx.Request.Context(), params.Cluster, *params)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	failed := 0
+	ctx.Stream(func(w io.Writer) bool {
+		item, ok := <-progress
+		if !ok {
+			ctx.SSEvent("end", failed)
+			return false
+		}
+		if item.Status == service.DrainFailed {
+			failed++
+		}
+		ctx.SSEvent("progress", item)
+		return true
 	})
-	//绑定参数给匿名结构体的属性赋值
-	//from格式使用ctx.bind方法
-	//json格式使用ctx.ShouldBind
-	if err := ctx.Bind(params); err != nil {
+}
+
+// 批量修改node的标签，返回每个node的修改结果
+func (n *node) UpdateNodeLabels(ctx *gin.Context) {
+	params := new(service.NodeLabels)
+	if err := ctx.ShouldBindJSON(params); err != nil {
 		logger.Error("绑定参数失败", err.Error())
 		ctx.JSON(http.StatusBadRequest, gin.H{
 			"msg":  err.Error(),
@@ -59,46 +127,41 @@ func (p *node) GetNodeDetail(ctx *gin.Context) {
 		})
 		return
 	}
-	//调用service方法获取数据
-	data, err := service.Node.GetNodeDetail(params.NodeName)
+	data, err := service.Node.UpdateNodeLabels(ctx.Request.Context(), params.Cluster, *params)
 	if err != nil {
-		logger.Error("获取Node详情错误" + err.Error())
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
 	}
 	ctx.JSON(http.StatusOK, gin.H{
-		"msg":  "获取Node详情成功",
+		"msg":  "修改node标签完成",
 		"data": data,
 	})
-
 }
 
-// 更新node
-func (p *node) UpdateNode(ctx *gin.Context) {
-	//匿名结构体用于定义入参,get请求为from格式其他请求为json格式
-	params := new(struct {
-		Content string `json:"content"`
-	})
-	//绑定参数给匿名结构体的属性赋值
-	//from格式使用ctx.bind方法
-	//json格式使用ctx.ShouldBind
+// 批量修改node的污点，返回每个node的修改结果
+func (n *node) UpdateNodeTaints(ctx *gin.Context) {
+	params := new(service.NodeTaints)
 	if err := ctx.ShouldBindJSON(params); err != nil {
 		logger.Error("绑定参数失败", err.Error())
-		ctx.JSON(http.StatusInternalServerError, gin.H{
+		ctx.JSON(http.StatusBadRequest, gin.H{
 			"msg":  err.Error(),
 			"data": nil,
 		})
 		return
 	}
-	//调用service方法获取数据
-	err := service.Node.UpdateNode(params.Content)
+	data, err := service.Node.UpdateNodeTaints(ctx.Request.Context(), params.Cluster, *params)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"msg":  "更新node失败",
+			"msg":  err.Error(),
 			"data": nil,
 		})
+		return
 	}
 	ctx.JSON(http.StatusOK, gin.H{
-		"msg":  "更新node成功",
-		"data": nil,
+		"msg":  "修改node污点完成",
+		"data": data,
 	})
-
 }
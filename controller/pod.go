@@ -1,178 +1,142 @@
 package controller
 
 import (
+	"bufio"
 	"github.com/gin-gonic/gin"
 	"github.com/wonderivan/logger"
+	"io"
 	"k8s-platform/service"
+	corev1 "k8s.io/api/core/v1"
 	"net/http"
 )
 
-var Pod pod
+// pod的列表、详情、删除、更新、创建由通用资源控制器提供
+var Pod = pod{newResource(service.Pod.Resource)}
 
-type pod struct{}
+type pod struct {
+	*resource[corev1.Pod, *corev1.Pod]
+}
 
-// pod列表支持过滤。排序。分页
-func (p *pod) GetPods(ctx *gin.Context) {
+// 获取pod的容器名
+func (p *pod) GetPodContainer(ctx *gin.Context) {
 	//匿名结构体用于定义入参,get请求为from格式其他请求为json格式
 	params := new(struct {
-		FilterName string `form:"filter_name"`
-		Namespace  string `form:"namespace"`
-		Page       int    `form:"page"`
-		Limit      int    `form:"limit"'`
+		PodName   string `form:"pod_name"`
+		Namespace string `form:"namespace"`
+		Cluster   string `form:"cluster"`
 	})
 	//绑定参数给匿名结构体的属性赋值
 	//from格式使用ctx.bind方法
 	//json格式使用ctx.ShouldBind
 	if err := ctx.Bind(params); err != nil {
 		logger.Error("绑定参数失败", err.Error())
-		ctx.JSON(http.StatusBadRequest, gin.H{
+		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"msg":  err.Error(),
 			"data": nil,
 		})
 		return
 	}
 	//调用service方法获取数据
-	data, err := service.Pod.GetPods(params.FilterName, params.Namespace, params.Limit, params.Page)
+	data, err := service.Pod.GetPodConatiner(ctx.Request.Context(), params.Cluster, params.PodName, params.Namespace)
 	if err != nil {
-		logger.Error("获取数据错误" + err.Error())
-	}
-	ctx.JSON(http.StatusOK, gin.H{
-		"msg":  "获取pod列表成功",
-		"data": data,
-	})
-
-}
-
-// Pod详情
-func (p *pod) GetPodDetail(ctx *gin.Context) {
-	//匿名结构体用于定义入参,get请求为from格式其他请求为json格式
-	params := new(struct {
-		PodName   string `form:"pod_name"`
-		Namespace string `form:"namespace"`
-	})
-	//绑定参数给匿名结构体的属性赋值
-	//from格式使用ctx.bind方法
-	//json格式使用ctx.ShouldBind
-	if err := ctx.Bind(params); err != nil {
-		logger.Error("绑定参数失败", err.Error())
-		ctx.JSON(http.StatusBadRequest, gin.H{
+		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"msg":  err.Error(),
 			"data": nil,
 		})
 		return
 	}
-	//调用service方法获取数据
-	data, err := service.Pod.GetPodDetail(params.PodName, params.Namespace)
-	if err != nil {
-		logger.Error("获取pod详情错误" + err.Error())
-	}
 	ctx.JSON(http.StatusOK, gin.H{
-		"msg":  "获取pod详情成功",
+		"msg":  "获取pod容器名成功",
 		"data": data,
 	})
 
 }
 
-// 删除pod
-func (p *pod) DeletePod(ctx *gin.Context) {
+// 获取pod日志
+func (p *pod) GetPodLog(ctx *gin.Context) {
 	//匿名结构体用于定义入参,get请求为from格式其他请求为json格式
 	params := new(struct {
-		PodName   string `json:"pod_name"`
-		Namespace string `json:"namespace"`
+		ContainerName string `form:"container"`
+		PodName       string `form:"pod_name"`
+		Namespace     string `form:"namespace"`
+		Cluster       string `form:"cluster"`
 	})
 	//绑定参数给匿名结构体的属性赋值
 	//from格式使用ctx.bind方法
 	//json格式使用ctx.ShouldBind
-	if err := ctx.ShouldBindJSON(params); err != nil {
-		logger.Error("删除pod失败", err.Error())
-		ctx.JSON(http.StatusInternalServerError, gin.H{
+	if err := ctx.Bind(params); err != nil {
+		logger.Error("绑定参数失败", err.Error())
+		ctx.JSON(http.StatusBadRequest, gin.H{
 			"msg":  err.Error(),
 			"data": nil,
 		})
 		return
 	}
 	//调用service方法获取数据
-	err := service.Pod.DeletePod(params.PodName, params.Namespace)
+	data, err := service.Pod.GetPodLog(ctx.Request.Context(), params.Cluster, params.ContainerName, params.PodName, params.Namespace)
 	if err != nil {
-		logger.Error("删除pod失败" + err.Error())
-	}
-	ctx.JSON(http.StatusOK, gin.H{
-		"msg":  "删除pod成功",
-		"data": nil,
-	})
-
-}
-
-// 更新pod
-func (p *pod) UpdatePod(ctx *gin.Context) {
-	//匿名结构体用于定义入参,get请求为from格式其他请求为json格式
-	params := new(struct {
-		Content   string `json:"content"`
-		Namespace string `json:"namespace"`
-	})
-	//绑定参数给匿名结构体的属性赋值
-	//from格式使用ctx.bind方法
-	//json格式使用ctx.ShouldBind
-	if err := ctx.ShouldBindJSON(params); err != nil {
-		logger.Error("绑定参数失败", err.Error())
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"msg":  err.Error(),
 			"data": nil,
 		})
 		return
 	}
-	//调用service方法获取数据
-	err := service.Pod.UpdatePod(params.Content, params.Namespace)
-	if err != nil {
-		logger.Error("更新pod失败" + err.Error())
-	}
 	ctx.JSON(http.StatusOK, gin.H{
-		"msg":  "更新pod成功",
-		"data": nil,
+		"msg":  "获取pod日志成功",
+		"data": data,
 	})
 
 }
 
-// 获取pod的容器名
-func (p *pod) GetPodContainer(ctx *gin.Context) {
-	//匿名结构体用于定义入参,get请求为from格式其他请求为json格式
-	params := new(struct {
-		PodName   string `from:"pod_name"`
-		Namespace string `from:"namespace"`
-	})
-	//绑定参数给匿名结构体的属性赋值
-	//from格式使用ctx.bind方法
-	//json格式使用ctx.ShouldBind
+// 流式获取pod日志，通过Server-Sent Events逐行推送给浏览器
+func (p *pod) GetPodLogStream(ctx *gin.Context) {
+	params := new(service.PodLogStream)
+	//绑定参数给结构体的属性赋值
 	if err := ctx.Bind(params); err != nil {
 		logger.Error("绑定参数失败", err.Error())
-		ctx.JSON(http.StatusInternalServerError, gin.H{
+		ctx.JSON(http.StatusBadRequest, gin.H{
 			"msg":  err.Error(),
 			"data": nil,
 		})
 		return
 	}
-	//调用service方法获取数据
-	data, err := service.Pod.GetPodConatiner(params.PodName, params.Namespace)
+	//使用请求的context，客户端断开后日志流随之关闭
+	stream, err := service.Pod.StreamPodLog(ctx.Request.Context(), params.Cluster, *params)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"msg":  err.Error(),
 			"data": nil,
 		})
+		return
 	}
-	ctx.JSON(http.StatusOK, gin.H{
-		"msg":  "获取pod容器名成功",
-		"data": data,
+	defer stream.Close()
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	//每读到一行推送一个log事件，日志流结束后推送end事件
+	ctx.Stream(func(w io.Writer) bool {
+		if scanner.Scan() {
+			ctx.SSEvent("log", scanner.Text())
+			return true
+		}
+		//客户端已断开，直接结束
+		if ctx.Request.Context().Err() != nil {
+			return false
+		}
+		if err := scanner.Err(); err != nil {
+			logger.Error("读取podlog流失败", err)
+			ctx.SSEvent("error", err.Error())
+			return false
+		}
+		ctx.SSEvent("end", "")
+		return false
 	})
-
 }
 
-// 获取pod日志
-func (p *pod) GetPodLog(ctx *gin.Context) {
+// 获取pod每个名称空间数量
+func (p *pod) GetPodNumPerNs(ctx *gin.Context) {
 	//匿名结构体用于定义入参,get请求为from格式其他请求为json格式
 	params := new(struct {
-		ContainerName string `from:"container"`
-		PodName       string `form:"pod_name"`
-		Namespace     string `form:"namespace"`
+		Cluster string `form:"cluster"`
 	})
 	//绑定参数给匿名结构体的属性赋值
 	//from格式使用ctx.bind方法
@@ -186,26 +150,7 @@ func (p *pod) GetPodLog(ctx *gin.Context) {
 		return
 	}
 	//调用service方法获取数据
-	data, err := service.Pod.GetPodLog(params.ContainerName, params.PodName, params.Namespace)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"msg":  err.Error(),
-			"data": nil,
-		})
-		return
-	}
-	ctx.JSON(http.StatusOK, gin.H{
-		"msg":  "获取pod日志成功",
-		"data": data,
-	})
-
-}
-
-// 获取pod每个名称空间数量
-func (p *pod) GetPodNumPerNs(ctx *gin.Context) {
-
-	//调用service方法获取数据
-	data, err := service.Pod.GetPodNumPerNs()
+	data, err := service.Pod.GetPodNumPerNs(ctx.Request.Context(), params.Cluster, accessFilter(ctx))
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"msg":  err.Error(),
@@ -1,105 +0,0 @@
-package controller
-
-import (
-	"github.com/gin-gonic/gin"
-	"github.com/wonderivan/logger"
-	"k8s-platform/service"
-	"net/http"
-)
-
-var Pv pv
-
-type pv struct{}
-
-// pv列表支持过滤。排序。分页
-func (p *pv) GetPv(ctx *gin.Context) {
-	//匿名结构体用于定义入参,get请求为from格式其他请求为json格式
-	params := new(struct {
-		FilterName string `form:"filter_name"`
-		Page       int    `form:"page"`
-		Limit      int    `form:"limit"'`
-	})
-	//绑定参数给匿名结构体的属性赋值
-	//from格式使用ctx.bind方法
-	//json格式使用ctx.ShouldBind
-	if err := ctx.Bind(params); err != nil {
-		logger.Error("绑定参数失败", err.Error())
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"msg":  err.Error(),
-			"data": nil,
-		})
-		return
-	}
-	//调用service方法获取数据
-	data, err := service.Pv.GetPv(params.FilterName, params.Limit, params.Page)
-	if err != nil {
-		logger.Error("获取数据错误" + err.Error())
-	}
-	ctx.JSON(http.StatusOK, gin.H{
-		"msg":  "获取pv列表成功",
-		"data": data,
-	})
-
-}
-
-// Pv详情
-func (p *pv) GetPvDetail(ctx *gin.Context) {
-	//匿名结构体用于定义入参,get请求为from格式其他请求为json格式
-	params := new(struct {
-		PvName string `from:"pv_name"`
-	})
-	//绑定参数给匿名结构体的属性赋值
-	//from格式使用ctx.bind方法
-	//json格式使用ctx.ShouldBind
-	if err := ctx.Bind(params); err != nil {
-		logger.Error("绑定参数失败", err.Error())
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"msg":  err.Error(),
-			"data": nil,
-		})
-		return
-	}
-	//调用service方法获取数据
-	data, err := service.Pv.GetPvDetail(params.PvName)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"msg":  err.Error(),
-			"data": data,
-		})
-
-	}
-	ctx.JSON(http.StatusOK, gin.H{
-		"msg":  "获取Pv详情成功",
-		"data": data,
-	})
-
-}
-
-// 删除pv
-func (p *pv) DeletePv(ctx *gin.Context) {
-	//匿名结构体用于定义入参,get请求为from格式其他请求为json格式
-	params := new(struct {
-		PvName string `json:"pv_name"`
-	})
-	//绑定参数给匿名结构体的属性赋值
-	//from格式使用ctx.bind方法
-	//json格式使用ctx.ShouldBind
-	if err := ctx.ShouldBindJSON(params); err != nil {
-		logger.Error("删除pv失败", err.Error())
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"msg":  err.Error(),
-			"data": nil,
-		})
-		return
-	}
-	//调用service方法获取数据
-	err := service.Pv.DeletePv(params.PvName)
-	if err != nil {
-		logger.Error("删除pv失败" + err.Error())
-	}
-	ctx.JSON(http.StatusOK, gin.H{
-		"msg":  "删除pv成功",
-		"data": nil,
-	})
-
-}
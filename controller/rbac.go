@@ -0,0 +1,147 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"io"
+	"k8s-platform/service"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"net/http"
+)
+
+// 授权中间件保存列表访问权限使用的key
+const accessFilterKey = "accessFilter"
+
+// 授权中间件，校验当前用户能否对请求中的集群、命名空间执行操作，多个资源类型时需要全部有权限
+// 列表操作同时保存用户可见资源的过滤条件，列表接口据此过滤结果
+func Authorize(verb string, kinds ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		claims := currentUser(ctx)
+		scope, err := requestScope(ctx)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"msg":  err.Error(),
+				"data": nil,
+			})
+			return
+		}
+		for _, kind := range kinds {
+			resolved, err := scope.resolve(kind)
+			if err != nil {
+				ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+					"msg":  err.Error(),
+					"data": nil,
+				})
+				return
+			}
+			if err = service.RBAC.AuthorizeScoped(claims, scope.Cluster, resolved.Namespace, resolved.Kind, verb, resolved.Namespaced); err != nil {
+				ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+					"msg":  err.Error(),
+					"data": nil,
+				})
+				return
+			}
+		}
+		if verb == service.VerbList && len(kinds) > 0 {
+			resolved, err := scope.resolve(kinds[0])
+			if err != nil {
+				ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+					"msg":  err.Error(),
+					"data": nil,
+				})
+				return
+			}
+			filter, err := service.RBAC.Filter(claims, scope.Cluster, resolved.Kind, verb)
+			if err != nil {
+				ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+					"msg":  err.Error(),
+					"data": nil,
+				})
+				return
+			}
+			ctx.Set(accessFilterKey, filter)
+		}
+		ctx.Next()
+	}
+}
+
+// 请求操作的范围，get请求取自查询参数，其他请求取自json body
+type requestScopeParams struct {
+	Cluster       string `form:"cluster" json:"cluster"`
+	Namespace     string `form:"namespace" json:"namespace"`
+	NamespaceName string `form:"namespace_name" json:"namespace_name"`
+	//crd接口操作的资源
+	Group    string `form:"group" json:"group"`
+	Version  string `form:"version" json:"version"`
+	Resource string `form:"resource" json:"resource"`
+}
+
+func requestScope(ctx *gin.Context) (*requestScopeParams, error) {
+	scope := new(requestScopeParams)
+	if ctx.Request.Method == http.MethodGet {
+		return scope, ctx.ShouldBindQuery(scope)
+	}
+	body, err := requestBody(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return scope, nil
+	}
+	return scope, json.Unmarshal(body, scope)
+}
+
+// 读取请求body，读取后还原，不影响controller绑定参数
+func requestBody(ctx *gin.Context) ([]byte, error) {
+	if ctx.Request.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		return nil, err
+	}
+	ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// 授权使用的资源类型和命名空间，集群级资源的命名空间为空
+type resourceScope struct {
+	Kind       string
+	Namespace  string
+	Namespaced bool
+}
+
+// 确定授权使用的资源类型和命名空间
+// namespace资源按其名称授权，crd接口按实际操作的资源授权，避免通过crd接口绕过内置资源的权限
+// 集群级资源忽略请求中的namespace，只能被*匹配
+func (s *requestScopeParams) resolve(kind string) (*resourceScope, error) {
+	switch kind {
+	case service.Namespace.Name:
+		return &resourceScope{Kind: kind, Namespace: s.NamespaceName, Namespaced: true}, nil
+	case "crd":
+		if s.Resource == "" {
+			return &resourceScope{Kind: kind, Namespace: s.Namespace, Namespaced: true}, nil
+		}
+		gvr := schema.GroupVersionResource{Group: s.Group, Version: s.Version, Resource: s.Resource}
+		namespaced, err := service.ResourceNamespaced(s.Cluster, gvr)
+		if err != nil {
+			return nil, err
+		}
+		return s.scope(service.ResourceKind(gvr), namespaced), nil
+	}
+	return s.scope(kind, service.IsNamespacedKind(kind)), nil
+}
+
+func (s *requestScopeParams) scope(kind string, namespaced bool) *resourceScope {
+	if !namespaced {
+		return &resourceScope{Kind: kind}
+	}
+	return &resourceScope{Kind: kind, Namespace: s.Namespace, Namespaced: true}
+}
+
+// 获取授权中间件保存的列表访问权限，没有时不过滤
+func accessFilter(ctx *gin.Context) service.AccessFilter {
+	filter, _ := ctx.Value(accessFilterKey).(service.AccessFilter)
+	return filter
+}
@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRequestScopeResolve(t *testing.T) {
+	tests := []struct {
+		name   string
+		params requestScopeParams
+		kind   string
+		want   resourceScope
+	}{
+		{
+			name:   "命名空间级资源",
+			params: requestScopeParams{Cluster: "TST-1", Namespace: "dev"},
+			kind:   "pod",
+			want:   resourceScope{Kind: "pod", Namespace: "dev", Namespaced: true},
+		},
+		{
+			name:   "集群级资源忽略请求的namespace",
+			params: requestScopeParams{Cluster: "TST-1", Namespace: "dev"},
+			kind:   "node",
+			want:   resourceScope{Kind: "node"},
+		},
+		{
+			name:   "namespace资源按名称授权",
+			params: requestScopeParams{Cluster: "TST-1", Namespace: "dev", NamespaceName: "prod"},
+			kind:   "namespace",
+			want:   resourceScope{Kind: "namespace", Namespace: "prod", Namespaced: true},
+		},
+		{
+			name:   "未注册的类型按命名空间级处理",
+			params: requestScopeParams{Cluster: "TST-1", Namespace: "dev"},
+			kind:   "dashboard",
+			want:   resourceScope{Kind: "dashboard", Namespace: "dev", Namespaced: true},
+		},
+		{
+			name:   "crd接口未指定资源",
+			params: requestScopeParams{Cluster: "TST-1", Namespace: "dev"},
+			kind:   "crd",
+			want:   resourceScope{Kind: "crd", Namespace: "dev", Namespaced: true},
+		},
+		{
+			name:   "crd接口操作内置资源时按内置资源授权",
+			params: requestScopeParams{Cluster: "TST-1", Namespace: "dev", Group: "apps", Version: "v1", Resource: "deployments"},
+			kind:   "crd",
+			want:   resourceScope{Kind: "deployment", Namespace: "dev", Namespaced: true},
+		},
+		{
+			name:   "crd接口操作内置集群级资源",
+			params: requestScopeParams{Cluster: "TST-1", Namespace: "dev", Version: "v1", Resource: "nodes"},
+			kind:   "crd",
+			want:   resourceScope{Kind: "node"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.params.resolve(tt.kind)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("resolve(%q) = %+v, want %+v", tt.kind, *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		want   requestScopeParams
+	}{
+		{
+			name:   "get请求取自查询参数",
+			method: http.MethodGet,
+			target: "/api/k8s/pods?cluster=TST-1&namespace=dev",
+			want:   requestScopeParams{Cluster: "TST-1", Namespace: "dev"},
+		},
+		{
+			name:   "其他请求取自json body",
+			method: http.MethodPut,
+			target: "/api/k8s/crd/update",
+			body:   `{"cluster":"TST-1","namespace":"dev","group":"apps","version":"v1","resource":"deployments"}`,
+			want:   requestScopeParams{Cluster: "TST-1", Namespace: "dev", Group: "apps", Version: "v1", Resource: "deployments"},
+		},
+		{
+			name:   "空body",
+			method: http.MethodDelete,
+			target: "/api/k8s/pod/del",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			got, err := requestScope(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got != tt.want {
+				t.Errorf("requestScope() = %+v, want %+v", *got, tt.want)
+			}
+			//读取后还原body，不影响controller绑定参数
+			body, err := requestBody(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != tt.body {
+				t.Errorf("body = %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package controller
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/wonderivan/logger"
+	"k8s-platform/service"
+	"net/http"
+)
+
+// 只有通用接口的资源，直接使用通用资源控制器
+var (
+	Svc       = newResource(service.Svc)
+	Ingress   = newResource(service.Ingress)
+	Configmap = newResource(service.Configmap)
+	Secret    = newResource(service.Secret)
+	Pvc       = newResource(service.Pvc)
+	Namespace = newResource(service.Namespace)
+	Pv        = newResource(service.Pv)
+)
+
+// 通用资源控制器，为注册到service的资源提供列表、详情、删除、更新、创建接口
+type resource[T any, PT service.Object[T]] struct {
+	svc *service.Resource[T, PT]
+}
+
+func newResource[T any, PT service.Object[T]](svc *service.Resource[T, PT]) *resource[T, PT] {
+	return &resource[T, PT]{svc: svc}
+}
+
+// 列表接口的过滤、排序、分页参数
+type listParams struct {
+	FilterName string `form:"filter_name"`
+	//filter_name按正则匹配
+	Regex bool `form:"regex"`
+	//filter_name忽略大小写
+	IgnoreCase    bool   `form:"ignore_case"`
+	LabelSelector string `form:"label_selector"`
+	FieldSelector string `form:"field_selector"`
+	Status        string `form:"status"`
+	//namespace为空时在所有命名空间中只保留这些命名空间的资源
+	Namespaces []string `form:"namespaces"`
+	//排序字段，如restarts:desc,name
+	SortBy string `form:"sort_by"`
+	Page   int    `form:"page"`
+	Limit  int    `form:"limit"`
+	//服务端分页，按limit分批从apiserver获取，下一页传入上一页返回的continue
+	ServerSide bool   `form:"server_side"`
+	Continue   string `form:"continue"`
+}
+
+// 组装service层的过滤、排序、分页条件，只保留当前用户可见的资源
+func (l *listParams) dataSelect(ctx *gin.Context) *service.DataSelect {
+	return &service.DataSelect{
+		FilterQuery: &service.Filter{
+			Name:          l.FilterName,
+			Regex:         l.Regex,
+			IgnoreCase:    l.IgnoreCase,
+			LabelSelector: l.LabelSelector,
+			FieldSelector: l.FieldSelector,
+			Status:        l.Status,
+			Namespaces:    l.Namespaces,
+			Allowed:       accessFilter(ctx),
+		},
+		SortQuery: &service.SortQuery{
+			SortBy: l.SortBy,
+		},
+		PaginateQuery: &service.Paginate{
+			Limit:      l.Limit,
+			Page:       l.Page,
+			ServerSide: l.ServerSide,
+			Continue:   l.Continue,
+		},
+	}
+}
+
+// 通用接口的入参，资源名的参数名因资源而异，如pod_name、configmap_name，单独解析
+type resourceParams struct {
+	listParams
+	Namespace string `form:"namespace" json:"namespace"`
+	Content   string `json:"content"`
+	Cluster   string `form:"cluster" json:"cluster"`
+	Name      string `form:"-" json:"-"`
+	//更新方式，apply为server-side apply，force强制接管冲突字段，dry_run只预览不保存
+	Apply  bool `json:"apply"`
+	Force  bool `json:"force"`
+	DryRun bool `json:"dry_run"`
+}
+
+// 绑定参数，get请求为form格式其他请求为json格式
+func (r *resource[T, PT]) bind(ctx *gin.Context) (*resourceParams, error) {
+	params := new(resourceParams)
+	nameKey := r.svc.Name + "_name"
+	if ctx.Request.Method == http.MethodGet {
+		if err := ctx.ShouldBind(params); err != nil {
+			return nil, err
+		}
+		params.Name = ctx.Query(nameKey)
+		return params, nil
+	}
+	//json格式的body需要读取两次，使用ShouldBindBodyWith缓存body
+	if err := ctx.ShouldBindBodyWith(params, binding.JSON); err != nil {
+		return nil, err
+	}
+	body := map[string]interface{}{}
+	if err := ctx.ShouldBindBodyWith(&body, binding.JSON); err != nil {
+		return nil, err
+	}
+	params.Name, _ = body[nameKey].(string)
+	return params, nil
+}
+
+// 绑定参数，失败时直接返回400
+func (r *resource[T, PT]) mustBind(ctx *gin.Context) (*resourceParams, bool) {
+	params, err := r.bind(ctx)
+	if err != nil {
+		logger.Error("绑定参数失败", err.Error())
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return nil, false
+	}
+	return params, true
+}
+
+// 资源列表支持过滤。排序。分页
+func (r *resource[T, PT]) List(ctx *gin.Context) {
+	params, ok := r.mustBind(ctx)
+	if !ok {
+		return
+	}
+	//调用service方法获取数据
+	data, err := r.svc.List(ctx.Request.Context(), params.Cluster, params.Namespace, params.dataSelect(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"msg":  "获取" + r.svc.Name + "列表成功",
+		"data": data,
+	})
+}
+
+// 资源详情，data与原来一样为资源对象，资源相关的事件放在单独的events中
+// 事件只返回给有该命名空间事件查看权限的用户，没有权限时events为空
+func (r *resource[T, PT]) Detail(ctx *gin.Context) {
+	params, ok := r.mustBind(ctx)
+	if !ok {
+		return
+	}
+	withEvents := service.RBAC.Authorize(currentUser(ctx), params.Cluster, params.Namespace, "event", service.VerbList) == nil
+	data, err := r.svc.GetDetail(ctx.Request.Context(), params.Cluster, params.Name, params.Namespace, withEvents)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"msg":    "获取" + r.svc.Name + "详情成功",
+		"data":   data.Item,
+		"events": data.Events,
+	})
+}
+
+// 删除资源
+func (r *resource[T, PT]) Delete(ctx *gin.Context) {
+	params, ok := r.mustBind(ctx)
+	if !ok {
+		return
+	}
+	if err := r.svc.Delete(ctx.Request.Context(), params.Cluster, params.Name, params.Namespace); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"msg":  "删除" + r.svc.Name + "成功",
+		"data": nil,
+	})
+}
+
+// 更新资源
+func (r *resource[T, PT]) Update(ctx *gin.Context) {
+	params, ok := r.mustBind(ctx)
+	if !ok {
+		return
+	}
+	data, err := r.svc.Update(ctx.Request.Context(), params.Cluster, params.Namespace, params.Content, service.UpdateOptions{
+		Apply:  params.Apply,
+		Force:  params.Force,
+		DryRun: params.DryRun,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	msg := "更新" + r.svc.Name + "成功"
+	if params.DryRun {
+		msg = "预览" + r.svc.Name + "更新成功"
+	}
+	//dry_run时返回服务端将要保存的对象
+	ctx.JSON(http.StatusOK, gin.H{
+		"msg":  msg,
+		"data": data,
+	})
+}
+
+// 创建资源
+func (r *resource[T, PT]) Create(ctx *gin.Context) {
+	params, ok := r.mustBind(ctx)
+	if !ok {
+		return
+	}
+	if err := r.svc.Create(ctx.Request.Context(), params.Cluster, params.Namespace, params.Content); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"msg":  "创建" + r.svc.Name + "成功",
+		"data": nil,
+	})
+}
+
+// 对比线上对象和编辑后的内容，入参与更新接口相同
+func (r *resource[T, PT]) Diff(ctx *gin.Context) {
+	params, ok := r.mustBind(ctx)
+	if !ok {
+		return
+	}
+	data, err := r.svc.Diff(ctx.Request.Context(), params.Cluster, params.Namespace, params.Content)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrDiffTooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		ctx.JSON(status, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"msg":  "对比" + r.svc.Name + "成功",
+		"data": data,
+	})
+}
@@ -2,6 +2,7 @@ package controller
 
 import (
 	"github.com/gin-gonic/gin"
+	"k8s-platform/service"
 )
 
 // 实例化router结构体,可使用改对象点出首字母大写的方式(挎包调用)
@@ -12,33 +13,128 @@ type router struct{}
 
 // 初始化路由规则创建测试api接口
 func (r *router) InitAPiRouter(router *gin.Engine) {
+	//登录和刷新token不需要认证
 	router.
+		POST("/api/login", Auth.Login).
+		POST("/api/refresh", Auth.Refresh)
+	//其他接口都需要携带有效的token，资源相关的接口按操作授权，变更操作记录审计日志
+	router.Group("", Auth.JWTAuth).
+		GET("/api/user/info", Auth.UserInfo).
+		//审计记录
+		GET("/api/audit", Authorize(service.VerbList, "audit"), AuditLog.List).
+		//集群
+		GET("/api/k8s/clusters", Cluster.GetClusters).
+		//总览，工作负载、pod、事件、资源和节点的统计
+		GET("/api/k8s/dashboard", Authorize(service.VerbList, "dashboard"), Dashboard.GetDashboard).
+		//事件，按类型、原因和关联对象过滤
+		GET("/api/k8s/events", Authorize(service.VerbList, "event"), Event.GetEvents).
 		//pod操作
-		GET("/api/k8s/pods", Pod.GetPods).
-		GET("/api/k8s/pod/detail", Pod.GetPodDetail).
-		DELETE("/api/k8s/pod/del", Pod.DeletePod).
-		PUT("/api/k8s/pod/update", Pod.UpdatePod).
-		GET("/api/k8s/pod/container", Pod.GetPodContainer).
-		GET("/api/k8s/pod/log", Pod.GetPodLog).
-		GET("/api/k8s/pod/numns", Pod.GetPodNumPerNs).
+		GET("/api/k8s/pods", Authorize(service.VerbList, "pod"), Pod.List).
+		GET("/api/k8s/pod/detail", Authorize(service.VerbGet, "pod"), Pod.Detail).
+		DELETE("/api/k8s/pod/del", Audit(service.VerbDelete, "pod"), Pod.Delete).
+		PUT("/api/k8s/pod/update", Audit(service.VerbUpdate, "pod"), Pod.Update).
+		POST("/api/k8s/pod/diff", Authorize(service.VerbUpdate, "pod"), Pod.Diff).
+		GET("/api/k8s/pod/container", Authorize(service.VerbGet, "pod"), Pod.GetPodContainer).
+		GET("/api/k8s/pod/log", Authorize(service.VerbGet, "pod"), Pod.GetPodLog).
+		GET("/api/k8s/pod/log/stream", Authorize(service.VerbGet, "pod"), Pod.GetPodLogStream).
+		GET("/api/k8s/pod/terminal", Authorize(service.VerbExec, "pod"), Terminal.PodTerminal).
+		GET("/api/k8s/pod/numns", Authorize(service.VerbList, "pod"), Pod.GetPodNumPerNs).
 		//deployment操作
-		GET("/api/k8s/deployments", Deployment.GetDeployment).
-		GET("/api/k8s/deployment/detail", Deployment.GetDeloymentDetail).
-		DELETE("/api/k8s/deployment/del", Deployment.DeleteDeloyment).
-		PUT("/api/k8s/deployment/update", Deployment.UpdateDeloyment).
-		PUT("/api/k8s/deployment/restart", Deployment.RestartDeployment).
-		PUT("/api/k8s/deployment/scale", Deployment.ScaleDeployment).
-		POST("/api/k8s/deployment/create", Deployment.CreateDeployment).
-		GET("/api/k8s/deployment/numns", Deployment.GetDeloymentNumPerNs).
+		GET("/api/k8s/deployments", Authorize(service.VerbList, "deployment"), Deployment.List).
+		GET("/api/k8s/deployment/detail", Authorize(service.VerbGet, "deployment"), Deployment.Detail).
+		DELETE("/api/k8s/deployment/del", Audit(service.VerbDelete, "deployment"), Deployment.Delete).
+		PUT("/api/k8s/deployment/update", Audit(service.VerbUpdate, "deployment"), Deployment.Update).
+		POST("/api/k8s/deployment/diff", Authorize(service.VerbUpdate, "deployment"), Deployment.Diff).
+		PUT("/api/k8s/deployment/restart", Audit(service.VerbRestart, "deployment"), Deployment.RestartDeployment).
+		PUT("/api/k8s/deployment/scale", Audit(service.VerbScale, "deployment"), Deployment.ScaleDeployment).
+		POST("/api/k8s/deployment/create", Audit(service.VerbCreate, "deployment"), Deployment.CreateDeployment).
+		GET("/api/k8s/deployment/numns", Authorize(service.VerbList, "deployment"), Deployment.GetDeloymentNumPerNs).
+		GET("/api/k8s/deployment/history", Authorize(service.VerbGet, "deployment"), Deployment.GetRolloutHistory).
+		PUT("/api/k8s/deployment/rollback", Audit(service.VerbRollout, "deployment"), Deployment.RollbackDeployment).
+		PUT("/api/k8s/deployment/pause", Audit(service.VerbRollout, "deployment"), Deployment.PauseDeployment).
+		PUT("/api/k8s/deployment/resume", Audit(service.VerbRollout, "deployment"), Deployment.ResumeDeployment).
+		GET("/api/k8s/deployment/rollout/status", Authorize(service.VerbGet, "deployment"), Deployment.GetRolloutStatus).
 		//daemonset
-		GET("/api/k8s/daemonset", Daemonset.GetDaemonSet).
-		GET("/api/k8s/daemonset/detail", Daemonset.GetDaemonSetDetail).
-		POST("/api/k8s/daemonset/del", Daemonset.DeleteDaemonSet).
-		PUT("/api/k8s/daemonset/update", Daemonset.UpdateDaemonSet).
+		GET("/api/k8s/daemonset", Authorize(service.VerbList, "daemonset"), Daemonset.List).
+		GET("/api/k8s/daemonset/detail", Authorize(service.VerbGet, "daemonset"), Daemonset.Detail).
+		POST("/api/k8s/daemonset/del", Audit(service.VerbDelete, "daemonset"), Daemonset.Delete).
+		PUT("/api/k8s/daemonset/update", Audit(service.VerbUpdate, "daemonset"), Daemonset.Update).
+		PUT("/api/k8s/daemonset/restart", Audit(service.VerbRestart, "daemonset"), Daemonset.RestartDaemonSet).
+		POST("/api/k8s/daemonset/diff", Authorize(service.VerbUpdate, "daemonset"), Daemonset.Diff).
+		POST("/api/k8s/daemonset/create", Audit(service.VerbCreate, "daemonset"), Daemonset.Create).
+		//statefulset
+		GET("/api/k8s/statefulset", Authorize(service.VerbList, "statefulset"), StatefulSet.List).
+		GET("/api/k8s/statefulset/detail", Authorize(service.VerbGet, "statefulset"), StatefulSet.Detail).
+		POST("/api/k8s/statefulset/del", Audit(service.VerbDelete, "statefulset"), StatefulSet.Delete).
+		PUT("/api/k8s/statefulset/update", Audit(service.VerbUpdate, "statefulset"), StatefulSet.Update).
+		PUT("/api/k8s/statefulset/restart", Audit(service.VerbRestart, "statefulset"), StatefulSet.RestartStatefulSet).
+		POST("/api/k8s/statefulset/diff", Authorize(service.VerbUpdate, "statefulset"), StatefulSet.Diff).
+		POST("/api/k8s/statefulset/create", Audit(service.VerbCreate, "statefulset"), StatefulSet.Create).
 		//service
-		GET("/api/k8s/svc", Svc.GetSvc).
-		GET("/api/k8s/daemonset/detail", Svc.GetSvcDetail).
-		POST("/api/k8s/daemonset/del", Svc.DeleteSvc).
-		PUT("/api/k8s/daemonset/update", Svc.UpdateSvc)
+		GET("/api/k8s/svc", Authorize(service.VerbList, "svc"), Svc.List).
+		GET("/api/k8s/svc/detail", Authorize(service.VerbGet, "svc"), Svc.Detail).
+		POST("/api/k8s/svc/del", Audit(service.VerbDelete, "svc"), Svc.Delete).
+		PUT("/api/k8s/svc/update", Audit(service.VerbUpdate, "svc"), Svc.Update).
+		POST("/api/k8s/svc/diff", Authorize(service.VerbUpdate, "svc"), Svc.Diff).
+		POST("/api/k8s/svc/create", Audit(service.VerbCreate, "svc"), Svc.Create).
+		//ingress
+		GET("/api/k8s/ingress", Authorize(service.VerbList, "ingress"), Ingress.List).
+		GET("/api/k8s/ingress/detail", Authorize(service.VerbGet, "ingress"), Ingress.Detail).
+		POST("/api/k8s/ingress/del", Audit(service.VerbDelete, "ingress"), Ingress.Delete).
+		PUT("/api/k8s/ingress/update", Audit(service.VerbUpdate, "ingress"), Ingress.Update).
+		POST("/api/k8s/ingress/diff", Authorize(service.VerbUpdate, "ingress"), Ingress.Diff).
+		POST("/api/k8s/ingress/create", Audit(service.VerbCreate, "ingress"), Ingress.Create).
+		//configmap
+		GET("/api/k8s/configmap", Authorize(service.VerbList, "configmap"), Configmap.List).
+		GET("/api/k8s/configmap/detail", Authorize(service.VerbGet, "configmap"), Configmap.Detail).
+		POST("/api/k8s/configmap/del", Audit(service.VerbDelete, "configmap"), Configmap.Delete).
+		PUT("/api/k8s/configmap/update", Audit(service.VerbUpdate, "configmap"), Configmap.Update).
+		POST("/api/k8s/configmap/diff", Authorize(service.VerbUpdate, "configmap"), Configmap.Diff).
+		POST("/api/k8s/configmap/create", Audit(service.VerbCreate, "configmap"), Configmap.Create).
+		//secret
+		GET("/api/k8s/secret", Authorize(service.VerbList, "secret"), Secret.List).
+		GET("/api/k8s/secret/detail", Authorize(service.VerbGet, "secret"), Secret.Detail).
+		POST("/api/k8s/secret/del", Audit(service.VerbDelete, "secret"), Secret.Delete).
+		PUT("/api/k8s/secret/update", Audit(service.VerbUpdate, "secret"), Secret.Update).
+		POST("/api/k8s/secret/diff", Authorize(service.VerbUpdate, "secret"), Secret.Diff).
+		POST("/api/k8s/secret/create", Audit(service.VerbCreate, "secret"), Secret.Create).
+		//pvc
+		GET("/api/k8s/pvc", Authorize(service.VerbList, "pvc"), Pvc.List).
+		GET("/api/k8s/pvc/detail", Authorize(service.VerbGet, "pvc"), Pvc.Detail).
+		POST("/api/k8s/pvc/del", Audit(service.VerbDelete, "pvc"), Pvc.Delete).
+		PUT("/api/k8s/pvc/update", Audit(service.VerbUpdate, "pvc"), Pvc.Update).
+		POST("/api/k8s/pvc/diff", Authorize(service.VerbUpdate, "pvc"), Pvc.Diff).
+		POST("/api/k8s/pvc/create", Audit(service.VerbCreate, "pvc"), Pvc.Create).
+		//node
+		GET("/api/k8s/node", Authorize(service.VerbList, "node"), Node.List).
+		GET("/api/k8s/node/detail", Authorize(service.VerbGet, "node"), Node.Detail).
+		PUT("/api/k8s/node/update", Audit(service.VerbUpdate, "node"), Node.Update).
+		POST("/api/k8s/node/diff", Authorize(service.VerbUpdate, "node"), Node.Diff).
+		PUT("/api/k8s/node/cordon", Audit(service.VerbCordon, "node"), Node.CordonNode).
+		PUT("/api/k8s/node/uncordon", Audit(service.VerbCordon, "node"), Node.UncordonNode).
+		POST("/api/k8s/node/drain", Audit(service.VerbDrain, "node"), Node.DrainNode).
+		PUT("/api/k8s/node/labels", Audit(service.VerbUpdate, "node"), Node.UpdateNodeLabels).
+		PUT("/api/k8s/node/taints", Audit(service.VerbUpdate, "node"), Node.UpdateNodeTaints).
+		//namespace
+		GET("/api/k8s/namespace", Authorize(service.VerbList, "namespace"), Namespace.List).
+		GET("/api/k8s/namespace/detail", Authorize(service.VerbGet, "namespace"), Namespace.Detail).
+		POST("/api/k8s/namespace/del", Audit(service.VerbDelete, "namespace"), Namespace.Delete).
+		POST("/api/k8s/namespace/create", Audit(service.VerbCreate, "namespace"), Namespace.Create).
+		//pv
+		GET("/api/k8s/pv", Authorize(service.VerbList, "pv"), Pv.List).
+		GET("/api/k8s/pv/detail", Authorize(service.VerbGet, "pv"), Pv.Detail).
+		POST("/api/k8s/pv/del", Audit(service.VerbDelete, "pv"), Pv.Delete).
+		POST("/api/k8s/pv/create", Audit(service.VerbCreate, "pv"), Pv.Create).
+		//应用，一次创建deployment、service和ingress
+		POST("/api/k8s/app/create", Audit(service.VerbCreate, "deployment", "svc"), App.CreateApp).
+		//清单，按顺序创建yaml或json中的多个对象，解析后逐个对象授权
+		POST("/api/k8s/manifest/create", Audit(service.VerbCreate), Manifest.CreateManifest).
+		//crd等任意资源，通过discovery和dynamic client操作
+		GET("/api/k8s/crd/resources", Authorize(service.VerbList, "crd"), Crd.GetApiResources).
+		GET("/api/k8s/crd", Authorize(service.VerbList, "crd"), Crd.GetCrds).
+		GET("/api/k8s/crd/detail", Authorize(service.VerbGet, "crd"), Crd.GetCrdDetail).
+		DELETE("/api/k8s/crd/del", Audit(service.VerbDelete, "crd"), Crd.DeleteCrd).
+		PUT("/api/k8s/crd/update", Audit(service.VerbUpdate, "crd"), Crd.UpdateCrd).
+		POST("/api/k8s/crd/diff", Authorize(service.VerbUpdate, "crd"), Crd.DiffCrd)
 
 }
@@ -0,0 +1,44 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wonderivan/logger"
+	"k8s-platform/service"
+	appsv1 "k8s.io/api/apps/v1"
+	"net/http"
+)
+
+// statefulset的列表、详情、删除、更新、创建由通用资源控制器提供
+var StatefulSet = statefulSet{newResource(service.StatefulSet.Resource)}
+
+type statefulSet struct {
+	*resource[appsv1.StatefulSet, *appsv1.StatefulSet]
+}
+
+// 重启statefulset
+func (s *statefulSet) RestartStatefulSet(ctx *gin.Context) {
+	params := new(struct {
+		Name      string `json:"statefulset_name"`
+		Namespace string `json:"namespace"`
+		Cluster   string `json:"cluster"`
+	})
+	if err := ctx.ShouldBindJSON(params); err != nil {
+		logger.Error("绑定参数失败", err.Error())
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	if err := service.StatefulSet.RestartStatefulSet(ctx.Request.Context(), params.Cluster, params.Name, params.Namespace); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"msg":  "重启statefulset成功",
+		"data": nil,
+	})
+}
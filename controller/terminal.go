@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/wonderivan/logger"
+	"k8s-platform/config"
+	"k8s-platform/service"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var Terminal terminal
+
+type terminal struct{}
+
+// websocket升级器，前端与后端不同源，只允许配置中的前端地址跨域
+var upgrader = websocket.Upgrader{
+	CheckOrigin: checkOrigin,
+}
+
+// 校验websocket请求的来源，避免其他站点借用浏览器中的登录状态连接终端
+// 没有Origin的非浏览器请求和同源请求允许，跨域请求只允许config.AllowedOrigins中的地址
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range strings.Split(config.AllowedOrigins, ",") {
+		if strings.EqualFold(strings.TrimSuffix(strings.TrimSpace(allowed), "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// 进入容器终端，http连接升级为websocket后桥接到容器的tty
+func (t *terminal) PodTerminal(ctx *gin.Context) {
+	//匿名结构体用于定义入参,get请求为from格式其他请求为json格式
+	params := new(struct {
+		PodName       string `form:"pod_name"`
+		ContainerName string `form:"container"`
+		Namespace     string `form:"namespace"`
+		Cluster       string `form:"cluster"`
+	})
+	if err := ctx.Bind(params); err != nil {
+		logger.Error("绑定参数失败", err.Error())
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	//升级为websocket连接，失败时upgrader已向客户端返回错误
+	conn, err := upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	if err != nil {
+		logger.Error("升级websocket连接失败", err)
+		return
+	}
+	session := service.NewTerminalSession(conn)
+	defer session.Close()
+	//调用service方法进入容器，阻塞直到shell退出或连接断开
+	if err := service.Terminal.Exec(ctx.Request.Context(), params.Cluster, params.PodName, params.ContainerName, params.Namespace, session); err != nil {
+		logger.Error("进入容器终端失败", err)
+		session.Write([]byte(err.Error()))
+	}
+}
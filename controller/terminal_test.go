@@ -0,0 +1,32 @@
+package controller
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckOrigin(t *testing.T) {
+	tests := []struct {
+		name   string
+		origin string
+		want   bool
+	}{
+		{name: "非浏览器请求", origin: "", want: true},
+		{name: "同源", origin: "http://platform.example.com:9090", want: true},
+		{name: "配置中的前端地址", origin: "http://localhost:8080", want: true},
+		{name: "其他站点", origin: "http://evil.example.com", want: false},
+		{name: "端口不同", origin: "http://localhost:8081", want: false},
+		{name: "Origin不合法", origin: "://", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "http://platform.example.com:9090/api/ws", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if got := checkOrigin(req); got != tt.want {
+				t.Errorf("checkOrigin(%q) = %v, want %v", tt.origin, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,9 +2,11 @@ package main
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/wonderivan/logger"
 	"k8s-platform/config"
 	"k8s-platform/controller"
 	"k8s-platform/service"
+	"os"
 )
 
 func main() {
@@ -12,6 +14,15 @@ func main() {
 	r := gin.Default()
 	//初始化k8s client
 	service.K8s.Init()
+	//初始化身份后端，失败时退出，不能在没有签名密钥的情况下提供服务
+	if err := service.Auth.Init(); err != nil {
+		logger.Error("初始化身份认证失败", err)
+		os.Exit(1)
+	}
+	//初始化授权策略
+	service.RBAC.Init()
+	//初始化审计存储
+	service.Audit.Init()
 	//初始化路由规则
 	controller.Router.InitAPiRouter(r)
 	//启动gin
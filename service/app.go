@@ -0,0 +1,280 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/wonderivan/logger"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"strings"
+	"time"
+)
+
+var App app
+
+type app struct{}
+
+// 创建应用失败时回滚的超时时间
+const appRollbackTimeout = 30 * time.Second
+
+// 定义结构体用于创建应用，deployment的字段与DeployCreate相同
+type AppCreate struct {
+	DeployCreate
+	Service AppService `json:"service"`
+	//为空时不创建ingress
+	Ingress *AppIngress `json:"ingress"`
+}
+
+// 应用的service，名称和selector与deployment相同
+type AppService struct {
+	//ClusterIP或NodePort，默认ClusterIP
+	Type string `json:"type"`
+	//为空时按容器端口生成，port与target_port相同
+	Ports []AppServicePort `json:"ports"`
+}
+
+type AppServicePort struct {
+	Name       string `json:"name"`
+	Port       int32  `json:"port"`
+	TargetPort int32  `json:"target_port"`
+	//type为NodePort时可指定，为0时自动分配
+	NodePort int32 `json:"node_port"`
+	//TCP、UDP、SCTP，默认TCP
+	Protocol string `json:"protocol"`
+}
+
+// 应用的ingress，名称与deployment相同，转发到应用的service
+type AppIngress struct {
+	ClassName string `json:"class_name"`
+	Host      string `json:"host"`
+	//默认为/
+	Path string `json:"path"`
+	//Prefix、Exact、ImplementationSpecific，默认Prefix
+	PathType string `json:"path_type"`
+	//转发到service的端口，为0时使用第一个端口
+	ServicePort int32 `json:"service_port"`
+	//https证书所在的secret，为空时不启用tls
+	TLSSecret   string            `json:"tls_secret"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// 创建应用，依次创建deployment、service和ingress
+// 所有对象先完成校验，创建时任一对象失败则删除已创建的对象
+func (a *app) CreateApp(ctx context.Context, cluster string, data AppCreate) (err error) {
+	deployment, err := data.toDeployment()
+	if err != nil {
+		return err
+	}
+	svc, errs := data.Service.toService(deployment)
+	var ingress *networkingv1.Ingress
+	if data.Ingress != nil {
+		var ingressErrs field.ErrorList
+		ingress, ingressErrs = data.Ingress.toIngress(svc)
+		errs = append(errs, ingressErrs...)
+	}
+	if len(errs) > 0 {
+		return errors.New("参数校验失败" + errs.ToAggregate().Error())
+	}
+	//已创建对象的回滚方法，失败时倒序执行
+	//回滚不使用请求的ctx，请求取消或超时后仍需删除已创建的对象
+	var rollbacks []func(ctx context.Context) error
+	rollback := func(cause error) error {
+		rollbackCtx, cancel := context.WithTimeout(context.Background(), appRollbackTimeout)
+		defer cancel()
+		//每个对象都尝试删除，失败的错误合并返回
+		var rollbackErrs []error
+		for i := len(rollbacks) - 1; i >= 0; i-- {
+			if err := rollbacks[i](rollbackCtx); err != nil {
+				logger.Error("回滚应用失败", err)
+				rollbackErrs = append(rollbackErrs, err)
+			}
+		}
+		if len(rollbackErrs) > 0 {
+			return errors.New(cause.Error() + "，回滚已创建的资源失败" + utilerrors.NewAggregate(rollbackErrs).Error())
+		}
+		return cause
+	}
+	if _, err = Deployment.create(ctx, cluster, deployment); err != nil {
+		return err
+	}
+	rollbacks = append(rollbacks, func(ctx context.Context) error {
+		return Deployment.Delete(ctx, cluster, deployment.Name, deployment.Namespace)
+	})
+	if _, err = Svc.create(ctx, cluster, svc); err != nil {
+		return rollback(err)
+	}
+	rollbacks = append(rollbacks, func(ctx context.Context) error {
+		return Svc.Delete(ctx, cluster, svc.Name, svc.Namespace)
+	})
+	if ingress != nil {
+		if _, err = Ingress.create(ctx, cluster, ingress); err != nil {
+			return rollback(err)
+		}
+	}
+	return nil
+}
+
+// 组装service，selector使用deployment的pod标签
+func (s *AppService) toService(deployment *appsv1.Deployment) (*corev1.Service, field.ErrorList) {
+	var errs field.ErrorList
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      deployment.Name,
+			Namespace: deployment.Namespace,
+			Labels:    deployment.Labels,
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: deployment.Spec.Template.Labels,
+		},
+	}
+	switch serviceType := corev1.ServiceType(s.Type); serviceType {
+	case "", corev1.ServiceTypeClusterIP:
+		service.Spec.Type = corev1.ServiceTypeClusterIP
+	case corev1.ServiceTypeNodePort:
+		service.Spec.Type = serviceType
+	default:
+		errs = append(errs, field.NotSupported(field.NewPath("service", "type"), s.Type, []string{"ClusterIP", "NodePort"}))
+	}
+	ports := s.Ports
+	if len(ports) == 0 {
+		for _, container := range deployment.Spec.Template.Spec.Containers {
+			for _, port := range container.Ports {
+				ports = append(ports, AppServicePort{
+					Name:       port.Name,
+					Port:       port.ContainerPort,
+					TargetPort: port.ContainerPort,
+					Protocol:   string(port.Protocol),
+				})
+			}
+		}
+	}
+	if len(ports) == 0 {
+		errs = append(errs, field.Required(field.NewPath("service", "ports"), "容器未声明端口时必须指定service端口"))
+	}
+	for i, port := range ports {
+		path := field.NewPath("service", "ports").Index(i)
+		if port.TargetPort == 0 {
+			port.TargetPort = port.Port
+		}
+		for _, msg := range validation.IsValidPortNum(int(port.Port)) {
+			errs = append(errs, field.Invalid(path.Child("port"), port.Port, msg))
+		}
+		for _, msg := range validation.IsValidPortNum(int(port.TargetPort)) {
+			errs = append(errs, field.Invalid(path.Child("target_port"), port.TargetPort, msg))
+		}
+		if port.NodePort != 0 {
+			if service.Spec.Type != corev1.ServiceTypeNodePort {
+				errs = append(errs, field.Forbidden(path.Child("node_port"), "只有type为NodePort时可以指定"))
+			}
+			for _, msg := range validation.IsValidPortNum(int(port.NodePort)) {
+				errs = append(errs, field.Invalid(path.Child("node_port"), port.NodePort, msg))
+			}
+		}
+		//多个端口时必须有名称
+		if port.Name == "" && len(ports) > 1 {
+			errs = append(errs, field.Required(path.Child("name"), "多个端口时必须指定名称"))
+		}
+		protocol := corev1.Protocol(strings.ToUpper(port.Protocol))
+		switch protocol {
+		case "":
+			protocol = corev1.ProtocolTCP
+		case corev1.ProtocolTCP, corev1.ProtocolUDP, corev1.ProtocolSCTP:
+		default:
+			errs = append(errs, field.NotSupported(path.Child("protocol"), port.Protocol, []string{"TCP", "UDP", "SCTP"}))
+		}
+		service.Spec.Ports = append(service.Spec.Ports, corev1.ServicePort{
+			Name:       port.Name,
+			Port:       port.Port,
+			TargetPort: intstr.FromInt(int(port.TargetPort)),
+			NodePort:   port.NodePort,
+			Protocol:   protocol,
+		})
+	}
+	return service, errs
+}
+
+// 组装ingress，转发到应用的service
+func (i *AppIngress) toIngress(service *corev1.Service) (*networkingv1.Ingress, field.ErrorList) {
+	var errs field.ErrorList
+	path := field.NewPath("ingress")
+	if i.Host != "" {
+		//允许*.example.com形式的泛域名
+		for _, msg := range validation.IsDNS1123Subdomain(strings.TrimPrefix(i.Host, "*.")) {
+			errs = append(errs, field.Invalid(path.Child("host"), i.Host, msg))
+		}
+	}
+	httpPath := i.Path
+	if httpPath == "" {
+		httpPath = "/"
+	}
+	if !strings.HasPrefix(httpPath, "/") {
+		errs = append(errs, field.Invalid(path.Child("path"), i.Path, "必须以/开头"))
+	}
+	pathType := networkingv1.PathType(i.PathType)
+	switch pathType {
+	case "":
+		pathType = networkingv1.PathTypePrefix
+	case networkingv1.PathTypePrefix, networkingv1.PathTypeExact, networkingv1.PathTypeImplementationSpecific:
+	default:
+		errs = append(errs, field.NotSupported(path.Child("path_type"), i.PathType, []string{"Prefix", "Exact", "ImplementationSpecific"}))
+	}
+	servicePort := i.ServicePort
+	if servicePort == 0 && len(service.Spec.Ports) > 0 {
+		servicePort = service.Spec.Ports[0].Port
+	}
+	found := false
+	for _, port := range service.Spec.Ports {
+		found = found || port.Port == servicePort
+	}
+	if !found {
+		errs = append(errs, field.NotFound(path.Child("service_port"), servicePort))
+	}
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        service.Name,
+			Namespace:   service.Namespace,
+			Labels:      service.Labels,
+			Annotations: i.Annotations,
+		},
+		Spec: networkingv1.IngressSpec{
+			Rules: []networkingv1.IngressRule{{
+				Host: i.Host,
+				IngressRuleValue: networkingv1.IngressRuleValue{
+					HTTP: &networkingv1.HTTPIngressRuleValue{
+						Paths: []networkingv1.HTTPIngressPath{{
+							Path:     httpPath,
+							PathType: &pathType,
+							Backend: networkingv1.IngressBackend{
+								Service: &networkingv1.IngressServiceBackend{
+									Name: service.Name,
+									Port: networkingv1.ServiceBackendPort{Number: servicePort},
+								},
+							},
+						}},
+					},
+				},
+			}},
+		},
+	}
+	if i.ClassName != "" {
+		className := i.ClassName
+		ingress.Spec.IngressClassName = &className
+	}
+	if i.TLSSecret != "" {
+		errs = append(errs, validateObjectName(i.TLSSecret, path.Child("tls_secret"))...)
+		if i.Host == "" {
+			errs = append(errs, field.Required(path.Child("host"), "启用tls时必须指定host"))
+		}
+		ingress.Spec.TLS = []networkingv1.IngressTLS{{
+			Hosts:      []string{i.Host},
+			SecretName: i.TLSSecret,
+		}}
+	}
+	return ingress, errs
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"strings"
+	"testing"
+)
+
+// 测试用的deployment，容器声明了http和metrics两个端口
+func testAppDeployment(t *testing.T) *appsv1.Deployment {
+	t.Helper()
+	data := DeployCreate{
+		Name:      "web",
+		Namespace: "dev",
+		Replicas:  1,
+		Containers: []ContainerSpec{{
+			Name:  "web",
+			Image: "nginx",
+			Ports: []PortSpec{{Name: "http", ContainerPort: 80}, {Name: "metrics", ContainerPort: 9090}},
+		}},
+	}
+	deployment, err := data.toDeployment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return deployment
+}
+
+func TestAppServiceToService(t *testing.T) {
+	tests := []struct {
+		name    string
+		service AppService
+		//不合法时错误信息中应包含的字段路径
+		wantErrs  []string
+		wantType  corev1.ServiceType
+		wantPorts []int32
+	}{
+		{name: "默认按容器端口生成", service: AppService{}, wantType: corev1.ServiceTypeClusterIP, wantPorts: []int32{80, 9090}},
+		{
+			name:      "NodePort",
+			service:   AppService{Type: "NodePort", Ports: []AppServicePort{{Port: 8080, TargetPort: 80, NodePort: 30080}}},
+			wantType:  corev1.ServiceTypeNodePort,
+			wantPorts: []int32{8080},
+		},
+		{name: "不支持的类型", service: AppService{Type: "LoadBalancer"}, wantErrs: []string{"service.type"}},
+		{
+			name:     "ClusterIP不能指定nodePort",
+			service:  AppService{Ports: []AppServicePort{{Port: 80, NodePort: 30080}}},
+			wantErrs: []string{"service.ports[0].node_port: Forbidden"},
+		},
+		{
+			name:     "多个端口时必须有名称",
+			service:  AppService{Ports: []AppServicePort{{Port: 80}, {Name: "b", Port: 81}}},
+			wantErrs: []string{"service.ports[0].name: Required"},
+		},
+		{
+			name:     "端口超出范围",
+			service:  AppService{Ports: []AppServicePort{{Port: 70000}}},
+			wantErrs: []string{"service.ports[0].port"},
+		},
+		{
+			name:     "协议不支持",
+			service:  AppService{Ports: []AppServicePort{{Port: 80, Protocol: "http"}}},
+			wantErrs: []string{"service.ports[0].protocol"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment := testAppDeployment(t)
+			svc, errs := tt.service.toService(deployment)
+			if len(tt.wantErrs) > 0 {
+				for _, want := range tt.wantErrs {
+					if !strings.Contains(errs.ToAggregate().Error(), want) {
+						t.Errorf("toService() errs = %v, want containing %q", errs, want)
+					}
+				}
+				return
+			}
+			if len(errs) > 0 {
+				t.Fatalf("toService() errs = %v", errs)
+			}
+			if svc.Name != "web" || svc.Namespace != "dev" || svc.Spec.Type != tt.wantType {
+				t.Errorf("toService() = %s/%s type %s", svc.Namespace, svc.Name, svc.Spec.Type)
+			}
+			if svc.Spec.Selector["app"] != deployment.Spec.Template.Labels["app"] {
+				t.Errorf("toService() selector = %v", svc.Spec.Selector)
+			}
+			var ports []int32
+			for _, port := range svc.Spec.Ports {
+				ports = append(ports, port.Port)
+				if port.TargetPort.IntValue() == 0 || port.Protocol != corev1.ProtocolTCP {
+					t.Errorf("toService() port = %+v", port)
+				}
+			}
+			if len(ports) != len(tt.wantPorts) {
+				t.Fatalf("toService() ports = %v, want %v", ports, tt.wantPorts)
+			}
+			for i := range ports {
+				if ports[i] != tt.wantPorts[i] {
+					t.Errorf("toService() ports = %v, want %v", ports, tt.wantPorts)
+				}
+			}
+		})
+	}
+}
+
+func TestAppIngressToIngress(t *testing.T) {
+	tests := []struct {
+		name         string
+		ingress      AppIngress
+		wantErrs     []string
+		wantPort     int32
+		wantPath     string
+		wantPathType networkingv1.PathType
+	}{
+		{name: "默认值", ingress: AppIngress{Host: "web.example.com"}, wantPort: 80, wantPath: "/", wantPathType: networkingv1.PathTypePrefix},
+		{
+			name:         "指定端口和路径",
+			ingress:      AppIngress{Host: "*.example.com", Path: "/api", PathType: "Exact", ServicePort: 9090, ClassName: "nginx"},
+			wantPort:     9090,
+			wantPath:     "/api",
+			wantPathType: networkingv1.PathTypeExact,
+		},
+		{name: "host不合法", ingress: AppIngress{Host: "Web_Example"}, wantErrs: []string{"ingress.host"}},
+		{name: "路径必须以/开头", ingress: AppIngress{Path: "api"}, wantErrs: []string{"ingress.path"}},
+		{name: "路径类型不支持", ingress: AppIngress{PathType: "Regex"}, wantErrs: []string{"ingress.path_type"}},
+		{name: "service端口不存在", ingress: AppIngress{ServicePort: 8080}, wantErrs: []string{"ingress.service_port: Not found"}},
+		{name: "启用tls时必须指定host", ingress: AppIngress{TLSSecret: "web-tls"}, wantErrs: []string{"ingress.host: Required"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, errs := (&AppService{}).toService(testAppDeployment(t))
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			}
+			ingress, errs := tt.ingress.toIngress(svc)
+			if len(tt.wantErrs) > 0 {
+				for _, want := range tt.wantErrs {
+					if !strings.Contains(errs.ToAggregate().Error(), want) {
+						t.Errorf("toIngress() errs = %v, want containing %q", errs, want)
+					}
+				}
+				return
+			}
+			if len(errs) > 0 {
+				t.Fatalf("toIngress() errs = %v", errs)
+			}
+			path := ingress.Spec.Rules[0].HTTP.Paths[0]
+			if path.Backend.Service.Name != "web" || path.Backend.Service.Port.Number != tt.wantPort {
+				t.Errorf("toIngress() backend = %+v", path.Backend.Service)
+			}
+			if path.Path != tt.wantPath || *path.PathType != tt.wantPathType {
+				t.Errorf("toIngress() path = %s %s", path.Path, *path.PathType)
+			}
+			if tt.ingress.ClassName != "" && (ingress.Spec.IngressClassName == nil || *ingress.Spec.IngressClassName != tt.ingress.ClassName) {
+				t.Errorf("toIngress() class = %v", ingress.Spec.IngressClassName)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/json"
+	yamlutil "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// 平台提交修改时使用的field manager，server-side apply据此记录字段归属
+const fieldManager = "k8s-platform"
+
+// UpdateOptions 更新方式，默认使用Update整体替换
+type UpdateOptions struct {
+	//使用server-side apply提交，只修改content中声明的字段，不校验resourceVersion
+	Apply bool
+	//apply时强制接管其他field manager拥有的冲突字段
+	Force bool
+	//只在服务端校验，不持久化，返回服务端将要保存的对象
+	DryRun bool
+}
+
+func (o UpdateOptions) updateOptions() metav1.UpdateOptions {
+	options := metav1.UpdateOptions{FieldManager: fieldManager}
+	if o.DryRun {
+		options.DryRun = []string{metav1.DryRunAll}
+	}
+	return options
+}
+
+func (o UpdateOptions) patchOptions() metav1.PatchOptions {
+	force := o.Force
+	options := metav1.PatchOptions{FieldManager: fieldManager, Force: &force}
+	if o.DryRun {
+		options.DryRun = []string{metav1.DryRunAll}
+	}
+	return options
+}
+
+// 将yaml或json格式的content转成unstructured对象
+func decodeUnstructured(content string) (*unstructured.Unstructured, error) {
+	data, err := yamlutil.ToJSON([]byte(content))
+	if err != nil {
+		return nil, errors.New("Content反序列化失败" + err.Error())
+	}
+	obj := &unstructured.Unstructured{}
+	if err = json.Unmarshal(data, &obj.Object); err != nil {
+		return nil, errors.New("Content反序列化失败" + err.Error())
+	}
+	return obj, nil
+}
+
+// 服务端维护的字段，apply和diff时忽略
+var serverFields = [][]string{
+	{"metadata", "resourceVersion"},
+	{"metadata", "managedFields"},
+	{"metadata", "uid"},
+	{"metadata", "generation"},
+	{"metadata", "creationTimestamp"},
+	{"metadata", "selfLink"},
+	{"status"},
+}
+
+// 生成server-side apply的patch
+// 去掉resourceVersion、managedFields等服务端维护的字段和status，避免resourceVersion过期导致冲突以及声明不属于平台的字段
+// content中没有apiVersion和kind时使用gvk补全
+func applyPatch(content string, gvk schema.GroupVersionKind) ([]byte, error) {
+	obj, err := decodeUnstructured(content)
+	if err != nil {
+		return nil, err
+	}
+	if obj.GetAPIVersion() == "" || obj.GetKind() == "" {
+		obj.SetGroupVersionKind(gvk)
+	}
+	for _, field := range serverFields {
+		unstructured.RemoveNestedField(obj.Object, field...)
+	}
+	return obj.MarshalJSON()
+}
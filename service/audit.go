@@ -0,0 +1,299 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/wonderivan/logger"
+	"io"
+	"k8s-platform/config"
+	yamlutil "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+	"strings"
+	"time"
+)
+
+var Audit audit
+
+type audit struct {
+	store AuditStore
+}
+
+// 审计记录，每个变更操作一条
+type AuditRecord struct {
+	Time time.Time `json:"time"`
+	User string    `json:"user"`
+	IP   string    `json:"ip"`
+	//操作，即授权使用的verb，如create、update、delete、scale、restart
+	Action    string `json:"action"`
+	Cluster   string `json:"cluster"`
+	Namespace string `json:"namespace"`
+	//资源类型，与授权使用的资源类型一致，多个类型用逗号分隔
+	Kind string `json:"kind"`
+	//资源名，批量操作时多个名称用逗号分隔
+	Name   string `json:"name"`
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	//请求参数，secret的data和stringData已脱敏
+	Payload string `json:"payload"`
+	//http状态码小于400为成功
+	Status  int    `json:"status"`
+	Success bool   `json:"success"`
+	Msg     string `json:"msg"`
+	//耗时，单位毫秒
+	Duration int64 `json:"duration"`
+}
+
+// 审计记录查询条件，字段为空时不过滤
+type AuditQuery struct {
+	User      string
+	Cluster   string
+	Namespace string
+	Kind      string
+	Name      string
+	Action    string
+	//success或failure
+	Result    string
+	StartTime time.Time
+	EndTime   time.Time
+	//访问权限，只返回用户可见命名空间的记录
+	Allowed AccessFilter
+	Limit   int
+	Page    int
+}
+
+// 审计记录查询结果，按时间倒序
+type AuditResp struct {
+	Items []*AuditRecord `json:"items"`
+	Total int            `json:"total"`
+}
+
+// 审计记录的存储，先支持本地文件，后续可接入SQLite等数据库
+type AuditStore interface {
+	Name() string
+	Save(record *AuditRecord) error
+	//按条件查询，返回当前页的记录和满足条件的总数
+	Query(query *AuditQuery) (*AuditResp, error)
+}
+
+// 已注册的审计存储，key为存储名
+var auditStores = map[string]func() (AuditStore, error){}
+
+// 注册审计存储
+func registerAuditStore(name string, factory func() (AuditStore, error)) {
+	auditStores[name] = factory
+}
+
+// secret脱敏后的内容
+const redacted = "******"
+
+// 初始化审计存储
+func (a *audit) Init() {
+	factory, ok := auditStores[config.AuditStore]
+	if !ok {
+		logger.Error("审计存储" + config.AuditStore + "未注册")
+		return
+	}
+	store, err := factory()
+	if err != nil {
+		logger.Error("初始化审计存储失败", err)
+		return
+	}
+	a.store = store
+}
+
+// 保存审计记录，失败时只记录日志，不影响请求结果
+func (a *audit) Record(record *AuditRecord) {
+	if a.store == nil {
+		logger.Error("审计存储未初始化，丢弃审计记录", record.Method+" "+record.Path)
+		return
+	}
+	if err := a.store.Save(record); err != nil {
+		logger.Error("保存审计记录失败", err)
+	}
+}
+
+// 查询审计记录
+func (a *audit) Query(query *AuditQuery) (*AuditResp, error) {
+	if a.store == nil {
+		return nil, errors.New("审计存储未初始化")
+	}
+	switch query.Result {
+	case "", "success", "failure":
+	default:
+		return nil, errors.New("不支持的审计结果" + query.Result + "，只能为success或failure")
+	}
+	resp, err := a.store.Query(query)
+	if err != nil {
+		logger.Error("查询审计记录失败", err)
+		return nil, errors.New("查询审计记录失败" + err.Error())
+	}
+	return resp, nil
+}
+
+// 判断记录是否满足查询条件
+func (q *AuditQuery) match(record *AuditRecord) bool {
+	if q.User != "" && record.User != q.User {
+		return false
+	}
+	if q.Cluster != "" && record.Cluster != q.Cluster {
+		return false
+	}
+	if q.Namespace != "" && record.Namespace != q.Namespace {
+		return false
+	}
+	if q.Kind != "" && !containsString(strings.Split(record.Kind, ","), q.Kind) {
+		return false
+	}
+	if q.Name != "" && !strings.Contains(record.Name, q.Name) {
+		return false
+	}
+	if q.Action != "" && record.Action != q.Action {
+		return false
+	}
+	if q.Result != "" && record.Success != (q.Result == "success") {
+		return false
+	}
+	if !q.StartTime.IsZero() && record.Time.Before(q.StartTime) {
+		return false
+	}
+	if !q.EndTime.IsZero() && record.Time.After(q.EndTime) {
+		return false
+	}
+	if q.Allowed != nil && !q.Allowed(record.Namespace, record.Name) {
+		return false
+	}
+	return true
+}
+
+// 对请求参数中的secret脱敏，返回json字符串
+// kinds为接口操作的资源类型，包含secret时content按secret解析后脱敏，不依赖content中是否声明了kind
+// 请求体中的kind为Secret的对象直接脱敏，content等字符串按yaml或json清单解析，其中的secret同样脱敏
+func RedactPayload(body []byte, kinds []string) string {
+	var payload interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return ""
+	}
+	if params, ok := payload.(map[string]interface{}); ok && containsString(kinds, Secret.Name) {
+		if content, ok := params["content"].(string); ok {
+			params["content"] = redactSecretContent(content)
+		}
+	}
+	payload = redactValue(payload)
+	content, err := json.Marshal(payload)
+	if err != nil {
+		return ""
+	}
+	return string(content)
+}
+
+// 递归脱敏，对象中kind为Secret时隐藏data和stringData的值
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if kind, _ := v["kind"].(string); kind == "Secret" {
+			redactSecret(v)
+		}
+		for key, item := range v {
+			v[key] = redactValue(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactValue(item)
+		}
+		return v
+	case string:
+		return redactManifest(v)
+	}
+	return value
+}
+
+// 隐藏secret中data和stringData的值，保留key便于确认修改了哪些字段
+func redactSecret(secret map[string]interface{}) {
+	for _, field := range []string{"data", "stringData"} {
+		data, ok := secret[field].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for key := range data {
+			data[key] = redacted
+		}
+	}
+}
+
+// 按secret解析content并隐藏data和stringData的值，用于secret接口，无法解析时整体隐藏
+func redactSecretContent(content string) string {
+	if strings.TrimSpace(content) == "" {
+		return content
+	}
+	secret := map[string]interface{}{}
+	if err := yamlutil.NewYAMLOrJSONDecoder(strings.NewReader(content), 4096).Decode(&secret); err != nil {
+		return redacted
+	}
+	redactSecret(secret)
+	doc, err := yaml.Marshal(secret)
+	if err != nil {
+		return redacted
+	}
+	return string(doc)
+}
+
+// 清单字符串中包含secret时脱敏，其他字符串原样返回
+// 包含Secret但无法解析的清单整体隐藏，避免明文泄露
+func redactManifest(content string) string {
+	if !strings.Contains(content, "kind") || !strings.Contains(content, "Secret") {
+		return content
+	}
+	var docs []string
+	found := false
+	decoder := yamlutil.NewYAMLOrJSONDecoder(strings.NewReader(content), 4096)
+	for {
+		obj := map[string]interface{}{}
+		if err := decoder.Decode(&obj); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return redacted
+		}
+		if containsSecret(obj) {
+			found = true
+			redactValue(obj)
+		}
+		doc, err := yaml.Marshal(obj)
+		if err != nil {
+			return redacted
+		}
+		docs = append(docs, string(doc))
+	}
+	if !found {
+		return content
+	}
+	return strings.Join(docs, "---\n")
+}
+
+// 对象或List中是否包含secret
+func containsSecret(obj map[string]interface{}) bool {
+	if kind, _ := obj["kind"].(string); kind == "Secret" {
+		return true
+	}
+	items, _ := obj["items"].([]interface{})
+	for _, item := range items {
+		if item, ok := item.(map[string]interface{}); ok && containsSecret(item) {
+			return true
+		}
+	}
+	return false
+}
+
+// 获取content中所有对象的名称，用逗号分隔，用于审计记录，无法解析时返回空
+func ContentNames(content string) string {
+	objects, err := Manifest.decode(content)
+	if err != nil {
+		return ""
+	}
+	names := make([]string, 0, len(objects))
+	for _, obj := range objects {
+		names = append(names, obj.GetName())
+	}
+	return strings.Join(names, ",")
+}
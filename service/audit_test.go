@@ -0,0 +1,178 @@
+package service
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedactPayload(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		//接口操作的资源类型
+		kinds []string
+		//脱敏后不能出现的内容
+		hidden []string
+		//脱敏后应保留的内容
+		kept []string
+		want string
+	}{
+		{
+			name: "没有secret时原样返回",
+			body: `{"cluster":"TST-1","namespace":"dev","content":"apiVersion: v1\nkind: ConfigMap\ndata:\n  a: b\n"}`,
+			want: `{"cluster":"TST-1","content":"apiVersion: v1\nkind: ConfigMap\ndata:\n  a: b\n","namespace":"dev"}`,
+		},
+		{
+			name:   "json对象中的secret",
+			body:   `{"kind":"Secret","metadata":{"name":"db"},"data":{"password":"cGFzc3dvcmQ="},"stringData":{"token":"abc123"}}`,
+			hidden: []string{"cGFzc3dvcmQ=", "abc123"},
+			kept:   []string{`"password":"******"`, `"token":"******"`, `"name":"db"`},
+		},
+		{
+			name:   "content中的yaml清单",
+			body:   `{"cluster":"TST-1","content":"apiVersion: v1\nkind: Secret\nmetadata:\n  name: db\ndata:\n  password: cGFzc3dvcmQ=\n"}`,
+			hidden: []string{"cGFzc3dvcmQ="},
+			kept:   []string{"password: '******'", "name: db", `"cluster":"TST-1"`},
+		},
+		{
+			name:   "content中的json清单",
+			body:   `{"content":"{\"apiVersion\":\"v1\",\"kind\":\"Secret\",\"metadata\":{\"name\":\"db\"},\"stringData\":{\"token\":\"abc123\"}}"}`,
+			hidden: []string{"abc123"},
+			kept:   []string{"token: '******'"},
+		},
+		{
+			name:   "多文档清单只脱敏secret",
+			body:   `{"content":"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cfg\ndata:\n  mode: prod\n---\napiVersion: v1\nkind: Secret\nmetadata:\n  name: db\ndata:\n  password: cGFzc3dvcmQ=\n"}`,
+			hidden: []string{"cGFzc3dvcmQ="},
+			kept:   []string{"mode: prod", "password: '******'"},
+		},
+		{
+			name:   "List中的secret",
+			body:   `{"content":"apiVersion: v1\nkind: List\nitems:\n- apiVersion: v1\n  kind: Secret\n  metadata:\n    name: db\n  data:\n    password: cGFzc3dvcmQ=\n"}`,
+			hidden: []string{"cGFzc3dvcmQ="},
+			kept:   []string{"password: '******'"},
+		},
+		{
+			name:   "无法解析的secret清单整体隐藏",
+			body:   `{"content":"kind: Secret\ndata:\n  password: [cGFzc3dvcmQ="}`,
+			hidden: []string{"cGFzc3dvcmQ="},
+			want:   `{"content":"******"}`,
+		},
+		{
+			name:   "数组中的secret",
+			body:   `[{"kind":"Secret","data":{"password":"cGFzc3dvcmQ="}}]`,
+			hidden: []string{"cGFzc3dvcmQ="},
+			want:   `[{"data":{"password":"******"},"kind":"Secret"}]`,
+		},
+		{
+			name:   "secret接口中没有kind的yaml",
+			body:   `{"cluster":"TST-1","content":"metadata:\n  name: db\ndata:\n  password: cGFzc3dvcmQ=\n"}`,
+			kinds:  []string{"secret"},
+			hidden: []string{"cGFzc3dvcmQ="},
+			kept:   []string{"password: '******'", "name: db", `"cluster":"TST-1"`},
+		},
+		{
+			name:   "secret接口中没有kind的json",
+			body:   `{"content":"{\"metadata\":{\"name\":\"db\"},\"stringData\":{\"token\":\"abc123\"}}"}`,
+			kinds:  []string{"secret"},
+			hidden: []string{"abc123"},
+			kept:   []string{"token: '******'"},
+		},
+		{
+			name:   "secret接口中无法解析的content整体隐藏",
+			body:   `{"content":"data:\n  password: [cGFzc3dvcmQ="}`,
+			kinds:  []string{"secret"},
+			hidden: []string{"cGFzc3dvcmQ="},
+			want:   `{"content":"******"}`,
+		},
+		{
+			name:  "其他接口中没有kind的content原样保留",
+			body:  `{"content":"data:\n  mode: prod\n"}`,
+			kinds: []string{"configmap"},
+			want:  `{"content":"data:\n  mode: prod\n"}`,
+		},
+		{
+			name: "非json请求体",
+			body: `password=abc123`,
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RedactPayload([]byte(tt.body), tt.kinds)
+			if tt.want != "" || len(tt.kept) == 0 {
+				if got != tt.want {
+					t.Errorf("RedactPayload() = %s, want %s", got, tt.want)
+				}
+			}
+			for _, hidden := range tt.hidden {
+				if strings.Contains(got, hidden) {
+					t.Errorf("RedactPayload() = %s, should not contain %q", got, hidden)
+				}
+			}
+			for _, kept := range tt.kept {
+				if !strings.Contains(got, kept) {
+					t.Errorf("RedactPayload() = %s, want containing %q", got, kept)
+				}
+			}
+		})
+	}
+}
+
+func TestAuditQueryMatch(t *testing.T) {
+	now := time.Now()
+	record := &AuditRecord{Time: now, User: "bob", Cluster: "TST-1", Namespace: "dev", Kind: "deployment,svc",
+		Name: "web,web-svc", Action: VerbCreate, Success: true}
+	tests := []struct {
+		name  string
+		query AuditQuery
+		want  bool
+	}{
+		{name: "无条件", query: AuditQuery{}, want: true},
+		{name: "用户", query: AuditQuery{User: "alice"}, want: false},
+		{name: "多个资源类型中的一个", query: AuditQuery{Kind: "svc"}, want: true},
+		{name: "资源类型需完整匹配", query: AuditQuery{Kind: "sv"}, want: false},
+		{name: "名称包含", query: AuditQuery{Name: "web-s"}, want: true},
+		{name: "成功", query: AuditQuery{Result: "success"}, want: true},
+		{name: "失败", query: AuditQuery{Result: "failure"}, want: false},
+		{name: "时间范围内", query: AuditQuery{StartTime: now.Add(-time.Minute), EndTime: now.Add(time.Minute)}, want: true},
+		{name: "早于开始时间", query: AuditQuery{StartTime: now.Add(time.Minute)}, want: false},
+		{
+			name: "不可见的命名空间",
+			query: AuditQuery{Allowed: func(namespace, name string) bool {
+				return namespace == "prod"
+			}},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.query.match(record); got != tt.want {
+				t.Errorf("match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileAuditStore(t *testing.T) {
+	store, err := newFileAuditStore(filepath.Join(t.TempDir(), "audit.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := time.Now()
+	for i, name := range []string{"a", "b", "c"} {
+		if err = store.Save(&AuditRecord{Time: base.Add(time.Duration(i) * time.Second), User: "bob", Name: name, Success: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resp, err := store.Query(&AuditQuery{User: "bob", Limit: 2, Page: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	//最新的记录在前
+	if resp.Total != 3 || len(resp.Items) != 2 || resp.Items[0].Name != "c" || resp.Items[1].Name != "b" {
+		t.Errorf("Query() = total %d, items %+v", resp.Total, resp.Items)
+	}
+}
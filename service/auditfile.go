@@ -0,0 +1,84 @@
+package service
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"github.com/wonderivan/logger"
+	"k8s-platform/config"
+	"os"
+	"sync"
+)
+
+func init() {
+	registerAuditStore("file", func() (AuditStore, error) {
+		return newFileAuditStore(config.AuditFile)
+	})
+}
+
+// 本地文件审计存储，每行一条json格式的记录，只追加不修改
+// 查询时读取整个文件在内存中过滤，适合记录量不大的场景
+type fileAuditStore struct {
+	path string
+	lock sync.Mutex
+	file *os.File
+}
+
+func newFileAuditStore(path string) (*fileAuditStore, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		logger.Error("打开审计文件"+path+"失败", err)
+		return nil, errors.New("打开审计文件" + path + "失败" + err.Error())
+	}
+	return &fileAuditStore{path: path, file: file}, nil
+}
+
+func (s *fileAuditStore) Name() string {
+	return "file"
+}
+
+func (s *fileAuditStore) Save(record *AuditRecord) error {
+	content, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_, err = s.file.Write(append(content, '\n'))
+	return err
+}
+
+func (s *fileAuditStore) Query(query *AuditQuery) (*AuditResp, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	file, err := os.Open(s.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var records []*AuditRecord
+	scanner := bufio.NewScanner(file)
+	//请求参数可能包含较大的清单
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		record := new(AuditRecord)
+		if err = json.Unmarshal(scanner.Bytes(), record); err != nil {
+			logger.Error("审计记录反序列化失败", err)
+			continue
+		}
+		if query.match(record) {
+			records = append(records, record)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	//文件按时间顺序追加，倒序后最新的记录在前
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+	return &AuditResp{
+		Items: paginate(records, query.Limit, query.Page),
+		Total: len(records),
+	}, nil
+}
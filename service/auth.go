@@ -0,0 +1,181 @@
+package service
+
+import (
+	"crypto/rand"
+	"errors"
+	"github.com/wonderivan/logger"
+	"k8s-platform/config"
+	"time"
+)
+
+var Auth auth
+
+type auth struct {
+	provider IdentityProvider
+	secret   []byte
+}
+
+// 登录用户
+type User struct {
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	//用户所属的组，用于授权
+	Groups []string `json:"groups"`
+	//禁用的用户不能登录和刷新token
+	Disabled bool `json:"disabled"`
+}
+
+// 身份后端，校验用户名密码并提供用户信息，先支持本地文件，后续可接入LDAP、OIDC
+type IdentityProvider interface {
+	Name() string
+	//校验用户名密码，成功时返回用户信息
+	Authenticate(username, password string) (*User, error)
+	//按用户名获取用户，刷新token时确认用户仍然有效
+	GetUser(username string) (*User, error)
+}
+
+// 已注册的身份后端，key为后端名
+var identityProviders = map[string]func() (IdentityProvider, error){}
+
+// 注册身份后端
+func registerIdentityProvider(name string, factory func() (IdentityProvider, error)) {
+	identityProviders[name] = factory
+}
+
+// 登录或刷新成功后返回的token
+type TokenResp struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	//在该时间之前可以刷新token
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	User             *User     `json:"user"`
+}
+
+var (
+	errLoginFailed    = errors.New("用户名或密码错误")
+	errUserDisabled   = errors.New("用户已被禁用")
+	errRefreshExpired = errors.New("token已超过刷新期限，请重新登录")
+	errAuthNotReady   = errors.New("身份认证未初始化")
+)
+
+// 初始化身份后端和签名密钥，失败时返回错误，服务不能在未初始化的情况下启动
+func (a *auth) Init() error {
+	factory, ok := identityProviders[config.IdentityProvider]
+	if !ok {
+		return errors.New("身份后端" + config.IdentityProvider + "未注册")
+	}
+	provider, err := factory()
+	if err != nil {
+		return errors.New("初始化身份后端失败" + err.Error())
+	}
+	secret := []byte(config.JWTSecret)
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err = rand.Read(secret); err != nil {
+			return errors.New("生成jwt密钥失败" + err.Error())
+		}
+	}
+	a.provider, a.secret = provider, secret
+	return nil
+}
+
+// 身份后端和签名密钥都已初始化，未初始化时拒绝所有登录和校验，避免使用空密钥校验token
+func (a *auth) ready() error {
+	if a.provider == nil || len(a.secret) == 0 {
+		return errAuthNotReady
+	}
+	return nil
+}
+
+// 登录，校验用户名密码后签发token
+func (a *auth) Login(username, password string) (*TokenResp, error) {
+	if err := a.ready(); err != nil {
+		return nil, err
+	}
+	user, err := a.provider.Authenticate(username, password)
+	if err != nil {
+		logger.Warn("用户" + username + "登录失败: " + err.Error())
+		return nil, errLoginFailed
+	}
+	if user.Disabled {
+		return nil, errUserDisabled
+	}
+	return a.issue(user, time.Now())
+}
+
+// 刷新token，已过期但在刷新期限内的token也可以刷新，刷新时重新获取用户信息
+func (a *auth) Refresh(token string) (*TokenResp, error) {
+	if err := a.ready(); err != nil {
+		return nil, err
+	}
+	claims, err := parseToken(token, a.secret)
+	if err != nil {
+		return nil, err
+	}
+	authTime := time.Unix(claims.AuthTime, 0)
+	if time.Now().After(authTime.Add(config.TokenRefreshExpire)) {
+		return nil, errRefreshExpired
+	}
+	if claims.Provider != a.provider.Name() {
+		return nil, errTokenInvalid
+	}
+	user, err := a.provider.GetUser(claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if user.Disabled {
+		return nil, errUserDisabled
+	}
+	return a.issue(user, authTime)
+}
+
+// 校验token，返回token中的用户信息
+// 每次校验都重新获取用户，用户被删除或禁用后已签发的token立即失效
+func (a *auth) Verify(token string) (*Claims, error) {
+	if err := a.ready(); err != nil {
+		return nil, err
+	}
+	claims, err := parseToken(token, a.secret)
+	if err != nil {
+		return nil, err
+	}
+	if claims.expired(time.Now()) {
+		return nil, errTokenExpired
+	}
+	if claims.Provider != a.provider.Name() {
+		return nil, errTokenInvalid
+	}
+	user, err := a.provider.GetUser(claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if user.Disabled {
+		return nil, errUserDisabled
+	}
+	return claims, nil
+}
+
+// 签发token，authTime为首次登录的时间
+func (a *auth) issue(user *User, authTime time.Time) (*TokenResp, error) {
+	now := time.Now()
+	expiresAt := now.Add(config.TokenExpire)
+	token, err := signToken(&Claims{
+		Subject:   user.Username,
+		Provider:  a.provider.Name(),
+		Groups:    user.Groups,
+		Issuer:    jwtIssuer,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+		AuthTime:  authTime.Unix(),
+	}, a.secret)
+	if err != nil {
+		logger.Error("签发token失败", err)
+		return nil, errors.New("签发token失败" + err.Error())
+	}
+	return &TokenResp{
+		Token:            token,
+		ExpiresAt:        expiresAt,
+		RefreshExpiresAt: authTime.Add(config.TokenRefreshExpire),
+		User:             user,
+	}, nil
+}
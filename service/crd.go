@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/wonderivan/logger"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"sort"
+)
+
+var Crd crd
+
+type crd struct{}
+
+// 通过discovery发现的资源类型
+type ApiResource struct {
+	Group      string   `json:"group"`
+	Version    string   `json:"version"`
+	Resource   string   `json:"resource"`
+	Kind       string   `json:"kind"`
+	Namespaced bool     `json:"namespaced"`
+	Verbs      []string `json:"verbs"`
+}
+
+// 定义crd列表的返回内容 items是资源列表 total为过滤后的元素总数
+// 服务端分页时total为当前页的元素数，continue为下一页的游标，remaining为apiserver估计的剩余元素数
+type CrdsResp struct {
+	Item      []unstructured.Unstructured `json:"items"`
+	Total     int                         `json:"total"`
+	Continue  string                      `json:"continue,omitempty"`
+	Remaining *int64                      `json:"remaining,omitempty"`
+}
+
+// 获取集群中所有的api资源类型，每个group只返回首选版本
+func (c *crd) GetApiResources(ctx context.Context, cluster string) (apiResources []*ApiResource, err error) {
+	client, err := K8s.GetClient(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	resourceLists, err := client.Discovery().ServerPreferredResources()
+	if err != nil {
+		//部分group发现失败(如metrics-server不可用)时仍返回其余可用的资源
+		if !discovery.IsGroupDiscoveryFailedError(err) {
+			logger.Error("获取api资源失败", err)
+			return nil, errors.New("获取api资源失败" + err.Error())
+		}
+		logger.Error("部分api group发现失败", err)
+	}
+	for _, resourceList := range resourceLists {
+		gv, err := schema.ParseGroupVersion(resourceList.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, resource := range resourceList.APIResources {
+			apiResources = append(apiResources, &ApiResource{
+				Group:      gv.Group,
+				Version:    gv.Version,
+				Resource:   resource.Name,
+				Kind:       resource.Kind,
+				Namespaced: resource.Namespaced,
+				Verbs:      resource.Verbs,
+			})
+		}
+	}
+	sort.Slice(apiResources, func(i, j int) bool {
+		if apiResources[i].Group != apiResources[j].Group {
+			return apiResources[i].Group < apiResources[j].Group
+		}
+		return apiResources[i].Resource < apiResources[j].Resource
+	})
+	return apiResources, nil
+}
+
+// 获取资源列表，namespace为空时获取所有命名空间，支持过滤、排序、分页
+func (c *crd) GetCrds(ctx context.Context, cluster string, gvr schema.GroupVersionResource, namespace string, query *DataSelect) (crdsResp *CrdsResp, err error) {
+	if gvr, namespace, err = c.resolve(cluster, gvr, namespace); err != nil {
+		return nil, err
+	}
+	client, err := K8s.GetDynamicClient(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	if err = query.FilterQuery.Parse(); err != nil {
+		return nil, err
+	}
+	//unstructured资源只支持通用的排序字段
+	if err = query.SortQuery.Parse(nil); err != nil {
+		return nil, err
+	}
+	//标签和字段选择器下推到apiserver，服务端分页时同时下推limit和continue
+	var options metav1.ListOptions
+	if query.PaginateQuery.ServerSide {
+		if options, err = query.ChunkListOptions(); err != nil {
+			return nil, err
+		}
+	} else {
+		options = query.FilterQuery.TakeListOptions()
+	}
+	list, err := client.Resource(gvr).Namespace(namespace).List(ctx, options)
+	if err != nil {
+		logger.Error("获取"+gvr.Resource+"列表失败", err)
+		return nil, errors.New("获取" + gvr.Resource + "列表失败" + err.Error())
+	}
+	if query.PaginateQuery.ServerSide {
+		data := (&DataSelector{GenericDataList: c.toCells(list.Items), DataSelectQuery: query}).Filter()
+		return &CrdsResp{
+			Item:      c.fromCells(data.GenericDataList),
+			Total:     len(data.GenericDataList),
+			Continue:  list.GetContinue(),
+			Remaining: list.GetRemainingItemCount(),
+		}, nil
+	}
+	//实例化DataSelector对象
+	selectableData := &DataSelector{
+		GenericDataList: c.toCells(list.Items),
+		DataSelectQuery: query,
+	}
+	//先过滤
+	filtered := selectableData.Filter()
+	//再拿total
+	total := len(filtered.GenericDataList)
+	//在排序和分页
+	data := filtered.Sort().Paginate()
+	return &CrdsResp{
+		Item:  c.fromCells(data.GenericDataList),
+		Total: total,
+	}, nil
+}
+
+// 获取资源详情
+func (c *crd) GetCrdDetail(ctx context.Context, cluster string, gvr schema.GroupVersionResource, name, namespace string) (obj *unstructured.Unstructured, err error) {
+	if gvr, namespace, err = c.resolve(cluster, gvr, namespace); err != nil {
+		return nil, err
+	}
+	client, err := K8s.GetDynamicClient(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	obj, err = client.Resource(gvr).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		logger.Error("获取" + gvr.Resource + "详情失败" + err.Error())
+		return nil, errors.New("获取" + gvr.Resource + "详情失败" + err.Error())
+	}
+	return obj, nil
+}
+
+// 删除资源
+func (c *crd) DeleteCrd(ctx context.Context, cluster string, gvr schema.GroupVersionResource, name, namespace string) (err error) {
+	client, err := K8s.GetDynamicClient(ctx, cluster)
+	if err != nil {
+		return err
+	}
+	err = client.Resource(gvr).Namespace(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil {
+		logger.Error("删除" + gvr.Resource + "失败" + err.Error())
+		return errors.New("删除" + gvr.Resource + "失败" + err.Error())
+	}
+	return nil
+}
+
+// 更新资源，content为资源的yaml或json，返回服务端保存后的对象
+// options.Apply为true时使用server-side apply，options.DryRun为true时不持久化
+func (c *crd) UpdateCrd(ctx context.Context, cluster string, gvr schema.GroupVersionResource, namespace, content string, options UpdateOptions) (obj *unstructured.Unstructured, err error) {
+	client, err := K8s.GetDynamicClient(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	//将content反序列化成为unstructured对象
+	obj, err = decodeUnstructured(content)
+	if err != nil {
+		logger.Error("Content反序列化失败", err)
+		return nil, err
+	}
+	if err = bindNamespace(obj, namespace); err != nil {
+		return nil, err
+	}
+	if options.Apply {
+		var patch []byte
+		if patch, err = applyPatch(content, obj.GroupVersionKind()); err != nil {
+			return nil, err
+		}
+		obj, err = client.Resource(gvr).Namespace(namespace).Patch(ctx, obj.GetName(), types.ApplyPatchType, patch, options.patchOptions())
+	} else {
+		obj, err = client.Resource(gvr).Namespace(namespace).Update(ctx, obj, options.updateOptions())
+	}
+	if err != nil {
+		logger.Error("更新" + gvr.Resource + "失败" + err.Error())
+		return nil, errors.New("更新" + gvr.Resource + "失败" + err.Error())
+	}
+	return obj, nil
+}
+
+// 对比线上对象和编辑后的content，content与UpdateCrd的参数相同
+func (c *crd) DiffCrd(ctx context.Context, cluster string, gvr schema.GroupVersionResource, namespace, content string) (result *DiffResult, err error) {
+	edited, err := decodeUnstructured(content)
+	if err != nil {
+		return nil, err
+	}
+	if err = bindNamespace(edited, namespace); err != nil {
+		return nil, err
+	}
+	live, err := c.GetCrdDetail(ctx, cluster, gvr, edited.GetName(), namespace)
+	if err != nil {
+		return nil, err
+	}
+	result, err = diffObjects(live.Object, edited.Object)
+	if errors.Is(err, ErrDiffTooLarge) {
+		return nil, err
+	}
+	if err != nil {
+		logger.Error("对比"+gvr.Resource+"失败", err)
+		return nil, errors.New("对比" + gvr.Resource + "失败" + err.Error())
+	}
+	return result, nil
+}
+
+// 通过RESTMapper确认资源类型存在并补全版本，集群级资源忽略传入的namespace
+func (c *crd) resolve(cluster string, gvr schema.GroupVersionResource, namespace string) (schema.GroupVersionResource, string, error) {
+	resolved, namespaced, err := ResolveResource(cluster, gvr)
+	if err != nil {
+		logger.Error("获取"+gvr.Resource+"的资源类型失败", err)
+		return gvr, "", err
+	}
+	if !namespaced {
+		namespace = ""
+	}
+	return resolved, namespace, nil
+}
+
+// 把unstructured转成datacell，unstructured实现了metav1.Object，可以直接使用objectCell
+// 状态取自status.phase，没有该字段的资源状态为空
+func (c *crd) toCells(std []unstructured.Unstructured) []DataCell {
+	cells := make([]DataCell, len(std))
+	for i := range std {
+		phase, _, _ := unstructured.NestedString(std[i].Object, "status", "phase")
+		cells[i] = objectCell{Object: &std[i], status: phase, fieldsSet: objectFields(&std[i])}
+	}
+	return cells
+}
+
+// 把datacell转成unstructured
+func (c *crd) fromCells(cells []DataCell) []unstructured.Unstructured {
+	items := make([]unstructured.Unstructured, len(cells))
+	for i := range cells {
+		items[i] = *cells[i].(objectCell).Object.(*unstructured.Unstructured)
+	}
+	return items
+}
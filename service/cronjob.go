@@ -0,0 +1,20 @@
+package service
+
+import (
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// cronjob，列表、详情、删除、更新、创建由通用资源Resource提供
+var CronJob = newResource[batchv1.CronJob]("cronjob", batchv1.SchemeGroupVersion.WithResource("cronjobs"), true,
+	func(clientset kubernetes.Interface, namespace string) typedClient[*batchv1.CronJob] {
+		return clientset.BatchV1().CronJobs(namespace)
+	}).withStatus(cronJobStatus)
+
+// cronjob状态，暂停时为Suspended，否则为Active
+func cronJobStatus(obj *batchv1.CronJob) string {
+	if obj.Spec.Suspend != nil && *obj.Spec.Suspend {
+		return "Suspended"
+	}
+	return "Active"
+}
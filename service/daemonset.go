@@ -2,109 +2,35 @@ package service
 
 import (
 	"context"
-	"errors"
-	"github.com/wonderivan/logger"
 	appsv1 "k8s.io/api/apps/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/client-go/kubernetes"
 )
 
-var DaemonSet daemonSet
+// daemonset，列表、详情、删除、更新、创建由通用资源Resource提供
+var DaemonSet = daemonSet{newResource[appsv1.DaemonSet]("daemonset", appsv1.SchemeGroupVersion.WithResource("daemonsets"), true,
+	func(clientset kubernetes.Interface, namespace string) typedClient[*appsv1.DaemonSet] {
+		return clientset.AppsV1().DaemonSets(namespace)
+	}).withStatus(daemonSetStatus).
+	withProperty("ready", daemonSetReady)}
 
-type daemonSet struct{}
-
-// 定义daemonSets的返回内容 items是daemonSet列表吗total为daemonSet元素总数
-type DaemonSetsResp struct {
-	Item  []appsv1.DaemonSet `json:"items"`
-	Total int                `json:"total"`
-}
-
-// 获取daemonSet列表
-func (p *daemonSet) GetDaemonSet(filterName, namespace string, limit, page int) (daemonSetsResp *DaemonSetsResp, err error) {
-	//通过clientset获取daemonSets完整列表
-	daemonSetList, err := K8s.ClientSet.AppsV1().DaemonSets(namespace).List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		logger.Error("获取daemonSet列表失败", err)
-		return nil, errors.New("获取daemonSet列表失败" + err.Error())
-	}
-	//实例化DataSelector对象
-	selectableData := &DataSelector{
-		GenericDataList: p.toCells(daemonSetList.Items),
-		DataSelectQuery: &DataSelect{
-			FilterQuery: &Filter{filterName},
-			PaginateQuery: &Paginate{
-				Limit: limit,
-				Page:  page,
-			},
-		},
-	}
-	//先过滤
-	filtered := selectableData.Filter()
-	//再拿total
-	total := len(filtered.GenericDataList)
-	//在排序和分页
-	data := filtered.Sort().Paginate()
-	//再将datacell切片数据转成原生daemonSet切片
-	daemonSets := p.fromCells(data.GenericDataList)
-	//返回
-	return &DaemonSetsResp{
-		Item:  daemonSets,
-		Total: total,
-	}, nil
+type daemonSet struct {
+	*Resource[appsv1.DaemonSet, *appsv1.DaemonSet]
 }
 
-// 获取daemonSet详情
-func (p *daemonSet) GetDaemonSetDetail(daemonSetName, namespace string) (daemonSet *appsv1.DaemonSet, err error) {
-	daemonSet, err = K8s.ClientSet.AppsV1().DaemonSets(namespace).Get(context.TODO(), daemonSetName, metav1.GetOptions{})
-	if err != nil {
-		logger.Error("获取DaemonSet详情失败" + err.Error())
-		return nil, errors.New("获取DaemonSet详情失败" + err.Error())
-	}
-	return daemonSet, nil
+// 重启daemonset
+func (d *daemonSet) RestartDaemonSet(ctx context.Context, cluster, daemonSetName, namespace string) (err error) {
+	return d.restart(ctx, cluster, daemonSetName, namespace)
 }
 
-// 删除daemonSet
-func (p *daemonSet) DeleteDaemonSet(daemonSetName, namespace string) (err error) {
-	err = K8s.ClientSet.AppsV1().DaemonSets(namespace).Delete(context.TODO(), daemonSetName, metav1.DeleteOptions{})
-	if err != nil {
-		logger.Error("删除DaemonSet失败" + err.Error())
-		return errors.New("获取DaemonSet详情失败" + err.Error())
+// daemonset状态，所有调度的节点上pod都可用时为Available，否则为Unavailable
+func daemonSetStatus(obj *appsv1.DaemonSet) string {
+	if obj.Status.NumberAvailable >= obj.Status.DesiredNumberScheduled {
+		return "Available"
 	}
-	return nil
+	return "Unavailable"
 }
 
-// 更新daemonSet
-func (p *daemonSet) UpdateDaemonSet(namespace, content string) (err error) {
-	//将content反序列化成为daemonSet对象
-	var daemonSet = &appsv1.DaemonSet{}
-	if err = json.Unmarshal([]byte(content), daemonSet); err != nil {
-		logger.Error("Content反序列化失败", err)
-		return errors.New("Content反序列化失败" + err.Error())
-	}
-	//更新daemonSet
-	_, err = K8s.ClientSet.AppsV1().DaemonSets(namespace).Update(context.TODO(), daemonSet, metav1.UpdateOptions{})
-	if err != nil {
-		logger.Error("更新DaemonSet失败" + err.Error())
-		return errors.New("更新DaemonSet失败" + err.Error())
-	}
-	return nil
-}
-
-// 把daemonSetCell转成appsv1 daemonSet
-func (p *daemonSet) fromCells(cells []DataCell) []appsv1.DaemonSet {
-	daemonSets := make([]appsv1.DaemonSet, len(cells))
-	for i := range cells {
-		daemonSets[i] = appsv1.DaemonSet(cells[i].(daemonSetCell))
-	}
-	return daemonSets
-}
-
-// 把appsv1 daemonSets转成datacell
-func (p *daemonSet) toCells(std []appsv1.DaemonSet) []DataCell {
-	cells := make([]DataCell, len(std))
-	for i := range std {
-		cells[i] = daemonSetCell(std[i])
-
-	}
-	return cells
+// daemonset的就绪pod数
+func daemonSetReady(obj *appsv1.DaemonSet) ComparableValue {
+	return StdComparableInt(obj.Status.NumberReady)
 }
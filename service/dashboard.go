@@ -0,0 +1,311 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/wonderivan/logger"
+	"k8s-platform/config"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sort"
+	"time"
+)
+
+var Dashboard dashboard
+
+type dashboard struct{}
+
+// 统计告警事件的时间范围
+const warningEventWindow = time.Hour
+
+// 总览，cluster为集群维度的汇总，namespaces为各命名空间的统计
+type DashboardResp struct {
+	Cluster *NamespaceOverview `json:"cluster"`
+	//没有node列表权限时为空
+	Nodes      *NodeOverview        `json:"nodes"`
+	Namespaces []*NamespaceOverview `json:"namespaces"`
+}
+
+// 命名空间或集群的统计
+type NamespaceOverview struct {
+	//集群维度时为空
+	Namespace string `json:"namespace"`
+	//各类工作负载的数量，key为资源名，如deployment、statefulset
+	Workloads map[string]int `json:"workloads"`
+	//各phase的pod数量
+	Pods map[string]int `json:"pods"`
+	//最近一小时的Warning事件数
+	WarningEvents int `json:"warning_events"`
+	//cpu单位为毫核
+	Cpu ResourceOverview `json:"cpu"`
+	//内存单位为字节
+	Memory ResourceOverview `json:"memory"`
+}
+
+// 资源的requests、limits之和，只统计未结束的pod
+type ResourceOverview struct {
+	Requests int64 `json:"requests"`
+	Limits   int64 `json:"limits"`
+	//节点可分配量之和，只有集群维度且有node列表权限时有值
+	Allocatable int64 `json:"allocatable,omitempty"`
+}
+
+// 节点就绪情况
+type NodeOverview struct {
+	Total         int `json:"total"`
+	Ready         int `json:"ready"`
+	NotReady      int `json:"not_ready"`
+	Unschedulable int `json:"unschedulable"`
+}
+
+// 获取集群总览，每类资源只从informer缓存List一次，事件只请求一次apiserver
+// namespace不为空时namespaces中只返回该命名空间，集群维度的汇总不受影响
+// allowed不为空时只统计用户可见的命名空间，集群维度的汇总也只包含这些命名空间
+// withNodes为false时不返回节点就绪情况和可分配资源，用于没有node列表权限的用户
+// 开启模拟用户时以用户身份逐个可见命名空间获取，k8s中没有权限的命名空间不统计，避免只有命名空间权限的用户整体失败
+func (d *dashboard) GetDashboard(ctx context.Context, cluster, namespace string, allowed AccessFilter, withNodes bool) (resp *DashboardResp, err error) {
+	resp = &DashboardResp{
+		Cluster: newNamespaceOverview(""),
+	}
+	visible := func(ns string) bool {
+		return allowed == nil || allowed(ns, "")
+	}
+	overviews := map[string]*NamespaceOverview{}
+	overview := func(ns string) *NamespaceOverview {
+		if _, ok := overviews[ns]; !ok {
+			overviews[ns] = newNamespaceOverview(ns)
+		}
+		return overviews[ns]
+	}
+	//命名空间名称取自管理员的缓存，按平台授权过滤
+	namespaces, err := Namespace.listFromInformer(cluster, "", labels.Everything())
+	if err != nil {
+		return nil, d.listError("namespace", err)
+	}
+	var visibleNamespaces []string
+	for _, ns := range namespaces {
+		if visible(ns.Name) {
+			overview(ns.Name)
+			visibleNamespaces = append(visibleNamespaces, ns.Name)
+		}
+	}
+	//各类工作负载的数量
+	workloads := map[string]func() ([]string, error){
+		Deployment.Name:  func() ([]string, error) { return namespacesOf(ctx, Deployment.Resource, cluster, visibleNamespaces) },
+		StatefulSet.Name: func() ([]string, error) { return namespacesOf(ctx, StatefulSet.Resource, cluster, visibleNamespaces) },
+		DaemonSet.Name:   func() ([]string, error) { return namespacesOf(ctx, DaemonSet.Resource, cluster, visibleNamespaces) },
+		Job.Name:         func() ([]string, error) { return namespacesOf(ctx, Job, cluster, visibleNamespaces) },
+		CronJob.Name:     func() ([]string, error) { return namespacesOf(ctx, CronJob, cluster, visibleNamespaces) },
+	}
+	for name, list := range workloads {
+		items, err := list()
+		if err != nil {
+			return nil, d.listError(name, err)
+		}
+		resp.Cluster.Workloads[name] = 0
+		for _, ns := range items {
+			if visible(ns) {
+				resp.Cluster.Workloads[name]++
+				overview(ns).Workloads[name]++
+			}
+		}
+	}
+	//pod数量按phase统计，资源只统计未结束的pod
+	pods, err := dashboardList(ctx, Pod.Resource, cluster, visibleNamespaces)
+	if err != nil {
+		return nil, d.listError(Pod.Name, err)
+	}
+	for _, pod := range pods {
+		if !visible(pod.Namespace) {
+			continue
+		}
+		targets := []*NamespaceOverview{resp.Cluster, overview(pod.Namespace)}
+		for _, target := range targets {
+			target.Workloads[Pod.Name]++
+			target.Pods[string(pod.Status.Phase)]++
+		}
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		requests, limits := podRequestsAndLimits(pod)
+		for _, target := range targets {
+			target.Cpu.Requests += requests.Cpu().MilliValue()
+			target.Cpu.Limits += limits.Cpu().MilliValue()
+			target.Memory.Requests += requests.Memory().Value()
+			target.Memory.Limits += limits.Memory().Value()
+		}
+	}
+	//节点就绪情况和可分配资源
+	if withNodes {
+		if resp.Nodes, err = d.nodeOverview(ctx, cluster, resp.Cluster); err != nil {
+			return nil, err
+		}
+	}
+	//最近一小时的告警事件，获取失败时只记录日志，不影响其他统计
+	if err = d.countWarningEvents(ctx, cluster, visibleNamespaces, resp.Cluster, overview, visible); err != nil {
+		logger.Warn("统计告警事件失败，总览中不包含告警事件数: " + err.Error())
+	}
+	for ns, item := range overviews {
+		if namespace == "" || ns == namespace {
+			resp.Namespaces = append(resp.Namespaces, item)
+		}
+	}
+	sort.Slice(resp.Namespaces, func(i, j int) bool {
+		return resp.Namespaces[i].Namespace < resp.Namespaces[j].Namespace
+	})
+	return resp, nil
+}
+
+// 统计节点就绪情况，可分配资源累加到集群维度的汇总
+// 模拟用户在k8s中没有node列表权限时不返回节点信息
+func (d *dashboard) nodeOverview(ctx context.Context, cluster string, total *NamespaceOverview) (*NodeOverview, error) {
+	nodes, err := Node.listFromCache(ctx, cluster, "", labels.Everything())
+	if apierrors.IsForbidden(err) {
+		logger.Warn("用户没有node列表权限，总览中不包含节点信息: " + err.Error())
+		return nil, nil
+	}
+	if err != nil {
+		return nil, d.listError(Node.Name, err)
+	}
+	overview := &NodeOverview{}
+	for _, node := range nodes {
+		overview.Total++
+		if nodeStatus(node) == "Ready" {
+			overview.Ready++
+		} else {
+			overview.NotReady++
+		}
+		if node.Spec.Unschedulable {
+			overview.Unschedulable++
+		}
+		total.Cpu.Allocatable += node.Status.Allocatable.Cpu().MilliValue()
+		total.Memory.Allocatable += node.Status.Allocatable.Memory().Value()
+	}
+	return overview, nil
+}
+
+// 统计最近一小时的Warning事件，事件不在informer缓存中，直接请求apiserver并按类型过滤
+// 开启模拟用户时逐个可见命名空间获取
+func (d *dashboard) countWarningEvents(ctx context.Context, cluster string, namespaces []string, total *NamespaceOverview, overview func(ns string) *NamespaceOverview, visible func(ns string) bool) error {
+	client, err := K8s.GetClient(ctx, cluster)
+	if err != nil {
+		return err
+	}
+	if !config.Impersonate {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+	var events []corev1.Event
+	for _, ns := range namespaces {
+		list, err := client.CoreV1().Events(ns).List(ctx, metav1.ListOptions{FieldSelector: "type=" + corev1.EventTypeWarning})
+		if apierrors.IsForbidden(err) {
+			continue
+		}
+		if err != nil {
+			return d.listError("event", err)
+		}
+		events = append(events, list.Items...)
+	}
+	since := time.Now().Add(-warningEventWindow)
+	for i := range events {
+		if eventTime(&events[i]).Before(since) || !visible(events[i].Namespace) {
+			continue
+		}
+		total.WarningEvents++
+		overview(events[i].Namespace).WarningEvents++
+	}
+	return nil
+}
+
+func (d *dashboard) listError(name string, err error) error {
+	logger.Error("获取"+name+"列表失败", err)
+	return errors.New("获取" + name + "列表失败" + err.Error())
+}
+
+func newNamespaceOverview(namespace string) *NamespaceOverview {
+	return &NamespaceOverview{
+		Namespace: namespace,
+		Workloads: map[string]int{},
+		Pods:      map[string]int{},
+	}
+}
+
+// 获取可见命名空间中的资源，返回每个资源所在的命名空间
+func namespacesOf[T any, PT Object[T]](ctx context.Context, r *Resource[T, PT], cluster string, namespaces []string) ([]string, error) {
+	items, err := dashboardList(ctx, r, cluster, namespaces)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, len(items))
+	for i, item := range items {
+		result[i] = item.GetNamespace()
+	}
+	return result, nil
+}
+
+// 获取总览统计的资源，未开启模拟用户时从缓存中获取所有命名空间的资源，由调用方按可见命名空间过滤
+// 开启模拟用户时以用户身份逐个命名空间获取，用户在k8s中没有权限的命名空间跳过
+func dashboardList[T any, PT Object[T]](ctx context.Context, r *Resource[T, PT], cluster string, namespaces []string) ([]PT, error) {
+	if !config.Impersonate {
+		return r.listFromCache(ctx, cluster, "", labels.Everything())
+	}
+	var items []PT
+	for _, ns := range namespaces {
+		list, err := r.listFromCache(ctx, cluster, ns, labels.Everything())
+		if apierrors.IsForbidden(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, list...)
+	}
+	return items, nil
+}
+
+// pod的requests和limits，与kubectl describe node的计算方式一致
+// 取所有容器之和与单个init容器的较大值，再加上pod的overhead
+func podRequestsAndLimits(pod *corev1.Pod) (requests, limits corev1.ResourceList) {
+	requests, limits = corev1.ResourceList{}, corev1.ResourceList{}
+	for _, container := range pod.Spec.Containers {
+		addResourceList(requests, container.Resources.Requests)
+		addResourceList(limits, container.Resources.Limits)
+	}
+	for _, container := range pod.Spec.InitContainers {
+		maxResourceList(requests, container.Resources.Requests)
+		maxResourceList(limits, container.Resources.Limits)
+	}
+	if pod.Spec.Overhead != nil {
+		addResourceList(requests, pod.Spec.Overhead)
+		//只有设置了limits的资源才加上overhead
+		for name, quantity := range pod.Spec.Overhead {
+			if value, ok := limits[name]; ok {
+				value.Add(quantity)
+				limits[name] = value
+			}
+		}
+	}
+	return requests, limits
+}
+
+// 把new中的资源量累加到list
+func addResourceList(list, new corev1.ResourceList) {
+	for name, quantity := range new {
+		if value, ok := list[name]; ok {
+			value.Add(quantity)
+			list[name] = value
+		} else {
+			list[name] = quantity.DeepCopy()
+		}
+	}
+}
+
+// list中的资源量取与new的较大值
+func maxResourceList(list, new corev1.ResourceList) {
+	for name, quantity := range new {
+		if value, ok := list[name]; !ok || quantity.Cmp(value) > 0 {
+			list[name] = quantity.DeepCopy()
+		}
+	}
+}
@@ -1,9 +1,11 @@
 package service
 
 import (
-	appsv1 "k8s.io/api/apps/v1"
-	corev1 "k8s.io/api/core/v1"
-	newv1 "k8s.io/api/networking/v1"
+	"errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"regexp"
 	"sort"
 	"strings"
 	"time"
@@ -19,20 +21,211 @@ type DataSelector struct {
 type DataCell interface {
 	GetCreation() time.Time
 	GetName() string
+	GetNamespace() string
+	GetLabels() map[string]string
+	//资源状态，如pod的phase、node的Ready/NotReady，没有状态的资源返回空
+	GetStatus() string
+	//字段选择器可匹配的字段
+	GetFields() fields.Set
+	//获取可排序的属性，不支持的属性返回nil
+	GetProperty(name string) ComparableValue
 }
 
-// DataSelect 定义过滤和分页的属性
+// DataSelect 定义过滤、排序和分页的属性
 type DataSelect struct {
 	FilterQuery   *Filter
+	SortQuery     *SortQuery
 	PaginateQuery *Paginate
 }
 type Filter struct {
 	Name string
+	//Name按正则匹配，默认为包含匹配
+	Regex bool
+	//Name忽略大小写
+	IgnoreCase bool
+	//标签选择器，如app=foo,tier!=db
+	LabelSelector string
+	//字段选择器，如status.phase=Running
+	FieldSelector string
+	//状态，忽略大小写，如Running、Ready、Available
+	Status string
+	//命名空间，列出所有命名空间后只保留其中的资源，为空时不过滤
+	Namespaces []string
+	//访问权限，只保留用户可见的资源，为空时不过滤
+	Allowed AccessFilter
+
+	//Parse解析后的结果，下推到ListOptions或缓存的选择器会被置空，不再在内存中重复过滤
+	nameRegexp    *regexp.Regexp
+	labelSelector labels.Selector
+	fieldSelector fields.Selector
+}
+
+// 解析正则和选择器，参数不合法时返回错误
+func (f *Filter) Parse() error {
+	if f.Regex && f.Name != "" {
+		expr := f.Name
+		if f.IgnoreCase {
+			expr = "(?i)" + expr
+		}
+		nameRegexp, err := regexp.Compile(expr)
+		if err != nil {
+			return errors.New("名称正则表达式不合法" + err.Error())
+		}
+		f.nameRegexp = nameRegexp
+	}
+	if f.LabelSelector != "" {
+		selector, err := labels.Parse(f.LabelSelector)
+		if err != nil {
+			return errors.New("标签选择器不合法" + err.Error())
+		}
+		f.labelSelector = selector
+	}
+	if f.FieldSelector != "" {
+		selector, err := fields.ParseSelector(f.FieldSelector)
+		if err != nil {
+			return errors.New("字段选择器不合法" + err.Error())
+		}
+		f.fieldSelector = selector
+	}
+	return nil
+}
+
+// 取出标签和字段选择器用于下推到缓存，之后Filter不再按选择器过滤，没有选择器时返回Everything
+func (f *Filter) TakeSelectors() (labels.Selector, fields.Selector) {
+	labelSelector, fieldSelector := f.labelSelector, f.fieldSelector
+	f.labelSelector, f.fieldSelector = nil, nil
+	if labelSelector == nil {
+		labelSelector = labels.Everything()
+	}
+	if fieldSelector == nil {
+		fieldSelector = fields.Everything()
+	}
+	return labelSelector, fieldSelector
+}
+
+// 取出标签和字段选择器下推到ListOptions，之后Filter只处理其余条件
+func (f *Filter) TakeListOptions() metav1.ListOptions {
+	options := metav1.ListOptions{}
+	if f.labelSelector != nil {
+		options.LabelSelector = f.labelSelector.String()
+		f.labelSelector = nil
+	}
+	if f.fieldSelector != nil {
+		options.FieldSelector = f.fieldSelector.String()
+		f.fieldSelector = nil
+	}
+	return options
+}
+
+// 判断元素是否满足所有过滤条件
+func (f *Filter) match(cell DataCell) bool {
+	if f.Name != "" {
+		if f.nameRegexp != nil {
+			if !f.nameRegexp.MatchString(cell.GetName()) {
+				return false
+			}
+		} else if f.IgnoreCase {
+			if !strings.Contains(strings.ToLower(cell.GetName()), strings.ToLower(f.Name)) {
+				return false
+			}
+		} else if !strings.Contains(cell.GetName(), f.Name) {
+			return false
+		}
+	}
+	if len(f.Namespaces) > 0 && !containsString(f.Namespaces, cell.GetNamespace()) {
+		return false
+	}
+	if f.Allowed != nil && !f.Allowed(cell.GetNamespace(), cell.GetName()) {
+		return false
+	}
+	if f.Status != "" && !strings.EqualFold(cell.GetStatus(), f.Status) {
+		return false
+	}
+	if f.labelSelector != nil && !f.labelSelector.Matches(labels.Set(cell.GetLabels())) {
+		return false
+	}
+	if f.fieldSelector != nil && !f.fieldSelector.Matches(cell.GetFields()) {
+		return false
+	}
+	return true
+}
+
+// 判断切片中是否包含字符串
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// SortQuery 排序规则，为空时按创建时间倒序
+type SortQuery struct {
+	//逗号分隔的排序字段，字段后可加:asc或:desc，默认升序，如restarts:desc,name
+	SortBy string
+
+	//Parse解析后的排序字段
+	sortFields []sortField
+}
+
+type sortField struct {
+	property  string
+	ascending bool
+}
+
+// 所有资源都支持的排序字段
+var commonProperties = []string{"name", "namespace", "creationTimestamp", "status"}
+
+// 解析排序规则，properties为资源额外声明的排序字段，字段不支持时返回错误
+func (s *SortQuery) Parse(properties []string) error {
+	if s == nil {
+		return nil
+	}
+	s.sortFields = nil
+	if strings.TrimSpace(s.SortBy) == "" {
+		return nil
+	}
+	for _, item := range strings.Split(s.SortBy, ",") {
+		property, order, _ := strings.Cut(strings.TrimSpace(item), ":")
+		if !containsString(commonProperties, property) && !containsString(properties, property) {
+			return errors.New("不支持的排序字段" + property)
+		}
+		field := sortField{property: property, ascending: true}
+		switch order {
+		case "", "asc":
+		case "desc":
+			field.ascending = false
+		default:
+			return errors.New("不支持的排序方式" + order)
+		}
+		s.sortFields = append(s.sortFields, field)
+	}
+	return nil
 }
 
 type Paginate struct {
 	Limit int
 	Page  int
+	//服务端分页，使用ListOptions的Limit和Continue直接从apiserver分批获取，忽略Page
+	ServerSide bool
+	//服务端分页的游标，取自上一页返回的continue，第一页为空
+	Continue string
+}
+
+// 服务端分页的ListOptions，标签和字段选择器一并下推到apiserver
+// apiserver按存储顺序返回，无法整体排序，因此服务端分页不支持排序
+func (d *DataSelect) ChunkListOptions() (metav1.ListOptions, error) {
+	if d.SortQuery != nil && len(d.SortQuery.sortFields) > 0 {
+		return metav1.ListOptions{}, errors.New("服务端分页不支持排序")
+	}
+	if d.PaginateQuery.Limit <= 0 {
+		return metav1.ListOptions{}, errors.New("服务端分页需要指定limit")
+	}
+	options := d.FilterQuery.TakeListOptions()
+	options.Limit = int64(d.PaginateQuery.Limit)
+	options.Continue = d.PaginateQuery.Continue
+	return options, nil
 }
 
 //排序
@@ -49,35 +242,48 @@ func (d *DataSelector) Swap(i, j int) {
 }
 
 // Less 方法用于定义数组中元素大小的比较方式
+// 依次比较排序规则中的字段，全部相等时按创建时间倒序、名称升序，保证结果稳定
 func (d *DataSelector) Less(i, j int) bool {
-	a := d.GenericDataList[i].GetCreation()
-	b := d.GenericDataList[j].GetCreation()
-	return b.Before(a)
+	a, b := d.GenericDataList[i], d.GenericDataList[j]
+	if d.DataSelectQuery.SortQuery != nil {
+		for _, field := range d.DataSelectQuery.SortQuery.sortFields {
+			result := compareValues(a.GetProperty(field.property), b.GetProperty(field.property))
+			if result == 0 {
+				continue
+			}
+			if field.ascending {
+				return result < 0
+			}
+			return result > 0
+		}
+	}
+	if !a.GetCreation().Equal(b.GetCreation()) {
+		return b.GetCreation().Before(a.GetCreation())
+	}
+	if a.GetNamespace() != b.GetNamespace() {
+		return a.GetNamespace() < b.GetNamespace()
+	}
+	return a.GetName() < b.GetName()
 }
 
-// 重写以上三个方法后，用sort.sort 方法进行排序
+// 重写以上三个方法后，用sort.Stable 方法进行排序
 func (d *DataSelector) Sort() *DataSelector {
-	sort.Sort(d)
+	sort.Stable(d)
 	return d
 }
 
 // 过滤
-// 比较元素中是否存在filterName相匹配的元素，若匹配则返回
+// 比较元素是否满足Filter中的所有条件，若满足则返回
 func (d *DataSelector) Filter() *DataSelector {
-	//若name为空则返回所有
-	if d.DataSelectQuery.FilterQuery.Name == "" {
+	filter := d.DataSelectQuery.FilterQuery
+	//若没有任何过滤条件则返回所有
+	if filter.Name == "" && filter.Status == "" && len(filter.Namespaces) == 0 && filter.Allowed == nil &&
+		filter.labelSelector == nil && filter.fieldSelector == nil {
 		return d
 	}
-	//若name 传参不为空则返回切片中包含name所有元素
 	fileredList := make([]DataCell, 0)
 	for _, value := range d.GenericDataList {
-		matched := true
-		objName := value.GetName()
-		if !strings.Contains(objName, d.DataSelectQuery.FilterQuery.Name) {
-			matched = false
-			continue
-		}
-		if matched {
+		if filter.match(value) {
 			fileredList = append(fileredList, value)
 		}
 	}
@@ -94,142 +300,130 @@ func (d *DataSelector) Paginate() *DataSelector {
 	if limit <= 0 || page <= 0 {
 		return d
 	}
-	startIndex := limit * (page - 1)
-	endIndex := limit * page
-	//处理最后一页
-	if len(d.GenericDataList) < endIndex {
-		endIndex = len(d.GenericDataList)
+	startIndex, endIndex := pageRange(len(d.GenericDataList), limit, page)
+	//超出范围的页返回空列表
+	if startIndex == endIndex {
+		d.GenericDataList = []DataCell{}
+		return d
 	}
 	d.GenericDataList = d.GenericDataList[startIndex:endIndex]
 	return d
 }
 
-// 定义podcell类型 实现datacell接口，用于类型转换
-type podCell corev1.Pod
-
-func (p podCell) GetCreation() time.Time {
-	return p.CreationTimestamp.Time
-}
-
-func (p podCell) GetName() string {
-	return p.Name
-}
-
-// deployment
-type deploymentCell appsv1.Deployment
-
-func (d deploymentCell) GetCreation() time.Time {
-	return d.CreationTimestamp.Time
-}
-func (d deploymentCell) GetName() string {
-	return d.Name
-}
-
-// demonset
-type daemonSetCell appsv1.DaemonSet
-
-func (d daemonSetCell) GetCreation() time.Time {
-	return d.CreationTimestamp.Time
-}
-func (d daemonSetCell) GetName() string {
-	return d.Name
-}
-
-//statefulset
-
-type statefulsetCell appsv1.StatefulSet
-
-func (d statefulsetCell) GetCreation() time.Time {
-	return d.CreationTimestamp.Time
-}
-func (d statefulsetCell) GetName() string {
-	return d.Name
-}
-
-// node
-type nodeCell corev1.Node
-
-func (p nodeCell) GetCreation() time.Time {
-	return p.CreationTimestamp.Time
-}
-
-func (p nodeCell) GetName() string {
-	return p.Name
-}
-
-// namepsace
-type namepsaceCell corev1.Namespace
-
-func (p namepsaceCell) GetCreation() time.Time {
-	return p.CreationTimestamp.Time
+// 计算第page页在长度为total的列表中的下标范围，超出范围的页返回空范围
+// 先用除法判断页是否超出范围再相乘，避免limit或page过大时溢出
+func pageRange(total, limit, page int) (start, end int) {
+	if total == 0 || page-1 > (total-1)/limit {
+		return 0, 0
+	}
+	start = limit * (page - 1)
+	end = total
+	if total-start > limit {
+		end = start + limit
+	}
+	return start, end
 }
 
-func (p namepsaceCell) GetName() string {
-	return p.Name
+// 对已排序的切片分页，与DataSelector的分页规则一致，limit或page不合法时返回所有
+func paginate[T any](items []T, limit, page int) []T {
+	if limit <= 0 || page <= 0 {
+		return items
+	}
+	start, end := pageRange(len(items), limit, page)
+	if start == end {
+		return []T{}
+	}
+	return items[start:end]
 }
 
-// pv
-type pvCell corev1.PersistentVolume
-
-func (p pvCell) GetCreation() time.Time {
-	return p.CreationTimestamp.Time
+// 通用的DataCell实现，所有实现了metav1.Object的资源都可以转成objectCell，用于类型转换
+type objectCell struct {
+	metav1.Object
+	status     string
+	fieldsSet  fields.Set
+	properties map[string]ComparableValue
 }
 
-func (p pvCell) GetName() string {
-	return p.Name
+func (o objectCell) GetCreation() time.Time {
+	return o.GetCreationTimestamp().Time
 }
 
-// service
-type serviceCell corev1.Service
-
-func (p serviceCell) GetCreation() time.Time {
-	return p.CreationTimestamp.Time
+func (o objectCell) GetStatus() string {
+	return o.status
 }
 
-func (p serviceCell) GetName() string {
-	return p.Name
+func (o objectCell) GetFields() fields.Set {
+	return o.fieldsSet
 }
 
-// Ingress
-type ingressCell newv1.Ingress
-
-func (p ingressCell) GetCreation() time.Time {
-	return p.CreationTimestamp.Time
+func (o objectCell) GetProperty(name string) ComparableValue {
+	switch name {
+	case "name":
+		return StdComparableString(o.GetName())
+	case "namespace":
+		return StdComparableString(o.GetNamespace())
+	case "creationTimestamp":
+		return StdComparableTime(o.GetCreation())
+	case "status":
+		return StdComparableString(o.status)
+	}
+	return o.properties[name]
 }
 
-func (p ingressCell) GetName() string {
-	return p.Name
+// ComparableValue 可排序的属性值，Compare返回-1、0、1
+type ComparableValue interface {
+	Compare(other ComparableValue) int
 }
 
-// configmap
-type configMapCell corev1.ConfigMap
+type StdComparableString string
 
-func (p configMapCell) GetCreation() time.Time {
-	return p.CreationTimestamp.Time
+func (s StdComparableString) Compare(other ComparableValue) int {
+	return strings.Compare(string(s), string(other.(StdComparableString)))
 }
 
-func (p configMapCell) GetName() string {
-	return p.Name
-}
-
-// secret
-type secretCell corev1.Secret
+type StdComparableInt int64
 
-func (p secretCell) GetCreation() time.Time {
-	return p.CreationTimestamp.Time
-}
-
-func (p secretCell) GetName() string {
-	return p.Name
+func (i StdComparableInt) Compare(other ComparableValue) int {
+	o := other.(StdComparableInt)
+	switch {
+	case i < o:
+		return -1
+	case i > o:
+		return 1
+	}
+	return 0
 }
 
-// pvc
-type pvcCell corev1.PersistentVolumeClaim
+type StdComparableTime time.Time
 
-func (p pvcCell) GetCreation() time.Time {
-	return p.CreationTimestamp.Time
+func (t StdComparableTime) Compare(other ComparableValue) int {
+	a, b := time.Time(t), time.Time(other.(StdComparableTime))
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
+
+// 比较两个属性值，缺失的值排在前面
+func compareValues(a, b ComparableValue) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	return a.Compare(b)
 }
 
-func (p pvcCell) GetName() string {
-	return p.Name
+// 所有资源都支持的字段选择器字段
+func objectFields(obj metav1.Object) fields.Set {
+	return fields.Set{
+		"metadata.name":      obj.GetName(),
+		"metadata.namespace": obj.GetNamespace(),
+	}
 }
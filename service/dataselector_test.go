@@ -0,0 +1,297 @@
+package service
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// 测试用的pod，创建时间按顺序递增
+func testPodCells() []DataCell {
+	base := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	newPod := func(i int, name, namespace string, phase corev1.PodPhase, labels map[string]string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         namespace,
+				Labels:            labels,
+				CreationTimestamp: metav1.NewTime(base.Add(time.Duration(i) * time.Minute)),
+			},
+			Status: corev1.PodStatus{Phase: phase},
+		}
+	}
+	return Pod.toCells([]*corev1.Pod{
+		newPod(0, "web-1", "dev", corev1.PodRunning, map[string]string{"app": "web"}),
+		newPod(1, "Web-2", "prod", corev1.PodPending, map[string]string{"app": "web", "tier": "front"}),
+		newPod(2, "db-1", "dev", corev1.PodRunning, map[string]string{"app": "db"}),
+		newPod(3, "cache-1", "test", corev1.PodFailed, nil),
+	})
+}
+
+func cellNames(cells []DataCell) []string {
+	names := make([]string, len(cells))
+	for i, cell := range cells {
+		names[i] = cell.GetName()
+	}
+	return names
+}
+
+func TestDataSelectorFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   Filter
+		want     []string
+		parseErr bool
+	}{
+		{name: "无过滤条件", filter: Filter{}, want: []string{"web-1", "Web-2", "db-1", "cache-1"}},
+		{name: "名称包含", filter: Filter{Name: "web"}, want: []string{"web-1"}},
+		{name: "名称忽略大小写", filter: Filter{Name: "WEB", IgnoreCase: true}, want: []string{"web-1", "Web-2"}},
+		{name: "名称正则", filter: Filter{Name: "^[a-z]+-1$", Regex: true}, want: []string{"web-1", "db-1", "cache-1"}},
+		{name: "正则忽略大小写", filter: Filter{Name: "^web", Regex: true, IgnoreCase: true}, want: []string{"web-1", "Web-2"}},
+		{name: "正则不合法", filter: Filter{Name: "(", Regex: true}, parseErr: true},
+		{name: "标签选择器", filter: Filter{LabelSelector: "app=web,tier!=front"}, want: []string{"web-1"}},
+		{name: "标签选择器不合法", filter: Filter{LabelSelector: "app in web"}, parseErr: true},
+		{name: "字段选择器", filter: Filter{FieldSelector: "status.phase=Running,metadata.namespace=dev"}, want: []string{"web-1", "db-1"}},
+		{name: "字段选择器不合法", filter: Filter{FieldSelector: "status.phase"}, parseErr: true},
+		{name: "状态忽略大小写", filter: Filter{Status: "running"}, want: []string{"web-1", "db-1"}},
+		{name: "命名空间", filter: Filter{Namespaces: []string{"prod", "test"}}, want: []string{"Web-2", "cache-1"}},
+		{name: "多个条件同时满足", filter: Filter{Name: "1", Namespaces: []string{"dev"}, Status: "Running"}, want: []string{"web-1", "db-1"}},
+		{name: "没有匹配", filter: Filter{Name: "none"}, want: []string{}},
+		{
+			name:   "访问权限",
+			filter: Filter{Allowed: func(namespace, name string) bool { return namespace != "dev" }},
+			want:   []string{"Web-2", "cache-1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := tt.filter
+			err := filter.Parse()
+			if (err != nil) != tt.parseErr {
+				t.Fatalf("Parse() err = %v, parseErr %v", err, tt.parseErr)
+			}
+			if err != nil {
+				return
+			}
+			selector := &DataSelector{
+				GenericDataList: testPodCells(),
+				DataSelectQuery: &DataSelect{FilterQuery: &filter},
+			}
+			if got := cellNames(selector.Filter().GenericDataList); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Filter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterTakeListOptions(t *testing.T) {
+	filter := &Filter{LabelSelector: "app=web", FieldSelector: "status.phase=Running"}
+	if err := filter.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	options := filter.TakeListOptions()
+	if options.LabelSelector != "app=web" || options.FieldSelector != "status.phase=Running" {
+		t.Errorf("TakeListOptions() = %+v", options)
+	}
+	//下推后不再在内存中按选择器过滤
+	selector := &DataSelector{
+		GenericDataList: testPodCells(),
+		DataSelectQuery: &DataSelect{FilterQuery: filter},
+	}
+	if got := len(selector.Filter().GenericDataList); got != 4 {
+		t.Errorf("Filter() after TakeListOptions len = %d, want 4", got)
+	}
+}
+
+func TestFilterTakeSelectors(t *testing.T) {
+	filter := &Filter{LabelSelector: "app=web", FieldSelector: "status.phase=Running"}
+	if err := filter.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	labelSelector, fieldSelector := filter.TakeSelectors()
+	if labelSelector.String() != "app=web" || fieldSelector.String() != "status.phase=Running" {
+		t.Errorf("TakeSelectors() = %s, %s", labelSelector, fieldSelector)
+	}
+	//缓存按选择器匹配，与在内存中过滤的结果一致
+	var got []string
+	for _, cell := range testPodCells() {
+		if labelSelector.Matches(labels.Set(cell.GetLabels())) && fieldSelector.Matches(cell.GetFields()) {
+			got = append(got, cell.GetName())
+		}
+	}
+	if !reflect.DeepEqual(got, []string{"web-1"}) {
+		t.Errorf("selectors matched %v, want [web-1]", got)
+	}
+	//没有选择器时返回Everything
+	labelSelector, fieldSelector = (&Filter{}).TakeSelectors()
+	if !labelSelector.Empty() || !fieldSelector.Empty() {
+		t.Errorf("TakeSelectors() = %s, %s, want empty", labelSelector, fieldSelector)
+	}
+}
+
+func TestDataSelectorSort(t *testing.T) {
+	tests := []struct {
+		name     string
+		sortBy   string
+		want     []string
+		parseErr bool
+	}{
+		{name: "默认按创建时间倒序", sortBy: "", want: []string{"cache-1", "db-1", "Web-2", "web-1"}},
+		{name: "名称升序", sortBy: "name", want: []string{"Web-2", "cache-1", "db-1", "web-1"}},
+		{name: "名称降序", sortBy: "name:desc", want: []string{"web-1", "db-1", "cache-1", "Web-2"}},
+		{name: "多个字段", sortBy: "namespace, name:desc", want: []string{"web-1", "db-1", "Web-2", "cache-1"}},
+		{name: "相等时按创建时间倒序", sortBy: "namespace", want: []string{"db-1", "web-1", "Web-2", "cache-1"}},
+		{name: "创建时间升序", sortBy: "creationTimestamp:asc", want: []string{"web-1", "Web-2", "db-1", "cache-1"}},
+		{name: "资源声明的字段", sortBy: "restarts:desc,name", want: []string{"db-1", "web-1", "Web-2", "cache-1"}},
+		{name: "不支持的字段", sortBy: "size", parseErr: true},
+		{name: "不支持的排序方式", sortBy: "name:up", parseErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := &SortQuery{SortBy: tt.sortBy}
+			err := query.Parse(Pod.Properties())
+			if (err != nil) != tt.parseErr {
+				t.Fatalf("Parse() err = %v, parseErr %v", err, tt.parseErr)
+			}
+			if err != nil {
+				return
+			}
+			cells := testPodCells()
+			//db-1重启2次，web-1重启1次
+			cells[2].(objectCell).properties["restarts"] = StdComparableInt(2)
+			cells[0].(objectCell).properties["restarts"] = StdComparableInt(1)
+			selector := &DataSelector{
+				GenericDataList: cells,
+				DataSelectQuery: &DataSelect{SortQuery: query},
+			}
+			if got := cellNames(selector.Sort().GenericDataList); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Sort() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareValues(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b ComparableValue
+		want int
+	}{
+		{name: "都缺失", want: 0},
+		{name: "缺失排在前面", b: StdComparableInt(1), want: -1},
+		{name: "缺失排在后面", a: StdComparableInt(1), want: 1},
+		{name: "整数", a: StdComparableInt(2), b: StdComparableInt(10), want: -1},
+		{name: "字符串", a: StdComparableString("b"), b: StdComparableString("a"), want: 1},
+		{name: "时间相等", a: StdComparableTime(time.Unix(1, 0)), b: StdComparableTime(time.Unix(1, 0)), want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := compareValues(tt.a, tt.b); got != tt.want {
+				t.Errorf("compareValues() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDataSelectorPaginate(t *testing.T) {
+	const maxInt = int(^uint(0) >> 1)
+	tests := []struct {
+		name  string
+		limit int
+		page  int
+		want  []string
+	}{
+		{name: "第一页", limit: 3, page: 1, want: []string{"web-1", "Web-2", "db-1"}},
+		{name: "最后一页不满", limit: 3, page: 2, want: []string{"cache-1"}},
+		{name: "刚好整页", limit: 2, page: 2, want: []string{"db-1", "cache-1"}},
+		{name: "超出范围的页", limit: 3, page: 3, want: []string{}},
+		{name: "远超范围的页", limit: 2, page: 100, want: []string{}},
+		{name: "limit大于总数", limit: 10, page: 1, want: []string{"web-1", "Web-2", "db-1", "cache-1"}},
+		{name: "limit不合法返回所有", limit: 0, page: 1, want: []string{"web-1", "Web-2", "db-1", "cache-1"}},
+		{name: "page不合法返回所有", limit: 2, page: -1, want: []string{"web-1", "Web-2", "db-1", "cache-1"}},
+		{name: "page过大不溢出", limit: 2, page: maxInt, want: []string{}},
+		{name: "limit过大不溢出", limit: maxInt, page: 2, want: []string{}},
+		{name: "limit和page都过大", limit: maxInt, page: maxInt, want: []string{}},
+		{name: "limit过大的第一页", limit: maxInt, page: 1, want: []string{"web-1", "Web-2", "db-1", "cache-1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector := &DataSelector{
+				GenericDataList: testPodCells(),
+				DataSelectQuery: &DataSelect{PaginateQuery: &Paginate{Limit: tt.limit, Page: tt.page}},
+			}
+			if got := cellNames(selector.Paginate().GenericDataList); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Paginate() = %v, want %v", got, tt.want)
+			}
+			//通用的分页与DataSelector的分页结果一致
+			if got := cellNames(paginate(testPodCells(), tt.limit, tt.page)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("paginate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaginateEmpty(t *testing.T) {
+	if got := paginate([]string{}, 10, 1); len(got) != 0 {
+		t.Errorf("paginate() = %v, want empty", got)
+	}
+	if got := paginate([]string(nil), 10, 2); got == nil || len(got) != 0 {
+		t.Errorf("paginate() = %#v, want empty slice", got)
+	}
+}
+
+func TestChunkListOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   DataSelect
+		want    metav1.ListOptions
+		wantErr bool
+	}{
+		{
+			name: "选择器和游标下推",
+			query: DataSelect{
+				FilterQuery:   &Filter{LabelSelector: "app=web", FieldSelector: "status.phase=Running"},
+				SortQuery:     &SortQuery{},
+				PaginateQuery: &Paginate{Limit: 50, ServerSide: true, Continue: "token"},
+			},
+			want: metav1.ListOptions{LabelSelector: "app=web", FieldSelector: "status.phase=Running", Limit: 50, Continue: "token"},
+		},
+		{
+			name: "不支持排序",
+			query: DataSelect{
+				FilterQuery:   &Filter{},
+				SortQuery:     &SortQuery{SortBy: "name"},
+				PaginateQuery: &Paginate{Limit: 50, ServerSide: true},
+			},
+			wantErr: true,
+		},
+		{
+			name: "需要指定limit",
+			query: DataSelect{
+				FilterQuery:   &Filter{},
+				PaginateQuery: &Paginate{ServerSide: true},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.query.FilterQuery.Parse(); err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.query.SortQuery.Parse(nil); err != nil {
+				t.Fatal(err)
+			}
+			got, err := tt.query.ChunkListOptions()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ChunkListOptions() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("ChunkListOptions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,601 @@
+package service
+
+import (
+	"errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sort"
+	"strings"
+)
+
+// 定义结构体用于创建deployment
+// containers为空时使用image、cpu、memory、container_port、health_check、health_path组装单个容器，兼容旧的表单
+type DeployCreate struct {
+	Name             string            `json:"name"`
+	Namespace        string            `json:"namespace"`
+	Replicas         int32             `json:"replicas"`
+	Image            string            `json:"image"`
+	Label            map[string]string `json:"label"`
+	Cpu              string            `json:"cpu"`
+	Memory           string            `json:"memory"`
+	ContainerPort    int32             `json:"container_port"`
+	HealthCheck      bool              `json:"health_check"`
+	HealthPath       string            `json:"health_path"`
+	Containers       []ContainerSpec   `json:"containers"`
+	InitContainers   []ContainerSpec   `json:"init_containers"`
+	Volumes          []VolumeSpec      `json:"volumes"`
+	NodeSelector     map[string]string `json:"node_selector"`
+	Tolerations      []TolerationSpec  `json:"tolerations"`
+	ImagePullSecrets []string          `json:"image_pull_secrets"`
+	Cluster          string            `json:"cluster"`
+}
+
+// 容器
+type ContainerSpec struct {
+	Name  string `json:"name"`
+	Image string `json:"image"`
+	//Always、IfNotPresent、Never，为空时由k8s决定
+	ImagePullPolicy string            `json:"image_pull_policy"`
+	Command         []string          `json:"command"`
+	Args            []string          `json:"args"`
+	Ports           []PortSpec        `json:"ports"`
+	Env             []EnvSpec         `json:"env"`
+	EnvFrom         []EnvFromSpec     `json:"env_from"`
+	VolumeMounts    []VolumeMountSpec `json:"volume_mounts"`
+	Requests        ResourceSpec      `json:"requests"`
+	Limits          ResourceSpec      `json:"limits"`
+	ReadinessProbe  *ProbeSpec        `json:"readiness_probe"`
+	LivenessProbe   *ProbeSpec        `json:"liveness_probe"`
+	StartupProbe    *ProbeSpec        `json:"startup_probe"`
+}
+
+type PortSpec struct {
+	Name          string `json:"name"`
+	ContainerPort int32  `json:"container_port"`
+	//TCP、UDP、SCTP，默认TCP
+	Protocol string `json:"protocol"`
+}
+
+// 环境变量，value为字面值，或者configmap/secret加key引用其中的一个键，三者只能选一种
+type EnvSpec struct {
+	Name      string `json:"name"`
+	Value     string `json:"value"`
+	ConfigMap string `json:"configmap"`
+	Secret    string `json:"secret"`
+	Key       string `json:"key"`
+}
+
+// 从configmap或secret导入所有键作为环境变量，二者只能选一种
+type EnvFromSpec struct {
+	ConfigMap string `json:"configmap"`
+	Secret    string `json:"secret"`
+	Prefix    string `json:"prefix"`
+}
+
+// 资源量，如cpu: 500m，memory: 512Mi，为空时不设置
+type ResourceSpec struct {
+	Cpu              string `json:"cpu"`
+	Memory           string `json:"memory"`
+	EphemeralStorage string `json:"ephemeral_storage"`
+}
+
+// 卷，type为pvc、configmap、secret、emptydir，source为pvc、configmap或secret的名称
+// read_only对所有类型的卷生效，该卷的所有挂载都为只读
+type VolumeSpec struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Source   string `json:"source"`
+	ReadOnly bool   `json:"read_only"`
+	//emptydir的存储介质，为空时使用节点磁盘，Memory为tmpfs
+	Medium    string `json:"medium"`
+	SizeLimit string `json:"size_limit"`
+}
+
+type VolumeMountSpec struct {
+	Name      string `json:"name"`
+	MountPath string `json:"mount_path"`
+	SubPath   string `json:"sub_path"`
+	ReadOnly  bool   `json:"read_only"`
+}
+
+// 探针，type为http、tcp、exec、grpc，时间参数为0时使用k8s的默认值
+type ProbeSpec struct {
+	Type string `json:"type"`
+	//http探针的路径
+	Path string `json:"path"`
+	//http、tcp、grpc探针的端口
+	Port int32 `json:"port"`
+	//http探针的协议，HTTP或HTTPS
+	Scheme string `json:"scheme"`
+	//exec探针执行的命令
+	Command []string `json:"command"`
+	//grpc探针的服务名
+	Service             string `json:"service"`
+	InitialDelaySeconds int32  `json:"initial_delay_seconds"`
+	PeriodSeconds       int32  `json:"period_seconds"`
+	TimeoutSeconds      int32  `json:"timeout_seconds"`
+	SuccessThreshold    int32  `json:"success_threshold"`
+	FailureThreshold    int32  `json:"failure_threshold"`
+}
+
+type TolerationSpec struct {
+	Key string `json:"key"`
+	//Equal或Exists，默认Equal
+	Operator string `json:"operator"`
+	Value    string `json:"value"`
+	//NoSchedule、PreferNoSchedule、NoExecute，为空时匹配所有effect
+	Effect            string `json:"effect"`
+	TolerationSeconds *int64 `json:"toleration_seconds"`
+}
+
+// 校验表单并组装成deployment对象，所有不合法的字段一起返回
+func (d *DeployCreate) toDeployment() (*appsv1.Deployment, error) {
+	var errs field.ErrorList
+	errs = append(errs, validateObjectName(d.Name, field.NewPath("name"))...)
+	errs = append(errs, validateName(d.Namespace, field.NewPath("namespace"))...)
+	if d.Replicas < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("replicas"), d.Replicas, "不能小于0"))
+	}
+	labels := d.Label
+	if len(labels) == 0 {
+		labels = map[string]string{"app": d.Name}
+	}
+	errs = append(errs, validateLabels(labels, field.NewPath("label"))...)
+	errs = append(errs, validateLabels(d.NodeSelector, field.NewPath("node_selector"))...)
+
+	containers := d.Containers
+	if len(containers) == 0 {
+		containers = []ContainerSpec{d.legacyContainer()}
+	}
+	volumes := map[string]bool{}
+	podSpec := corev1.PodSpec{NodeSelector: d.NodeSelector}
+	for i, volume := range d.Volumes {
+		path := field.NewPath("volumes").Index(i)
+		v, volumeErrs := volume.toVolume(path)
+		errs = append(errs, volumeErrs...)
+		if _, ok := volumes[volume.Name]; ok {
+			errs = append(errs, field.Duplicate(path.Child("name"), volume.Name))
+		}
+		volumes[volume.Name] = volume.ReadOnly
+		podSpec.Volumes = append(podSpec.Volumes, v)
+	}
+	names := map[string]bool{}
+	for i, spec := range containers {
+		path := field.NewPath("containers").Index(i)
+		container, containerErrs := spec.toContainer(path, volumes, true)
+		errs = append(errs, containerErrs...)
+		if names[spec.Name] {
+			errs = append(errs, field.Duplicate(path.Child("name"), spec.Name))
+		}
+		names[spec.Name] = true
+		podSpec.Containers = append(podSpec.Containers, container)
+	}
+	for i, spec := range d.InitContainers {
+		path := field.NewPath("init_containers").Index(i)
+		container, containerErrs := spec.toContainer(path, volumes, false)
+		errs = append(errs, containerErrs...)
+		if names[spec.Name] {
+			errs = append(errs, field.Duplicate(path.Child("name"), spec.Name))
+		}
+		names[spec.Name] = true
+		podSpec.InitContainers = append(podSpec.InitContainers, container)
+	}
+	for i, toleration := range d.Tolerations {
+		t, tolerationErrs := toleration.toToleration(field.NewPath("tolerations").Index(i))
+		errs = append(errs, tolerationErrs...)
+		podSpec.Tolerations = append(podSpec.Tolerations, t)
+	}
+	for i, secret := range d.ImagePullSecrets {
+		errs = append(errs, validateObjectName(secret, field.NewPath("image_pull_secrets").Index(i))...)
+		podSpec.ImagePullSecrets = append(podSpec.ImagePullSecrets, corev1.LocalObjectReference{Name: secret})
+	}
+	if len(errs) > 0 {
+		return nil, errors.New("参数校验失败" + errs.ToAggregate().Error())
+	}
+	replicas := d.Replicas
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      d.Name,
+			Namespace: d.Namespace,
+			Labels:    labels,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: podSpec,
+			},
+		},
+	}, nil
+}
+
+// 旧表单的单个容器，requests和limits相同，health_check打开时使用http探针
+func (d *DeployCreate) legacyContainer() ContainerSpec {
+	container := ContainerSpec{
+		Name:     d.Name,
+		Image:    d.Image,
+		Requests: ResourceSpec{Cpu: d.Cpu, Memory: d.Memory},
+		Limits:   ResourceSpec{Cpu: d.Cpu, Memory: d.Memory},
+	}
+	if d.ContainerPort > 0 {
+		container.Ports = []PortSpec{{Name: "http", ContainerPort: d.ContainerPort}}
+	}
+	if d.HealthCheck {
+		container.ReadinessProbe = &ProbeSpec{Type: "http", Path: d.HealthPath, Port: d.ContainerPort,
+			InitialDelaySeconds: 5, TimeoutSeconds: 5, PeriodSeconds: 5}
+		container.LivenessProbe = &ProbeSpec{Type: "http", Path: d.HealthPath, Port: d.ContainerPort,
+			InitialDelaySeconds: 15, TimeoutSeconds: 15, PeriodSeconds: 15}
+	}
+	return container
+}
+
+// 校验并组装容器，volumes为已声明的卷名及该卷是否只读，init容器不支持探针
+func (c *ContainerSpec) toContainer(path *field.Path, volumes map[string]bool, probes bool) (corev1.Container, field.ErrorList) {
+	errs := validateName(c.Name, path.Child("name"))
+	if strings.TrimSpace(c.Image) == "" {
+		errs = append(errs, field.Required(path.Child("image"), ""))
+	}
+	container := corev1.Container{
+		Name:    c.Name,
+		Image:   c.Image,
+		Command: c.Command,
+		Args:    c.Args,
+	}
+	switch policy := corev1.PullPolicy(c.ImagePullPolicy); policy {
+	case "", corev1.PullAlways, corev1.PullIfNotPresent, corev1.PullNever:
+		container.ImagePullPolicy = policy
+	default:
+		errs = append(errs, field.NotSupported(path.Child("image_pull_policy"), c.ImagePullPolicy,
+			[]string{string(corev1.PullAlways), string(corev1.PullIfNotPresent), string(corev1.PullNever)}))
+	}
+	for i, port := range c.Ports {
+		p := path.Child("ports").Index(i)
+		for _, msg := range validation.IsValidPortNum(int(port.ContainerPort)) {
+			errs = append(errs, field.Invalid(p.Child("container_port"), port.ContainerPort, msg))
+		}
+		if port.Name != "" {
+			for _, msg := range validation.IsValidPortName(port.Name) {
+				errs = append(errs, field.Invalid(p.Child("name"), port.Name, msg))
+			}
+		}
+		protocol := corev1.Protocol(strings.ToUpper(port.Protocol))
+		switch protocol {
+		case "":
+			protocol = corev1.ProtocolTCP
+		case corev1.ProtocolTCP, corev1.ProtocolUDP, corev1.ProtocolSCTP:
+		default:
+			errs = append(errs, field.NotSupported(p.Child("protocol"), port.Protocol, []string{"TCP", "UDP", "SCTP"}))
+		}
+		container.Ports = append(container.Ports, corev1.ContainerPort{Name: port.Name, ContainerPort: port.ContainerPort, Protocol: protocol})
+	}
+	for i, env := range c.Env {
+		e, envErrs := env.toEnvVar(path.Child("env").Index(i))
+		errs = append(errs, envErrs...)
+		container.Env = append(container.Env, e)
+	}
+	for i, envFrom := range c.EnvFrom {
+		e, envErrs := envFrom.toEnvFromSource(path.Child("env_from").Index(i))
+		errs = append(errs, envErrs...)
+		container.EnvFrom = append(container.EnvFrom, e)
+	}
+	for i, mount := range c.VolumeMounts {
+		p := path.Child("volume_mounts").Index(i)
+		readOnly, ok := volumes[mount.Name]
+		if !ok {
+			errs = append(errs, field.NotFound(p.Child("name"), mount.Name))
+		}
+		if !strings.HasPrefix(mount.MountPath, "/") {
+			errs = append(errs, field.Invalid(p.Child("mount_path"), mount.MountPath, "必须是绝对路径"))
+		}
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      mount.Name,
+			MountPath: mount.MountPath,
+			SubPath:   mount.SubPath,
+			ReadOnly:  mount.ReadOnly || readOnly,
+		})
+	}
+	requests, requestErrs := c.Requests.toResourceList(path.Child("requests"))
+	limits, limitErrs := c.Limits.toResourceList(path.Child("limits"))
+	errs = append(append(errs, requestErrs...), limitErrs...)
+	for name, request := range requests {
+		if limit, ok := limits[name]; ok && request.Cmp(limit) > 0 {
+			errs = append(errs, field.Invalid(path.Child("requests", string(name)), request.String(), "不能大于limits"))
+		}
+	}
+	container.Resources = corev1.ResourceRequirements{Requests: requests, Limits: limits}
+	if !probes && (c.ReadinessProbe != nil || c.LivenessProbe != nil || c.StartupProbe != nil) {
+		errs = append(errs, field.Forbidden(path, "init容器不支持探针"))
+		return container, errs
+	}
+	var probeErrs field.ErrorList
+	container.ReadinessProbe, probeErrs = c.ReadinessProbe.toProbe(path.Child("readiness_probe"))
+	errs = append(errs, probeErrs...)
+	container.LivenessProbe, probeErrs = c.LivenessProbe.toProbe(path.Child("liveness_probe"))
+	errs = append(errs, probeErrs...)
+	container.StartupProbe, probeErrs = c.StartupProbe.toProbe(path.Child("startup_probe"))
+	errs = append(errs, probeErrs...)
+	return container, errs
+}
+
+func (e *EnvSpec) toEnvVar(path *field.Path) (corev1.EnvVar, field.ErrorList) {
+	var errs field.ErrorList
+	for _, msg := range validation.IsEnvVarName(e.Name) {
+		errs = append(errs, field.Invalid(path.Child("name"), e.Name, msg))
+	}
+	env := corev1.EnvVar{Name: e.Name}
+	switch {
+	case e.ConfigMap != "" && e.Secret != "", (e.ConfigMap != "" || e.Secret != "") && e.Value != "":
+		errs = append(errs, field.Invalid(path, e.Name, "value、configmap、secret只能选一种"))
+	case e.ConfigMap != "":
+		errs = append(errs, validateObjectName(e.ConfigMap, path.Child("configmap"))...)
+		errs = append(errs, validateKey(e.Key, path.Child("key"))...)
+		env.ValueFrom = &corev1.EnvVarSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: e.ConfigMap},
+			Key:                  e.Key,
+		}}
+	case e.Secret != "":
+		errs = append(errs, validateObjectName(e.Secret, path.Child("secret"))...)
+		errs = append(errs, validateKey(e.Key, path.Child("key"))...)
+		env.ValueFrom = &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: e.Secret},
+			Key:                  e.Key,
+		}}
+	default:
+		env.Value = e.Value
+	}
+	return env, errs
+}
+
+func (e *EnvFromSpec) toEnvFromSource(path *field.Path) (corev1.EnvFromSource, field.ErrorList) {
+	var errs field.ErrorList
+	source := corev1.EnvFromSource{Prefix: e.Prefix}
+	switch {
+	case (e.ConfigMap == "") == (e.Secret == ""):
+		errs = append(errs, field.Invalid(path, e, "configmap和secret必须且只能选一种"))
+	case e.ConfigMap != "":
+		errs = append(errs, validateObjectName(e.ConfigMap, path.Child("configmap"))...)
+		source.ConfigMapRef = &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: e.ConfigMap}}
+	default:
+		errs = append(errs, validateObjectName(e.Secret, path.Child("secret"))...)
+		source.SecretRef = &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: e.Secret}}
+	}
+	return source, errs
+}
+
+func (v *VolumeSpec) toVolume(path *field.Path) (corev1.Volume, field.ErrorList) {
+	errs := validateName(v.Name, path.Child("name"))
+	volume := corev1.Volume{Name: v.Name}
+	switch strings.ToLower(v.Type) {
+	case "pvc":
+		errs = append(errs, validateObjectName(v.Source, path.Child("source"))...)
+		volume.PersistentVolumeClaim = &corev1.PersistentVolumeClaimVolumeSource{ClaimName: v.Source, ReadOnly: v.ReadOnly}
+	case "configmap":
+		errs = append(errs, validateObjectName(v.Source, path.Child("source"))...)
+		volume.ConfigMap = &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: v.Source}}
+	case "secret":
+		errs = append(errs, validateObjectName(v.Source, path.Child("source"))...)
+		volume.Secret = &corev1.SecretVolumeSource{SecretName: v.Source}
+	case "emptydir":
+		emptyDir := &corev1.EmptyDirVolumeSource{}
+		switch medium := corev1.StorageMedium(v.Medium); medium {
+		case corev1.StorageMediumDefault, corev1.StorageMediumMemory:
+			emptyDir.Medium = medium
+		default:
+			errs = append(errs, field.NotSupported(path.Child("medium"), v.Medium, []string{"", string(corev1.StorageMediumMemory)}))
+		}
+		if v.SizeLimit != "" {
+			sizeLimit, err := resource.ParseQuantity(v.SizeLimit)
+			if err != nil {
+				errs = append(errs, field.Invalid(path.Child("size_limit"), v.SizeLimit, err.Error()))
+			} else {
+				emptyDir.SizeLimit = &sizeLimit
+			}
+		}
+		volume.EmptyDir = emptyDir
+	default:
+		errs = append(errs, field.NotSupported(path.Child("type"), v.Type, []string{"pvc", "configmap", "secret", "emptydir"}))
+	}
+	return volume, errs
+}
+
+// 解析资源量，非法的值作为错误返回，不使用会panic的MustParse
+func (r *ResourceSpec) toResourceList(path *field.Path) (corev1.ResourceList, field.ErrorList) {
+	var errs field.ErrorList
+	list := corev1.ResourceList{}
+	for _, item := range []struct {
+		name  corev1.ResourceName
+		value string
+	}{
+		{corev1.ResourceCPU, r.Cpu},
+		{corev1.ResourceMemory, r.Memory},
+		{corev1.ResourceEphemeralStorage, r.EphemeralStorage},
+	} {
+		name, value := item.name, item.value
+		if value == "" {
+			continue
+		}
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			errs = append(errs, field.Invalid(path.Child(string(name)), value, err.Error()))
+			continue
+		}
+		if quantity.Sign() < 0 {
+			errs = append(errs, field.Invalid(path.Child(string(name)), value, "不能小于0"))
+			continue
+		}
+		list[name] = quantity
+	}
+	if len(list) == 0 {
+		return nil, errs
+	}
+	return list, errs
+}
+
+// 组装探针，p为nil时不设置探针
+func (p *ProbeSpec) toProbe(path *field.Path) (*corev1.Probe, field.ErrorList) {
+	if p == nil {
+		return nil, nil
+	}
+	var errs field.ErrorList
+	probe := &corev1.Probe{
+		InitialDelaySeconds: p.InitialDelaySeconds,
+		PeriodSeconds:       p.PeriodSeconds,
+		TimeoutSeconds:      p.TimeoutSeconds,
+		SuccessThreshold:    p.SuccessThreshold,
+		FailureThreshold:    p.FailureThreshold,
+	}
+	for _, item := range []struct {
+		name  string
+		value int32
+	}{
+		{"initial_delay_seconds", p.InitialDelaySeconds},
+		{"period_seconds", p.PeriodSeconds},
+		{"timeout_seconds", p.TimeoutSeconds},
+		{"success_threshold", p.SuccessThreshold},
+		{"failure_threshold", p.FailureThreshold},
+	} {
+		if item.value < 0 {
+			errs = append(errs, field.Invalid(path.Child(item.name), item.value, "不能小于0"))
+		}
+	}
+	validatePort := func() {
+		for _, msg := range validation.IsValidPortNum(int(p.Port)) {
+			errs = append(errs, field.Invalid(path.Child("port"), p.Port, msg))
+		}
+	}
+	switch strings.ToLower(p.Type) {
+	case "http":
+		validatePort()
+		scheme := corev1.URIScheme(strings.ToUpper(p.Scheme))
+		switch scheme {
+		case "":
+			scheme = corev1.URISchemeHTTP
+		case corev1.URISchemeHTTP, corev1.URISchemeHTTPS:
+		default:
+			errs = append(errs, field.NotSupported(path.Child("scheme"), p.Scheme, []string{"HTTP", "HTTPS"}))
+		}
+		probe.HTTPGet = &corev1.HTTPGetAction{Path: p.Path, Port: intstr.FromInt(int(p.Port)), Scheme: scheme}
+	case "tcp":
+		validatePort()
+		probe.TCPSocket = &corev1.TCPSocketAction{Port: intstr.FromInt(int(p.Port))}
+	case "exec":
+		if len(p.Command) == 0 {
+			errs = append(errs, field.Required(path.Child("command"), ""))
+		}
+		probe.Exec = &corev1.ExecAction{Command: p.Command}
+	case "grpc":
+		validatePort()
+		probe.GRPC = &corev1.GRPCAction{Port: p.Port}
+		if p.Service != "" {
+			service := p.Service
+			probe.GRPC.Service = &service
+		}
+	default:
+		errs = append(errs, field.NotSupported(path.Child("type"), p.Type, []string{"http", "tcp", "exec", "grpc"}))
+	}
+	return probe, errs
+}
+
+func (t *TolerationSpec) toToleration(path *field.Path) (corev1.Toleration, field.ErrorList) {
+	var errs field.ErrorList
+	if t.Key != "" {
+		for _, msg := range validation.IsQualifiedName(t.Key) {
+			errs = append(errs, field.Invalid(path.Child("key"), t.Key, msg))
+		}
+	}
+	toleration := corev1.Toleration{
+		Key:               t.Key,
+		Value:             t.Value,
+		Effect:            corev1.TaintEffect(t.Effect),
+		TolerationSeconds: t.TolerationSeconds,
+	}
+	switch operator := corev1.TolerationOperator(t.Operator); operator {
+	case "", corev1.TolerationOpEqual:
+		toleration.Operator = corev1.TolerationOpEqual
+		if t.Key == "" {
+			errs = append(errs, field.Required(path.Child("key"), "operator为Equal时必须指定key"))
+		}
+	case corev1.TolerationOpExists:
+		toleration.Operator = operator
+		if t.Value != "" {
+			errs = append(errs, field.Invalid(path.Child("value"), t.Value, "operator为Exists时value必须为空"))
+		}
+	default:
+		errs = append(errs, field.NotSupported(path.Child("operator"), t.Operator, []string{"Equal", "Exists"}))
+	}
+	switch toleration.Effect {
+	case "", corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+	default:
+		errs = append(errs, field.NotSupported(path.Child("effect"), t.Effect, []string{"NoSchedule", "PreferNoSchedule", "NoExecute"}))
+	}
+	if t.TolerationSeconds != nil && toleration.Effect != corev1.TaintEffectNoExecute {
+		errs = append(errs, field.Invalid(path.Child("toleration_seconds"), *t.TolerationSeconds, "只有effect为NoExecute时可以设置"))
+	}
+	return toleration, errs
+}
+
+// 校验命名空间、容器、卷等名称，必须符合DNS-1123 label
+func validateName(name string, path *field.Path) field.ErrorList {
+	if name == "" {
+		return field.ErrorList{field.Required(path, "")}
+	}
+	var errs field.ErrorList
+	for _, msg := range validation.IsDNS1123Label(name) {
+		errs = append(errs, field.Invalid(path, name, msg))
+	}
+	return errs
+}
+
+// 校验引用的configmap、secret、pvc等对象的名称，必须符合DNS-1123 subdomain
+func validateObjectName(name string, path *field.Path) field.ErrorList {
+	if name == "" {
+		return field.ErrorList{field.Required(path, "")}
+	}
+	var errs field.ErrorList
+	for _, msg := range validation.IsDNS1123Subdomain(name) {
+		errs = append(errs, field.Invalid(path, name, msg))
+	}
+	return errs
+}
+
+// 校验configmap、secret中的键
+func validateKey(key string, path *field.Path) field.ErrorList {
+	if key == "" {
+		return field.ErrorList{field.Required(path, "")}
+	}
+	var errs field.ErrorList
+	for _, msg := range validation.IsConfigMapKey(key) {
+		errs = append(errs, field.Invalid(path, key, msg))
+	}
+	return errs
+}
+
+// 校验标签的键值
+func validateLabels(labels map[string]string, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := labels[key]
+		for _, msg := range validation.IsQualifiedName(key) {
+			errs = append(errs, field.Invalid(path.Key(key), key, msg))
+		}
+		for _, msg := range validation.IsValidLabelValue(value) {
+			errs = append(errs, field.Invalid(path.Key(key), value, msg))
+		}
+	}
+	return errs
+}
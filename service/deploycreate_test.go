@@ -0,0 +1,195 @@
+package service
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"strings"
+	"testing"
+)
+
+func TestDeployCreateValidation(t *testing.T) {
+	valid := func() DeployCreate {
+		return DeployCreate{
+			Name:      "web",
+			Namespace: "dev",
+			Replicas:  2,
+			Containers: []ContainerSpec{{
+				Name:           "web",
+				Image:          "nginx:1.23",
+				Ports:          []PortSpec{{Name: "http", ContainerPort: 80}},
+				Env:            []EnvSpec{{Name: "MODE", Value: "prod"}, {Name: "TOKEN", Secret: "web-token", Key: "token"}},
+				VolumeMounts:   []VolumeMountSpec{{Name: "data", MountPath: "/data"}},
+				Requests:       ResourceSpec{Cpu: "100m", Memory: "128Mi"},
+				Limits:         ResourceSpec{Cpu: "500m", Memory: "512Mi"},
+				ReadinessProbe: &ProbeSpec{Type: "http", Path: "/healthz", Port: 80},
+			}},
+			Volumes:     []VolumeSpec{{Name: "data", Type: "pvc", Source: "web-data"}},
+			Tolerations: []TolerationSpec{{Key: "dedicated", Operator: "Exists", Effect: "NoSchedule"}},
+		}
+	}
+	tests := []struct {
+		name   string
+		modify func(d *DeployCreate)
+		//错误信息中应包含的字段路径，为空时期望校验通过
+		wantErrs []string
+	}{
+		{name: "合法", modify: func(d *DeployCreate) {}},
+		{
+			name: "旧表单",
+			modify: func(d *DeployCreate) {
+				*d = DeployCreate{Name: "web", Namespace: "dev", Replicas: 1, Image: "nginx", Cpu: "1", Memory: "1Gi",
+					ContainerPort: 80, HealthCheck: true, HealthPath: "/"}
+			},
+		},
+		{name: "名称为空", modify: func(d *DeployCreate) { d.Name = "" }, wantErrs: []string{"name: Required"}},
+		{name: "命名空间不合法", modify: func(d *DeployCreate) { d.Namespace = "Dev_1" }, wantErrs: []string{"namespace: Invalid"}},
+		{name: "副本数为负", modify: func(d *DeployCreate) { d.Replicas = -1 }, wantErrs: []string{"replicas"}},
+		{name: "标签不合法", modify: func(d *DeployCreate) { d.Label = map[string]string{"app": "a b"} }, wantErrs: []string{"label[app]"}},
+		{name: "镜像为空", modify: func(d *DeployCreate) { d.Containers[0].Image = " " }, wantErrs: []string{"containers[0].image: Required"}},
+		{
+			name: "容器重名",
+			modify: func(d *DeployCreate) {
+				d.InitContainers = []ContainerSpec{{Name: "web", Image: "busybox"}}
+			},
+			wantErrs: []string{"init_containers[0].name: Duplicate"},
+		},
+		{name: "端口超出范围", modify: func(d *DeployCreate) { d.Containers[0].Ports[0].ContainerPort = 70000 }, wantErrs: []string{"containers[0].ports[0].container_port"}},
+		{name: "协议不支持", modify: func(d *DeployCreate) { d.Containers[0].Ports[0].Protocol = "http" }, wantErrs: []string{"containers[0].ports[0].protocol"}},
+		{
+			name: "环境变量同时指定value和secret",
+			modify: func(d *DeployCreate) {
+				d.Containers[0].Env[1].Value = "x"
+			},
+			wantErrs: []string{"containers[0].env[1]"},
+		},
+		{name: "引用secret缺少key", modify: func(d *DeployCreate) { d.Containers[0].Env[1].Key = "" }, wantErrs: []string{"containers[0].env[1].key: Required"}},
+		{name: "挂载未声明的卷", modify: func(d *DeployCreate) { d.Containers[0].VolumeMounts[0].Name = "logs" }, wantErrs: []string{"containers[0].volume_mounts[0].name: Not found"}},
+		{name: "挂载路径不是绝对路径", modify: func(d *DeployCreate) { d.Containers[0].VolumeMounts[0].MountPath = "data" }, wantErrs: []string{"containers[0].volume_mounts[0].mount_path"}},
+		{name: "卷类型不支持", modify: func(d *DeployCreate) { d.Volumes[0].Type = "hostpath" }, wantErrs: []string{"volumes[0].type: Unsupported"}},
+		{
+			name: "卷重名",
+			modify: func(d *DeployCreate) {
+				d.Volumes = append(d.Volumes, VolumeSpec{Name: "data", Type: "emptydir"})
+			},
+			wantErrs: []string{"volumes[1].name: Duplicate"},
+		},
+		{name: "资源量不合法", modify: func(d *DeployCreate) { d.Containers[0].Requests.Cpu = "abc" }, wantErrs: []string{"containers[0].requests.cpu"}},
+		{name: "requests大于limits", modify: func(d *DeployCreate) { d.Containers[0].Requests.Memory = "1Gi" }, wantErrs: []string{"containers[0].requests.memory"}},
+		{name: "探针类型不支持", modify: func(d *DeployCreate) { d.Containers[0].ReadinessProbe.Type = "udp" }, wantErrs: []string{"containers[0].readiness_probe.type"}},
+		{
+			name: "init容器不支持探针",
+			modify: func(d *DeployCreate) {
+				d.InitContainers = []ContainerSpec{{Name: "init", Image: "busybox", LivenessProbe: &ProbeSpec{Type: "exec", Command: []string{"true"}}}}
+			},
+			wantErrs: []string{"init_containers[0]: Forbidden"},
+		},
+		{
+			name: "Exists的toleration不能指定value",
+			modify: func(d *DeployCreate) {
+				d.Tolerations[0].Value = "x"
+			},
+			wantErrs: []string{"tolerations[0].value"},
+		},
+		{
+			name: "多个错误一起返回",
+			modify: func(d *DeployCreate) {
+				d.Name = ""
+				d.Containers[0].Image = ""
+				d.ImagePullSecrets = []string{"Bad_Secret"}
+			},
+			wantErrs: []string{"name: Required", "containers[0].image: Required", "image_pull_secrets[0]"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := valid()
+			tt.modify(&data)
+			deployment, err := data.toDeployment()
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Fatalf("toDeployment() err = %v", err)
+				}
+				if deployment.Name != data.Name || *deployment.Spec.Replicas != data.Replicas {
+					t.Errorf("toDeployment() = %s replicas %d", deployment.Name, *deployment.Spec.Replicas)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("toDeployment() err = nil, want %v", tt.wantErrs)
+			}
+			for _, want := range tt.wantErrs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("toDeployment() err = %v, want containing %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDeployCreateLegacyContainer(t *testing.T) {
+	data := DeployCreate{Name: "web", Namespace: "dev", Replicas: 1, Image: "nginx", Cpu: "1", Memory: "1Gi",
+		ContainerPort: 8080, HealthCheck: true, HealthPath: "/healthz"}
+	deployment, err := data.toDeployment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := deployment.Spec.Selector.MatchLabels["app"]; got != "web" {
+		t.Errorf("默认标签app = %q, want web", got)
+	}
+	containers := deployment.Spec.Template.Spec.Containers
+	if len(containers) != 1 {
+		t.Fatalf("containers len = %d, want 1", len(containers))
+	}
+	container := containers[0]
+	if container.Ports[0].ContainerPort != 8080 || container.Ports[0].Protocol != corev1.ProtocolTCP {
+		t.Errorf("ports = %+v", container.Ports)
+	}
+	if !container.Resources.Requests.Cpu().Equal(*container.Resources.Limits.Cpu()) {
+		t.Errorf("旧表单的requests和limits应相同，got %v", container.Resources)
+	}
+	if container.ReadinessProbe == nil || container.ReadinessProbe.HTTPGet.Path != "/healthz" || container.LivenessProbe == nil {
+		t.Errorf("health_check打开时应设置http探针，got %+v", container.ReadinessProbe)
+	}
+}
+
+func TestDeployCreateReadOnlyVolume(t *testing.T) {
+	data := DeployCreate{
+		Name:      "web",
+		Namespace: "dev",
+		Replicas:  1,
+		Containers: []ContainerSpec{{
+			Name:  "web",
+			Image: "nginx",
+			VolumeMounts: []VolumeMountSpec{
+				{Name: "config", MountPath: "/etc/web"},
+				{Name: "token", MountPath: "/var/run/token"},
+				{Name: "data", MountPath: "/data"},
+				{Name: "cache", MountPath: "/cache", ReadOnly: true},
+			},
+		}},
+		InitContainers: []ContainerSpec{{
+			Name:         "init",
+			Image:        "busybox",
+			VolumeMounts: []VolumeMountSpec{{Name: "config", MountPath: "/etc/web"}},
+		}},
+		Volumes: []VolumeSpec{
+			{Name: "config", Type: "configmap", Source: "web-config", ReadOnly: true},
+			{Name: "token", Type: "secret", Source: "web-token", ReadOnly: true},
+			{Name: "data", Type: "pvc", Source: "web-data"},
+			{Name: "cache", Type: "emptydir"},
+		},
+	}
+	deployment, err := data.toDeployment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	//卷只读时所有挂载只读，卷不是只读时使用挂载自身的设置
+	want := map[string]bool{"config": true, "token": true, "data": false, "cache": true}
+	for _, mount := range deployment.Spec.Template.Spec.Containers[0].VolumeMounts {
+		if mount.ReadOnly != want[mount.Name] {
+			t.Errorf("挂载%s的read_only = %v, want %v", mount.Name, mount.ReadOnly, want[mount.Name])
+		}
+	}
+	if mount := deployment.Spec.Template.Spec.InitContainers[0].VolumeMounts[0]; !mount.ReadOnly {
+		t.Errorf("init容器挂载%s的read_only = false, want true", mount.Name)
+	}
+}
@@ -5,23 +5,21 @@ import (
 	"errors"
 	"github.com/wonderivan/logger"
 	appsv1 "k8s.io/api/apps/v1"
-	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/util/intstr"
-	"k8s.io/apimachinery/pkg/util/json"
-	"strconv"
-	"time"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
 )
 
-var Deployment deployment
+// deployment，列表、详情、删除、更新、创建由通用资源Resource提供
+var Deployment = deployment{newResource[appsv1.Deployment]("deployment", appsv1.SchemeGroupVersion.WithResource("deployments"), true,
+	func(clientset kubernetes.Interface, namespace string) typedClient[*appsv1.Deployment] {
+		return clientset.AppsV1().Deployments(namespace)
+	}).withStatus(deploymentStatus).
+	withProperty("ready", deploymentReady).
+	withProperty("readiness", deploymentReadiness)}
 
-type deployment struct{}
-
-// 定义deployments的返回内容 items是deployment列表吗total为deployment元素总数
-type DeploymentsResp struct {
-	Item  []appsv1.Deployment `json:"items"`
-	Total int                 `json:"total"`
+type deployment struct {
+	*Resource[appsv1.Deployment, *appsv1.Deployment]
 }
 
 type DeploymentsNs struct {
@@ -29,95 +27,14 @@ type DeploymentsNs struct {
 	DeploymentNum int    `json:"deployment_num"`
 }
 
-// 定义结构体用于创建deployment
-type DeployCreate struct {
-	Name          string            `json:"name"`
-	Namespace     string            `json:"namespace"`
-	Replicas      int32             `json:"replicas"`
-	Image         string            `json:"image"`
-	Label         map[string]string `json:"label"`
-	Cpu           string            `json:"cpu"`
-	Memory        string            `json:"memory"`
-	ContainerPort int32             `json:"container_port"`
-	HealthCheck   bool              `json:"health_check"`
-	HealthPath    string            `json:"health_path"`
-}
-
-// 获取deployment列表
-func (p *deployment) GetDeployments(filterName, namespace string, limit, page int) (deploymentsResp *DeploymentsResp, err error) {
-	//通过clientset获取deployments完整列表
-	deploymentList, err := K8s.ClientSet.AppsV1().Deployments(namespace).List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		logger.Error("获取deployment列表失败", err)
-		return nil, errors.New("获取deployment列表失败" + err.Error())
-	}
-	//实例化DataSelector对象
-	selectableData := &DataSelector{
-		GenericDataList: p.toCells(deploymentList.Items),
-		DataSelectQuery: &DataSelect{
-			FilterQuery: &Filter{filterName},
-			PaginateQuery: &Paginate{
-				Limit: limit,
-				Page:  page,
-			},
-		},
-	}
-	//先过滤
-	filtered := selectableData.Filter()
-	//再拿total
-	total := len(filtered.GenericDataList)
-	//在排序和分页
-	data := filtered.Sort().Paginate()
-	//再将datacell切片数据转成原生deployment切片
-	deployments := p.fromCells(data.GenericDataList)
-	//返回
-	return &DeploymentsResp{
-		Item:  deployments,
-		Total: total,
-	}, nil
-}
-
-// 获取deployment详情
-func (p *deployment) GetDeploymentDetail(deploymentName, namespace string) (deployment *appsv1.Deployment, err error) {
-	deployment, err = K8s.ClientSet.AppsV1().Deployments(namespace).Get(context.TODO(), deploymentName, metav1.GetOptions{})
-	if err != nil {
-		logger.Error("获取Deployment详情失败" + err.Error())
-		return nil, errors.New("获取Deployment详情失败" + err.Error())
-	}
-	return deployment, nil
-}
-
-// 删除deployment
-func (p *deployment) DeleteDeployment(deploymentName, namespace string) (err error) {
-	err = K8s.ClientSet.AppsV1().Deployments(namespace).Delete(context.TODO(), deploymentName, metav1.DeleteOptions{})
-	if err != nil {
-		logger.Error("删除Deployment失败" + err.Error())
-		return errors.New("获取Deployment详情失败" + err.Error())
-	}
-	return nil
-}
-
-// 更新deployment
-func (p *deployment) UpdateDeployment(namespace, content string) (err error) {
-	//将content反序列化成为deployment对象
-	var deployment = &appsv1.Deployment{}
-	if err = json.Unmarshal([]byte(content), deployment); err != nil {
-		logger.Error("Content反序列化失败", err)
-		return errors.New("Content反序列化失败" + err.Error())
-	}
-	//更新deployment
-	_, err = K8s.ClientSet.AppsV1().Deployments(namespace).Update(context.TODO(), deployment, metav1.UpdateOptions{})
+// 修改deployment副本数
+func (p *deployment) ScaleDeployment(ctx context.Context, cluster, deploymentName, namespace string, scaleNum int) (replicas int32, err error) {
+	client, err := K8s.GetClient(ctx, cluster)
 	if err != nil {
-		logger.Error("更新Deployment失败" + err.Error())
-		return errors.New("更新Deployment失败" + err.Error())
+		return 0, err
 	}
-	return nil
-}
-
-// 修改deployment副本数
-func (p *deployment) ScaleDeployment(deploymentName, namespace string, scaleNum int) (replicas int32, err error) {
 	//获取autoscaling.scale对象，能点出当前的副本数
-	scale, err := K8s.ClientSet.AppsV1().Deployments(namespace).GetScale(context.TODO(), deploymentName, metav1.GetOptions{})
+	scale, err := client.AppsV1().Deployments(namespace).GetScale(ctx, deploymentName, metav1.GetOptions{})
 	if err != nil {
 		logger.Error("获取deployment副本数失败", err.Error())
 		return 0, errors.New("获取deployment副本数失败" + err.Error())
@@ -125,7 +42,7 @@ func (p *deployment) ScaleDeployment(deploymentName, namespace string, scaleNum
 	//修改副本数
 	scale.Spec.Replicas = int32(scaleNum)
 	//更新副本数
-	newScale, err := K8s.ClientSet.AppsV1().Deployments(namespace).UpdateScale(context.TODO(), deploymentName, scale, metav1.UpdateOptions{})
+	newScale, err := client.AppsV1().Deployments(namespace).UpdateScale(ctx, deploymentName, scale, metav1.UpdateOptions{})
 	if err != nil {
 		logger.Error("更新deployment副本数失败", err.Error())
 		return 0, errors.New("更新deployment副本数失败" + err.Error())
@@ -133,120 +50,29 @@ func (p *deployment) ScaleDeployment(deploymentName, namespace string, scaleNum
 	return newScale.Spec.Replicas, nil
 }
 
-// 重启deployment
-func (p *deployment) RestartDeployment(deploymentName, namespace string) (err error) {
-	//使用patchData map 组装数据
-	patchData := map[string]interface{}{
-		"spec": map[string]interface{}{
-			"template": map[string]interface{}{
-				"spec": map[string]interface{}{
-					"containers": []map[string]interface{}{
-						{"name": deploymentName,
-							"env": []map[string]string{{
-								"name":  "RESTART_",
-								"value": strconv.FormatInt(time.Now().Unix(), 10),
-							}},
-						},
-					},
-				},
-			},
-		},
-	}
-	//序列化为字节。因为patch方法值接收字节类型参数
-	patchByte, err := json.Marshal(patchData)
+// 重启deployment，已暂停的deployment需要先恢复
+func (p *deployment) RestartDeployment(ctx context.Context, cluster, deploymentName, namespace string) (err error) {
+	deployment, err := p.Get(ctx, cluster, deploymentName, namespace)
 	if err != nil {
-		logger.Error("patchdata序列化失败", err)
-		return errors.New("patchdata序列化失败" + err.Error())
+		return err
 	}
-	//调用patch方法更新deployment副本数
-	_, err = K8s.ClientSet.AppsV1().Deployments(namespace).Patch(context.TODO(), deploymentName, "application/strategic-merge-patch+json", patchByte, metav1.PatchOptions{})
-	if err != nil {
-		logger.Error("修改deployment副本数失败", err)
-		return errors.New("修改deployment副本数失败" + err.Error())
+	if deployment.Spec.Paused {
+		return errors.New("deployment已暂停，请先恢复后再重启")
 	}
-	return nil
+	return p.restart(ctx, cluster, deploymentName, namespace)
 }
 
-// 创建deployment
-func (p *deployment) CreateDeployment(data DeployCreate) (err error) {
-	//将data中的数据组装成appsv1.deployment对象
-	deployment := &appsv1.Deployment{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      data.Name,
-			Namespace: data.Namespace,
-			Labels:    data.Label,
-		},
-		Spec: appsv1.DeploymentSpec{
-			Replicas: &data.Replicas,
-			Selector: &metav1.LabelSelector{
-				MatchLabels: data.Label,
-			},
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:   data.Name,
-					Labels: data.Label,
-				},
-				Spec: corev1.PodSpec{
-					Containers: []corev1.Container{
-						{
-							Name:  data.Name,
-							Image: data.Image,
-							Ports: []corev1.ContainerPort{
-								{
-									Name:          "http",
-									Protocol:      corev1.ProtocolTCP,
-									ContainerPort: data.ContainerPort,
-								},
-							},
-						},
-					},
-				},
-			},
-		},
-		Status: appsv1.DeploymentStatus{},
-	}
-	//判断是否打开健康检测功能，若打开，则规定ReadinessProbe和LivenessProbe
-	if data.HealthCheck {
-		deployment.Spec.Template.Spec.Containers[0].ReadinessProbe = &corev1.Probe{
-			ProbeHandler: corev1.ProbeHandler{
-				HTTPGet: &corev1.HTTPGetAction{
-					Path: data.HealthPath,
-					Port: intstr.IntOrString{
-						Type:   0,
-						IntVal: data.ContainerPort,
-					},
-				},
-			},
-			InitialDelaySeconds: 5,
-			TimeoutSeconds:      5,
-			PeriodSeconds:       5,
-		}
-		deployment.Spec.Template.Spec.Containers[0].LivenessProbe = &corev1.Probe{
-			ProbeHandler: corev1.ProbeHandler{
-				HTTPGet: &corev1.HTTPGetAction{
-					Path: data.HealthPath,
-					Port: intstr.IntOrString{
-						Type:   0,
-						IntVal: data.ContainerPort,
-					},
-				},
-			},
-			InitialDelaySeconds: 15,
-			TimeoutSeconds:      15,
-			PeriodSeconds:       15,
-		}
-	}
-	//定义容器的limit和request
-	deployment.Spec.Template.Spec.Containers[0].Resources.Limits = map[corev1.ResourceName]resource.Quantity{
-		corev1.ResourceCPU:    resource.MustParse(data.Cpu),
-		corev1.ResourceMemory: resource.MustParse(data.Memory),
+// 创建deployment，表单校验失败时返回所有不合法的字段
+func (p *deployment) CreateDeployment(ctx context.Context, cluster string, data DeployCreate) (err error) {
+	deployment, err := data.toDeployment()
+	if err != nil {
+		return err
 	}
-	deployment.Spec.Template.Spec.Containers[0].Resources.Requests = map[corev1.ResourceName]resource.Quantity{
-		corev1.ResourceCPU:    resource.MustParse(data.Cpu),
-		corev1.ResourceMemory: resource.MustParse(data.Memory),
+	client, err := K8s.GetClient(ctx, cluster)
+	if err != nil {
+		return err
 	}
-	//创建deployment
-	_, err = K8s.ClientSet.AppsV1().Deployments(data.Namespace).Create(context.TODO(), deployment, metav1.CreateOptions{})
+	_, err = client.AppsV1().Deployments(data.Namespace).Create(ctx, deployment, metav1.CreateOptions{FieldManager: fieldManager})
 	if err != nil {
 		logger.Error("创建deployment失败", err)
 		return errors.New("创建deployment失败" + err.Error())
@@ -254,18 +80,21 @@ func (p *deployment) CreateDeployment(data DeployCreate) (err error) {
 	return nil
 }
 
-// 获取每个命名空间deployment数量
-func (p *deployment) GetDeploymentNumPerNs() (deploymentsNss []*DeploymentsNs, err error) {
+// 获取每个命名空间deployment数量，allowed不为空时只统计用户可见的命名空间
+func (p *deployment) GetDeploymentNumPerNs(ctx context.Context, cluster string, allowed AccessFilter) (deploymentsNss []*DeploymentsNs, err error) {
 	//获取namespace列表
-	namespaceList, err := K8s.ClientSet.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
+	namespaceList, err := Namespace.listFromCache(ctx, cluster, "", labels.Everything())
 	if err != nil {
 		logger.Error("获取namespace列表失败", err)
 		return nil, errors.New("获取namespace列表失败" + err.Error())
 	}
 	//for循环
-	for _, namespace := range namespaceList.Items {
+	for _, namespace := range namespaceList {
+		if allowed != nil && !allowed(namespace.Name, "") {
+			continue
+		}
 		//获取deployment列表
-		deploymentList, err := K8s.ClientSet.AppsV1().Deployments(namespace.Name).List(context.TODO(), metav1.ListOptions{})
+		deploymentList, err := Deployment.listFromCache(ctx, cluster, namespace.Name, labels.Everything())
 		if err != nil {
 			logger.Error("获取deployment列表失败", err)
 			return nil, errors.New("获取deployment列表失败" + err.Error())
@@ -273,28 +102,38 @@ func (p *deployment) GetDeploymentNumPerNs() (deploymentsNss []*DeploymentsNs, e
 		//组装数据
 		deploymentsNs := &DeploymentsNs{
 			Namespace:     namespace.Name,
-			DeploymentNum: len(deploymentList.Items),
+			DeploymentNum: len(deploymentList),
 		}
 		deploymentsNss = append(deploymentsNss, deploymentsNs)
 	}
 	return deploymentsNss, nil
 }
 
-// 把deploymentCell转成appsv1 deployment
-func (p *deployment) fromCells(cells []DataCell) []appsv1.Deployment {
-	deployments := make([]appsv1.Deployment, len(cells))
-	for i := range cells {
-		deployments[i] = appsv1.Deployment(cells[i].(deploymentCell))
+// deployment状态，可用副本数达到期望副本数时为Available，否则为Unavailable
+func deploymentStatus(obj *appsv1.Deployment) string {
+	replicas := int32(1)
+	if obj.Spec.Replicas != nil {
+		replicas = *obj.Spec.Replicas
+	}
+	if obj.Status.AvailableReplicas >= replicas {
+		return "Available"
 	}
-	return deployments
+	return "Unavailable"
 }
 
-// 把appsv1 deployments转成datacell
-func (p *deployment) toCells(std []appsv1.Deployment) []DataCell {
-	cells := make([]DataCell, len(std))
-	for i := range std {
-		cells[i] = deploymentCell(std[i])
+// deployment的就绪副本数
+func deploymentReady(obj *appsv1.Deployment) ComparableValue {
+	return StdComparableInt(obj.Status.ReadyReplicas)
+}
 
+// deployment的就绪比例，就绪副本数/期望副本数，以千分比表示，期望副本数为0时视为全部就绪
+func deploymentReadiness(obj *appsv1.Deployment) ComparableValue {
+	replicas := int32(1)
+	if obj.Spec.Replicas != nil {
+		replicas = *obj.Spec.Replicas
+	}
+	if replicas == 0 {
+		return StdComparableInt(1000)
 	}
-	return cells
+	return StdComparableInt(int64(obj.Status.ReadyReplicas) * 1000 / int64(replicas))
 }
@@ -0,0 +1,328 @@
+package service
+
+import (
+	"errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"reflect"
+	"sigs.k8s.io/yaml"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DiffResult 线上对象与编辑内容的差异
+type DiffResult struct {
+	//从线上对象到编辑内容的json patch(RFC 6902)
+	Patch []JsonPatchOperation `json:"patch"`
+	//yaml格式的unified diff，没有差异时为空
+	Diff string `json:"diff"`
+}
+
+// JsonPatchOperation json patch中的一个操作
+type JsonPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// 对比内容的大小上限，线上对象和编辑内容转成yaml后都不能超过该大小，避免逐行对比占用过多cpu
+const MaxDiffSize = 512 << 10
+
+// 对比内容超过上限，controller据此返回413
+var ErrDiffTooLarge = errors.New("对比内容超过" + strconv.Itoa(MaxDiffSize>>10) + "KiB，无法对比")
+
+// 对比线上对象和编辑内容，忽略resourceVersion、managedFields、status等服务端维护的字段
+func diffObjects(live, edited map[string]interface{}) (*DiffResult, error) {
+	live = stripServerFields(live)
+	edited = stripServerFields(edited)
+	liveYaml, err := yaml.Marshal(live)
+	if err != nil {
+		return nil, err
+	}
+	editedYaml, err := yaml.Marshal(edited)
+	if err != nil {
+		return nil, err
+	}
+	if len(liveYaml) > MaxDiffSize || len(editedYaml) > MaxDiffSize {
+		return nil, ErrDiffTooLarge
+	}
+	return &DiffResult{
+		Patch: jsonPatch("", live, edited, []JsonPatchOperation{}),
+		Diff:  unifiedDiff("live", "edited", string(liveYaml), string(editedYaml)),
+	}, nil
+}
+
+// 复制对象并去掉服务端维护的字段
+func stripServerFields(obj map[string]interface{}) map[string]interface{} {
+	obj = (&unstructured.Unstructured{Object: obj}).DeepCopy().Object
+	for _, field := range serverFields {
+		unstructured.RemoveNestedField(obj, field...)
+	}
+	return obj
+}
+
+// 递归生成json patch，map按key排序保证结果稳定，数组按下标逐个比较
+func jsonPatch(path string, from, to interface{}, ops []JsonPatchOperation) []JsonPatchOperation {
+	switch f := from.(type) {
+	case map[string]interface{}:
+		t, ok := to.(map[string]interface{})
+		if !ok {
+			return append(ops, JsonPatchOperation{Op: "replace", Path: path, Value: to})
+		}
+		for _, key := range sortedKeys(f) {
+			if value, ok := t[key]; ok {
+				ops = jsonPatch(path+"/"+escapePointer(key), f[key], value, ops)
+			} else {
+				ops = append(ops, JsonPatchOperation{Op: "remove", Path: path + "/" + escapePointer(key)})
+			}
+		}
+		for _, key := range sortedKeys(t) {
+			if _, ok := f[key]; !ok {
+				ops = append(ops, JsonPatchOperation{Op: "add", Path: path + "/" + escapePointer(key), Value: t[key]})
+			}
+		}
+	case []interface{}:
+		t, ok := to.([]interface{})
+		if !ok {
+			return append(ops, JsonPatchOperation{Op: "replace", Path: path, Value: to})
+		}
+		common := len(f)
+		if len(t) < common {
+			common = len(t)
+		}
+		for i := 0; i < common; i++ {
+			ops = jsonPatch(path+"/"+strconv.Itoa(i), f[i], t[i], ops)
+		}
+		for i := common; i < len(t); i++ {
+			ops = append(ops, JsonPatchOperation{Op: "add", Path: path + "/" + strconv.Itoa(i), Value: t[i]})
+		}
+		//从后往前删除，保证前面元素的下标不变
+		for i := len(f) - 1; i >= common; i-- {
+			ops = append(ops, JsonPatchOperation{Op: "remove", Path: path + "/" + strconv.Itoa(i)})
+		}
+	default:
+		if !reflect.DeepEqual(from, to) {
+			ops = append(ops, JsonPatchOperation{Op: "replace", Path: path, Value: to})
+		}
+	}
+	return ops
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// json pointer中的~和/需要转义
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+func escapePointer(key string) string {
+	return pointerEscaper.Replace(key)
+}
+
+// diff中的一行，op为' '、'-'、'+'，aPos和bPos为该行之前两侧已有的行数
+type diffLine struct {
+	op   byte
+	text string
+	aPos int
+	bPos int
+}
+
+// 上下文行数，与diff -u一致
+const diffContext = 3
+
+// 生成unified diff
+func unifiedDiff(fromName, toName, from, to string) string {
+	lines := diffLines(splitLines(from), splitLines(to))
+	var buf strings.Builder
+	for i := 0; i < len(lines); {
+		if lines[i].op == ' ' {
+			i++
+			continue
+		}
+		//相邻变更之间的相同行不超过两倍上下文时合并到同一个hunk
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for j := i; j < len(lines); j++ {
+			if lines[j].op != ' ' {
+				end = j
+			} else if j-end > 2*diffContext {
+				break
+			}
+		}
+		stop := end + diffContext + 1
+		if stop > len(lines) {
+			stop = len(lines)
+		}
+		if buf.Len() == 0 {
+			buf.WriteString("--- " + fromName + "\n+++ " + toName + "\n")
+		}
+		aCount, bCount := 0, 0
+		for _, line := range lines[start:stop] {
+			if line.op != '+' {
+				aCount++
+			}
+			if line.op != '-' {
+				bCount++
+			}
+		}
+		buf.WriteString("@@ -" + hunkRange(lines[start].aPos, aCount) + " +" + hunkRange(lines[start].bPos, bCount) + " @@\n")
+		for _, line := range lines[start:stop] {
+			buf.WriteByte(line.op)
+			buf.WriteString(line.text + "\n")
+		}
+		i = stop
+	}
+	return buf.String()
+}
+
+// hunk头中的行号范围，起始行从1开始，没有行时为前一行的行号
+func hunkRange(pos, count int) string {
+	if count == 0 {
+		return strconv.Itoa(pos) + ",0"
+	}
+	if count == 1 {
+		return strconv.Itoa(pos + 1)
+	}
+	return strconv.Itoa(pos+1) + "," + strconv.Itoa(count)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// 逐行对比，使用线性空间的Myers算法，内存占用与行数成正比
+// 同一处变更中删除的行排在新增的行之前，与diff -u一致
+func diffLines(a, b []string) []diffLine {
+	lines := make([]diffLine, 0, len(a)+len(b))
+	aPos, bPos := 0, 0
+	add := func(op byte, text string) {
+		lines = append(lines, diffLine{op: op, text: text, aPos: aPos, bPos: bPos})
+		if op != '+' {
+			aPos++
+		}
+		if op != '-' {
+			bPos++
+		}
+	}
+	var changed []diffLine
+	//连续的变更先暂存，遇到相同行时按先删除后新增输出
+	flush := func() {
+		for _, op := range []byte{'-', '+'} {
+			for _, line := range changed {
+				if line.op == op {
+					add(op, line.text)
+				}
+			}
+		}
+		changed = changed[:0]
+	}
+	myersDiff(a, b, func(op byte, text string) {
+		if op != ' ' {
+			changed = append(changed, diffLine{op: op, text: text})
+			return
+		}
+		flush()
+		add(op, text)
+	})
+	flush()
+	return lines
+}
+
+// 按顺序输出a到b的编辑操作，先去掉相同的首尾，再以中间snake为界递归对比两侧
+func myersDiff(a, b []string, emit func(op byte, text string)) {
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		emit(' ', a[0])
+		a, b = a[1:], b[1:]
+	}
+	suffix := 0
+	for suffix < len(a) && suffix < len(b) && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	tail := a[len(a)-suffix:]
+	a, b = a[:len(a)-suffix], b[:len(b)-suffix]
+	switch {
+	case len(a) == 0:
+		for _, line := range b {
+			emit('+', line)
+		}
+	case len(b) == 0:
+		for _, line := range a {
+			emit('-', line)
+		}
+	default:
+		//去掉首尾后两侧都不为空时至少有两处编辑，snake两侧的编辑数都少于整体，递归一定会结束
+		x, y, u, v := middleSnake(a, b)
+		myersDiff(a[:x], b[:y], emit)
+		for _, line := range a[x:u] {
+			emit(' ', line)
+		}
+		myersDiff(a[u:], b[v:], emit)
+	}
+	for _, line := range tail {
+		emit(' ', line)
+	}
+}
+
+// 查找最短编辑路径中间的snake，返回snake在a中的起止位置x、u和在b中的起止位置y、v
+// 从两端同时搜索，只保存每条对角线上到达的最远位置
+func middleSnake(a, b []string) (x, y, u, v int) {
+	n, m := len(a), len(b)
+	delta := n - m
+	limit := (n + m + 1) / 2
+	offset := limit + 1
+	//forward[k]为正向搜索在对角线k(x-y)上到达的最远x，backward[k]为从末尾反向搜索在对角线k上走过的最远距离
+	forward := make([]int, 2*offset+1)
+	backward := make([]int, 2*offset+1)
+	for d := 0; d <= limit; d++ {
+		for k := -d; k <= d; k += 2 {
+			if k == -d || (k != d && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y = x - k
+			u, v = x, y
+			for u < n && v < m && a[u] == b[v] {
+				u++
+				v++
+			}
+			forward[offset+k] = u
+			//反向对角线为delta-k，编辑数之和为奇数时在正向搜索中相遇
+			if back := delta - k; delta%2 != 0 && back >= -(d-1) && back <= d-1 && u+backward[offset+back] >= n {
+				return x, y, u, v
+			}
+		}
+		for k := -d; k <= d; k += 2 {
+			var bx int
+			if k == -d || (k != d && backward[offset+k-1] < backward[offset+k+1]) {
+				bx = backward[offset+k+1]
+			} else {
+				bx = backward[offset+k-1] + 1
+			}
+			by := bx - k
+			ex, ey := bx, by
+			for ex < n && ey < m && a[n-1-ex] == b[m-1-ey] {
+				ex++
+				ey++
+			}
+			backward[offset+k] = ex
+			//编辑数之和为偶数时在反向搜索中相遇，反向走过的距离换算成正向的位置
+			if fwd := delta - k; delta%2 == 0 && fwd >= -d && fwd <= d && forward[offset+fwd]+ex >= n {
+				return n - ex, m - ey, n - bx, m - by
+			}
+		}
+	}
+	//两侧都不为空时一定会在上面相遇
+	return 0, 0, 0, 0
+}
@@ -0,0 +1,274 @@
+package service
+
+import (
+	"math/rand"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestJsonPatch(t *testing.T) {
+	tests := []struct {
+		name string
+		from interface{}
+		to   interface{}
+		want []JsonPatchOperation
+	}{
+		{
+			name: "相同",
+			from: map[string]interface{}{"a": "1", "b": []interface{}{"x"}},
+			to:   map[string]interface{}{"a": "1", "b": []interface{}{"x"}},
+			want: []JsonPatchOperation{},
+		},
+		{
+			name: "修改、删除和新增字段按key排序",
+			from: map[string]interface{}{"b": "1", "a": "1", "c": "1"},
+			to:   map[string]interface{}{"b": "2", "d": "1", "a": "1"},
+			want: []JsonPatchOperation{
+				{Op: "replace", Path: "/b", Value: "2"},
+				{Op: "remove", Path: "/c"},
+				{Op: "add", Path: "/d", Value: "1"},
+			},
+		},
+		{
+			name: "嵌套对象",
+			from: map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(1)}},
+			to:   map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(3)}},
+			want: []JsonPatchOperation{{Op: "replace", Path: "/spec/replicas", Value: int64(3)}},
+		},
+		{
+			name: "数组新增元素",
+			from: map[string]interface{}{"args": []interface{}{"a"}},
+			to:   map[string]interface{}{"args": []interface{}{"a", "b", "c"}},
+			want: []JsonPatchOperation{
+				{Op: "add", Path: "/args/1", Value: "b"},
+				{Op: "add", Path: "/args/2", Value: "c"},
+			},
+		},
+		{
+			name: "数组从后往前删除",
+			from: map[string]interface{}{"args": []interface{}{"a", "b", "c"}},
+			to:   map[string]interface{}{"args": []interface{}{"x"}},
+			want: []JsonPatchOperation{
+				{Op: "replace", Path: "/args/0", Value: "x"},
+				{Op: "remove", Path: "/args/2"},
+				{Op: "remove", Path: "/args/1"},
+			},
+		},
+		{
+			name: "类型变化时整体替换",
+			from: map[string]interface{}{"data": map[string]interface{}{"a": "1"}},
+			to:   map[string]interface{}{"data": []interface{}{"a"}},
+			want: []JsonPatchOperation{{Op: "replace", Path: "/data", Value: []interface{}{"a"}}},
+		},
+		{
+			name: "key中的~和/转义",
+			from: map[string]interface{}{"metadata": map[string]interface{}{"annotations": map[string]interface{}{}}},
+			to:   map[string]interface{}{"metadata": map[string]interface{}{"annotations": map[string]interface{}{"app.io/a~b": "1"}}},
+			want: []JsonPatchOperation{{Op: "add", Path: "/metadata/annotations/app.io~1a~0b", Value: "1"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := jsonPatch("", tt.from, tt.to, []JsonPatchOperation{})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("jsonPatch() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// 生成n行文本，每行为前缀加行号
+func numberedLines(prefix string, n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = prefix + strconv.Itoa(i+1)
+	}
+	return lines
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	ten := numberedLines("line", 10)
+	join := func(lines []string) string {
+		if len(lines) == 0 {
+			return ""
+		}
+		return strings.Join(lines, "\n") + "\n"
+	}
+	replace := func(lines []string, index int, text string) []string {
+		result := append([]string{}, lines...)
+		result[index] = text
+		return result
+	}
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want string
+	}{
+		{name: "没有差异", from: join(ten), to: join(ten), want: ""},
+		{
+			name: "修改中间一行带三行上下文",
+			from: join(ten),
+			to:   join(replace(ten, 4, "changed")),
+			want: "--- live\n+++ edited\n@@ -2,7 +2,7 @@\n line2\n line3\n line4\n-line5\n+changed\n line6\n line7\n line8\n",
+		},
+		{
+			name: "修改第一行",
+			from: join(ten),
+			to:   join(replace(ten, 0, "changed")),
+			want: "--- live\n+++ edited\n@@ -1,4 +1,4 @@\n-line1\n+changed\n line2\n line3\n line4\n",
+		},
+		{
+			name: "末尾新增",
+			from: "a\nb\n",
+			to:   "a\nb\nc\n",
+			want: "--- live\n+++ edited\n@@ -1,2 +1,3 @@\n a\n b\n+c\n",
+		},
+		{
+			name: "删除所有行",
+			from: "a\n",
+			to:   "",
+			want: "--- live\n+++ edited\n@@ -1 +0,0 @@\n-a\n",
+		},
+		{
+			name: "从空内容新增",
+			from: "",
+			to:   "a\nb\n",
+			want: "--- live\n+++ edited\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name: "相距较近的变更合并到同一个hunk",
+			from: join(ten),
+			to:   join(replace(replace(ten, 1, "x"), 7, "y")),
+			want: "--- live\n+++ edited\n@@ -1,10 +1,10 @@\n line1\n-line2\n+x\n line3\n line4\n line5\n line6\n line7\n-line8\n+y\n line9\n line10\n",
+		},
+		{
+			name: "相距较远的变更分成两个hunk",
+			from: join(numberedLines("line", 20)),
+			to:   join(replace(replace(numberedLines("line", 20), 1, "x"), 17, "y")),
+			want: "--- live\n+++ edited\n@@ -1,5 +1,5 @@\n line1\n-line2\n+x\n line3\n line4\n line5\n" +
+				"@@ -15,6 +15,6 @@\n line15\n line16\n line17\n-line18\n+y\n line19\n line20\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unifiedDiff("live", "edited", tt.from, tt.to); got != tt.want {
+				t.Errorf("unifiedDiff() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffObjects(t *testing.T) {
+	live := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":            "cfg",
+			"resourceVersion": "100",
+			"uid":             "abc",
+		},
+		"data": map[string]interface{}{"mode": "dev"},
+	}
+	edited := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"name": "cfg"},
+		"data":       map[string]interface{}{"mode": "prod"},
+	}
+	result, err := diffObjects(live, edited)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//resourceVersion、uid等服务端维护的字段不出现在差异中
+	want := []JsonPatchOperation{{Op: "replace", Path: "/data/mode", Value: "prod"}}
+	if !reflect.DeepEqual(result.Patch, want) {
+		t.Errorf("diffObjects() patch = %+v, want %+v", result.Patch, want)
+	}
+	if !strings.Contains(result.Diff, "-  mode: dev\n+  mode: prod\n") || strings.Contains(result.Diff, "resourceVersion") {
+		t.Errorf("diffObjects() diff = %s", result.Diff)
+	}
+	//不修改传入的对象
+	if _, ok := live["metadata"].(map[string]interface{})["uid"]; !ok {
+		t.Error("diffObjects() modified live object")
+	}
+}
+
+// 最长公共子序列的长度，用于校验diff的编辑数最少
+func lcsLength(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	for i := range a {
+		cur := make([]int, len(b)+1)
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else if prev[j+1] >= cur[j] {
+				cur[j+1] = prev[j+1]
+			} else {
+				cur[j+1] = cur[j]
+			}
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func TestDiffLinesMinimal(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	randomLines := func() []string {
+		lines := make([]string, random.Intn(30))
+		for i := range lines {
+			lines[i] = strconv.Itoa(random.Intn(4))
+		}
+		return lines
+	}
+	for i := 0; i < 500; i++ {
+		a, b := randomLines(), randomLines()
+		lines := diffLines(a, b)
+		var gotA, gotB []string
+		edits := 0
+		for _, line := range lines {
+			if line.op != '+' {
+				gotA = append(gotA, line.text)
+			}
+			if line.op != '-' {
+				gotB = append(gotB, line.text)
+			}
+			if line.op != ' ' {
+				edits++
+			}
+		}
+		if strings.Join(gotA, ",") != strings.Join(a, ",") || strings.Join(gotB, ",") != strings.Join(b, ",") {
+			t.Fatalf("diffLines(%v, %v) = %v", a, b, lines)
+		}
+		if want := len(a) + len(b) - 2*lcsLength(a, b); edits != want {
+			t.Fatalf("diffLines(%v, %v) edits = %d, want %d", a, b, edits, want)
+		}
+	}
+}
+
+func TestDiffLinesLarge(t *testing.T) {
+	//大量行时只占用线性内存，少量变更时很快完成
+	from := numberedLines("line", 50000)
+	to := append([]string{}, from...)
+	to[100], to[25000], to[49000] = "a", "b", "c"
+	edits := 0
+	for _, line := range diffLines(from, to) {
+		if line.op != ' ' {
+			edits++
+		}
+	}
+	if edits != 6 {
+		t.Errorf("diffLines() edits = %d, want 6", edits)
+	}
+}
+
+func TestDiffObjectsTooLarge(t *testing.T) {
+	live := map[string]interface{}{"data": map[string]interface{}{"a": strings.Repeat("x", MaxDiffSize)}}
+	edited := map[string]interface{}{"data": map[string]interface{}{"a": "y"}}
+	if _, err := diffObjects(live, edited); err != ErrDiffTooLarge {
+		t.Errorf("diffObjects() err = %v, want %v", err, ErrDiffTooLarge)
+	}
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/wonderivan/logger"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	//被PodDisruptionBudget拒绝后重试驱逐的间隔
+	evictionRetryInterval = 5 * time.Second
+	//检查pod是否已删除的间隔
+	podDeletePollInterval = time.Second
+)
+
+// 驱逐过程中pod的状态
+const (
+	DrainSkipped  = "Skipped"
+	DrainEvicting = "Evicting"
+	DrainRetrying = "Retrying"
+	DrainEvicted  = "Evicted"
+	DrainFailed   = "Failed"
+)
+
+// 定义结构体用于驱逐节点上的pod，与kubectl drain的参数一致
+type NodeDrain struct {
+	NodeName string `json:"node_name"`
+	//pod优雅终止的秒数，为空时使用pod自身的terminationGracePeriodSeconds
+	GracePeriodSeconds *int64 `json:"grace_period_seconds"`
+	//整个驱逐过程的超时秒数，为0时不超时
+	TimeoutSeconds int64 `json:"timeout_seconds"`
+	//是否驱逐使用emptyDir的pod，emptyDir中的数据会丢失
+	DeleteEmptyDirData bool `json:"delete_emptydir_data"`
+	//是否驱逐不受控制器管理的pod，这些pod驱逐后不会被重建
+	Force   bool   `json:"force"`
+	Cluster string `json:"cluster"`
+}
+
+// 单个pod的驱逐进度
+type DrainProgress struct {
+	Pod       string `json:"pod"`
+	Namespace string `json:"namespace"`
+	//Skipped、Evicting、Retrying、Evicted、Failed
+	Status string `json:"status"`
+	Msg    string `json:"msg"`
+}
+
+// 驱逐节点上的pod，先将节点设置为禁止调度，再通过Eviction API逐个驱逐，驱逐会遵循PodDisruptionBudget
+// 跳过DaemonSet管理的pod和static pod(mirror pod)，使用emptyDir或不受控制器管理的pod需要显式确认
+// 校验通过后返回进度channel，所有pod处理完成或超时后关闭，ctx取消时停止驱逐
+func (n *node) DrainNode(ctx context.Context, cluster string, data NodeDrain) (progress <-chan *DrainProgress, err error) {
+	client, err := K8s.GetClient(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	podList, err := client.CoreV1().Pods("").List(ctx, metav1.ListOptions{FieldSelector: "spec.nodeName=" + data.NodeName})
+	if err != nil {
+		logger.Error("获取node上的pod失败", err)
+		return nil, errors.New("获取node上的pod失败" + err.Error())
+	}
+	var pods []corev1.Pod
+	var skipped []*DrainProgress
+	var blocked []string
+	for _, pod := range podList.Items {
+		skip, reason := drainFilter(&pod, data)
+		switch {
+		case skip:
+			skipped = append(skipped, &DrainProgress{Pod: pod.Name, Namespace: pod.Namespace, Status: DrainSkipped, Msg: reason})
+		case reason != "":
+			blocked = append(blocked, pod.Namespace+"/"+pod.Name+"("+reason+")")
+		default:
+			pods = append(pods, pod)
+		}
+	}
+	//与kubectl一致，存在无法安全驱逐的pod时不做任何操作
+	if len(blocked) > 0 {
+		return nil, errors.New("以下pod无法驱逐: " + strings.Join(blocked, ", "))
+	}
+	if err = n.CordonNode(ctx, cluster, data.NodeName); err != nil {
+		return nil, err
+	}
+	ch := make(chan *DrainProgress, len(podList.Items))
+	for _, item := range skipped {
+		ch <- item
+	}
+	//超时只停止驱逐，进度仍然推送，直到调用方的ctx结束
+	evictCtx, cancel := ctx, context.CancelFunc(func() {})
+	if data.TimeoutSeconds > 0 {
+		evictCtx, cancel = context.WithTimeout(ctx, time.Duration(data.TimeoutSeconds)*time.Second)
+	}
+	var wg sync.WaitGroup
+	for i := range pods {
+		wg.Add(1)
+		go func(pod *corev1.Pod) {
+			defer wg.Done()
+			evictPod(evictCtx, client, pod, data.GracePeriodSeconds, func(status, msg string) {
+				select {
+				case ch <- &DrainProgress{Pod: pod.Name, Namespace: pod.Namespace, Status: status, Msg: msg}:
+				case <-ctx.Done():
+				}
+			})
+		}(&pods[i])
+	}
+	go func() {
+		wg.Wait()
+		cancel()
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// 判断pod在驱逐时是否跳过，不跳过但需要确认时返回原因
+func drainFilter(pod *corev1.Pod, data NodeDrain) (skip bool, reason string) {
+	if _, ok := pod.Annotations[corev1.MirrorPodAnnotationKey]; ok {
+		return true, "static pod，由kubelet管理"
+	}
+	controller := metav1.GetControllerOf(pod)
+	if controller != nil && controller.Kind == "DaemonSet" {
+		return true, "DaemonSet管理的pod"
+	}
+	//已结束的pod驱逐不会造成影响
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return false, ""
+	}
+	if controller == nil && !data.Force {
+		return false, "不受控制器管理，需要指定force"
+	}
+	if !data.DeleteEmptyDirData {
+		for _, volume := range pod.Spec.Volumes {
+			if volume.EmptyDir != nil {
+				return false, "使用emptyDir，需要指定delete_emptydir_data"
+			}
+		}
+	}
+	return false, ""
+}
+
+// 驱逐pod并等待删除完成，被PodDisruptionBudget拒绝时持续重试直到ctx结束
+func evictPod(ctx context.Context, client kubernetes.Interface, pod *corev1.Pod, gracePeriodSeconds *int64, report func(status, msg string)) {
+	eviction := &policyv1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+		DeleteOptions: &metav1.DeleteOptions{
+			GracePeriodSeconds: gracePeriodSeconds,
+			//pod被重建为同名的新pod时不会误删
+			Preconditions: &metav1.Preconditions{UID: &pod.UID},
+		},
+	}
+	report(DrainEvicting, "")
+	for {
+		err := client.PolicyV1().Evictions(pod.Namespace).Evict(ctx, eviction)
+		if err == nil || apierrors.IsNotFound(err) || apierrors.IsConflict(err) {
+			break
+		}
+		//429表示驱逐会违反PodDisruptionBudget，稍后重试
+		if !apierrors.IsTooManyRequests(err) {
+			logger.Error("驱逐pod失败", err)
+			report(DrainFailed, "驱逐pod失败"+err.Error())
+			return
+		}
+		report(DrainRetrying, err.Error())
+		select {
+		case <-ctx.Done():
+			report(DrainFailed, "驱逐pod超时"+ctx.Err().Error())
+			return
+		case <-time.After(evictionRetryInterval):
+		}
+	}
+	if err := waitForPodDeleted(ctx, client, pod.Namespace, pod.Name, pod.UID); err != nil {
+		report(DrainFailed, err.Error())
+		return
+	}
+	report(DrainEvicted, "")
+}
+
+// 等待pod删除，pod不存在或已被同名的新pod替换时视为删除完成
+func waitForPodDeleted(ctx context.Context, client kubernetes.Interface, namespace, podName string, uid types.UID) error {
+	for {
+		pod, err := client.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) || (err == nil && pod.UID != uid) {
+			return nil
+		}
+		if err != nil && ctx.Err() == nil {
+			logger.Error("获取pod状态失败", err)
+			return errors.New("获取pod状态失败" + err.Error())
+		}
+		select {
+		case <-ctx.Done():
+			return errors.New("等待pod删除超时" + ctx.Err().Error())
+		case <-time.After(podDeletePollInterval):
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func TestDrainFilter(t *testing.T) {
+	controlled := func(kind string) []metav1.OwnerReference {
+		isController := true
+		return []metav1.OwnerReference{{Kind: kind, Name: "owner", Controller: &isController}}
+	}
+	emptyDir := []corev1.Volume{{Name: "tmp", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}}
+	tests := []struct {
+		name       string
+		pod        corev1.Pod
+		data       NodeDrain
+		wantSkip   bool
+		wantReason bool
+	}{
+		{
+			name: "replicaset管理的pod直接驱逐",
+			pod:  corev1.Pod{ObjectMeta: metav1.ObjectMeta{OwnerReferences: controlled("ReplicaSet")}},
+		},
+		{
+			name:       "static pod跳过",
+			pod:        corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{corev1.MirrorPodAnnotationKey: "x"}}},
+			wantSkip:   true,
+			wantReason: true,
+		},
+		{
+			name:       "daemonset管理的pod跳过",
+			pod:        corev1.Pod{ObjectMeta: metav1.ObjectMeta{OwnerReferences: controlled("DaemonSet")}},
+			wantSkip:   true,
+			wantReason: true,
+		},
+		{
+			name:       "不受控制器管理的pod需要force",
+			pod:        corev1.Pod{},
+			wantReason: true,
+		},
+		{
+			name: "指定force时驱逐不受控制器管理的pod",
+			pod:  corev1.Pod{},
+			data: NodeDrain{Force: true},
+		},
+		{
+			name: "已结束的pod不需要force",
+			pod:  corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodSucceeded}},
+		},
+		{
+			name:       "使用emptyDir需要确认",
+			pod:        corev1.Pod{ObjectMeta: metav1.ObjectMeta{OwnerReferences: controlled("ReplicaSet")}, Spec: corev1.PodSpec{Volumes: emptyDir}},
+			wantReason: true,
+		},
+		{
+			name: "指定delete_emptydir_data时驱逐使用emptyDir的pod",
+			pod:  corev1.Pod{ObjectMeta: metav1.ObjectMeta{OwnerReferences: controlled("ReplicaSet")}, Spec: corev1.PodSpec{Volumes: emptyDir}},
+			data: NodeDrain{DeleteEmptyDirData: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			skip, reason := drainFilter(&tt.pod, tt.data)
+			if skip != tt.wantSkip || (reason != "") != tt.wantReason {
+				t.Errorf("drainFilter() = %v, %q, want skip %v, reason %v", skip, reason, tt.wantSkip, tt.wantReason)
+			}
+		})
+	}
+}
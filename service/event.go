@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/wonderivan/logger"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sort"
+	"time"
+)
+
+// 事件不放入informer缓存，数量多且变化频繁，每次直接请求apiserver
+var Event event
+
+type event struct{}
+
+// 事件查询条件，字段为空时不过滤，除分页外都下推到apiserver的字段选择器
+type EventQuery struct {
+	//Normal或Warning
+	Type   string
+	Reason string
+	//事件关联对象的Kind和名称，如Pod、Deployment
+	Kind  string
+	Name  string
+	Limit int
+	Page  int
+}
+
+// 事件列表，按时间倒序
+type EventsResp struct {
+	Items []corev1.Event `json:"items"`
+	Total int            `json:"total"`
+}
+
+// 获取事件列表，namespace为空时获取所有命名空间，allowed不为空时只返回用户可见命名空间的事件
+func (e *event) GetEvents(ctx context.Context, cluster, namespace string, query *EventQuery, allowed AccessFilter) (resp *EventsResp, err error) {
+	selector, err := query.fieldSet()
+	if err != nil {
+		return nil, err
+	}
+	events, err := e.list(ctx, cluster, namespace, selector)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]corev1.Event, 0, len(events))
+	for _, item := range events {
+		if allowed == nil || allowed(item.Namespace, item.Name) {
+			items = append(items, item)
+		}
+	}
+	return &EventsResp{
+		Items: paginate(items, query.Limit, query.Page),
+		Total: len(items),
+	}, nil
+}
+
+// 查询条件对应的字段选择器，类型不合法时返回错误
+func (q *EventQuery) fieldSet() (fields.Set, error) {
+	switch q.Type {
+	case "", corev1.EventTypeNormal, corev1.EventTypeWarning:
+	default:
+		return nil, errors.New("不支持的事件类型" + q.Type + "，只能为Normal或Warning")
+	}
+	selector := fields.Set{}
+	for key, value := range map[string]string{
+		"type":                q.Type,
+		"reason":              q.Reason,
+		"involvedObject.kind": q.Kind,
+		"involvedObject.name": q.Name,
+	} {
+		if value != "" {
+			selector[key] = value
+		}
+	}
+	return selector, nil
+}
+
+// 获取与对象相关的事件，按时间倒序，用于在详情中展示对象的事件时间线
+// 集群级资源的事件可能记录在任意命名空间，因此在所有命名空间中查找
+func (e *event) GetObjectEvents(ctx context.Context, cluster string, obj runtime.Object) ([]corev1.Event, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, err
+	}
+	//typed client返回的对象没有TypeMeta，通过scheme获取Kind
+	kinds, _, err := scheme.Scheme.ObjectKinds(obj)
+	if err != nil {
+		return nil, err
+	}
+	return e.list(ctx, cluster, accessor.GetNamespace(), fields.Set{
+		"involvedObject.kind": kinds[0].Kind,
+		"involvedObject.name": accessor.GetName(),
+	})
+}
+
+// 按字段选择器获取事件，按时间倒序
+func (e *event) list(ctx context.Context, cluster, namespace string, selector fields.Set) ([]corev1.Event, error) {
+	client, err := K8s.GetClient(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	eventList, err := client.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: selector.AsSelector().String(),
+	})
+	if err != nil {
+		logger.Error("获取事件列表失败", err)
+		return nil, errors.New("获取事件列表失败" + err.Error())
+	}
+	events := eventList.Items
+	sort.SliceStable(events, func(i, j int) bool {
+		return eventTime(&events[i]).After(eventTime(&events[j]))
+	})
+	return events, nil
+}
+
+// 事件最后一次发生的时间，依次取lastTimestamp、eventTime、创建时间
+func eventTime(event *corev1.Event) time.Time {
+	if !event.LastTimestamp.IsZero() {
+		return event.LastTimestamp.Time
+	}
+	if !event.EventTime.IsZero() {
+		return event.EventTime.Time
+	}
+	return event.CreationTimestamp.Time
+}
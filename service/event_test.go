@@ -0,0 +1,85 @@
+package service
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEventQueryFieldSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   EventQuery
+		want    fields.Set
+		wantErr bool
+	}{
+		{name: "没有条件", query: EventQuery{}, want: fields.Set{}},
+		{name: "按类型", query: EventQuery{Type: corev1.EventTypeWarning}, want: fields.Set{"type": "Warning"}},
+		{
+			name:  "所有条件",
+			query: EventQuery{Type: corev1.EventTypeNormal, Reason: "Scheduled", Kind: "Pod", Name: "web-1"},
+			want: fields.Set{
+				"type":                "Normal",
+				"reason":              "Scheduled",
+				"involvedObject.kind": "Pod",
+				"involvedObject.name": "web-1",
+			},
+		},
+		{name: "分页条件不下推", query: EventQuery{Name: "web-1", Limit: 10, Page: 2}, want: fields.Set{"involvedObject.name": "web-1"}},
+		{name: "不支持的类型", query: EventQuery{Type: "Error"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.query.fieldSet()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("fieldSet() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fieldSet() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEventTime(t *testing.T) {
+	created := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	last := created.Add(time.Hour)
+	tests := []struct {
+		name  string
+		event corev1.Event
+		want  time.Time
+	}{
+		{
+			name: "优先使用lastTimestamp",
+			event: corev1.Event{
+				ObjectMeta:    metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(created)},
+				LastTimestamp: metav1.NewTime(last),
+				EventTime:     metav1.NewMicroTime(created.Add(time.Minute)),
+			},
+			want: last,
+		},
+		{
+			name: "其次使用eventTime",
+			event: corev1.Event{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(created)},
+				EventTime:  metav1.NewMicroTime(last),
+			},
+			want: last,
+		},
+		{
+			name:  "最后使用创建时间",
+			event: corev1.Event{ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(created)}},
+			want:  created,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := eventTime(&tt.event); !got.Equal(tt.want) {
+				t.Errorf("eventTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
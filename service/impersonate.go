@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"strings"
+)
+
+type claimsContextKey struct{}
+
+// WithClaims 将当前用户保存到context中，模拟用户访问集群时据此获取用户名和组
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsContextKey{}, claims)
+}
+
+// 获取context中的当前用户，没有时返回nil
+func claimsFrom(ctx context.Context) *Claims {
+	claims, _ := ctx.Value(claimsContextKey{}).(*Claims)
+	return claims
+}
+
+// 以用户身份访问集群的客户端
+type impersonatedClient struct {
+	clientset *kubernetes.Clientset
+	dynamic   dynamic.Interface
+	conf      *rest.Config
+}
+
+// 获取以context中的用户身份访问集群的客户端，按集群、用户和组缓存
+// 客户端共用管理员kubeconfig的连接配置，client-go按tls配置复用底层连接
+func (k *k8s) impersonate(ctx context.Context, cluster string) (*impersonatedClient, error) {
+	conf, ok := k.RestConfMap[cluster]
+	if !ok {
+		return nil, errors.New("集群" + cluster + "不存在")
+	}
+	//没有用户时拒绝访问，避免以管理员身份执行
+	claims := claimsFrom(ctx)
+	if claims == nil || claims.Subject == "" {
+		return nil, errors.New("未获取到当前用户，无法模拟用户访问集群")
+	}
+	key := cluster + "\x00" + claims.Subject + "\x00" + strings.Join(claims.Groups, "\x00")
+	if client, ok := k.impersonated.Load(key); ok {
+		return client.(*impersonatedClient), nil
+	}
+	userConf := rest.CopyConfig(conf)
+	userConf.Impersonate = rest.ImpersonationConfig{
+		UserName: claims.Subject,
+		Groups:   claims.Groups,
+	}
+	clientset, err := kubernetes.NewForConfig(userConf)
+	if err != nil {
+		return nil, errors.New("创建模拟用户的clientset失败" + err.Error())
+	}
+	dynamicClient, err := dynamic.NewForConfig(userConf)
+	if err != nil {
+		return nil, errors.New("创建模拟用户的dynamic client失败" + err.Error())
+	}
+	client, _ := k.impersonated.LoadOrStore(key, &impersonatedClient{
+		clientset: clientset,
+		dynamic:   dynamicClient,
+		conf:      userConf,
+	})
+	return client.(*impersonatedClient), nil
+}
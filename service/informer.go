@@ -0,0 +1,71 @@
+package service
+
+import (
+	"errors"
+	"github.com/wonderivan/logger"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// informer缓存，列表接口从缓存中读取数据，避免每次请求都对apiserver发起全量List
+// 缓存中的对象与informer共享，只能读取不能修改
+type informerCache struct {
+	Factory informers.SharedInformerFactory
+	//各个informer的同步状态，全部同步完成后缓存才可用
+	synced []cache.InformerSynced
+	stopCh chan struct{}
+}
+
+// 为集群创建informer缓存并启动
+func newInformerCache(cluster string, client kubernetes.Interface) *informerCache {
+	factory := informers.NewSharedInformerFactory(client, 0)
+	c := &informerCache{
+		Factory: factory,
+		stopCh:  make(chan struct{}),
+	}
+	//根据资源注册表注册需要缓存的资源类型，未注册和声明不缓存的类型不会启动
+	//secret不缓存，避免所有secret的明文常驻内存
+	for _, info := range GetResourceInfos() {
+		if !info.IsCached() {
+			continue
+		}
+		genericInformer, err := factory.ForResource(info.GetGVR())
+		if err != nil {
+			logger.Error("集群"+cluster+"注册"+info.GetName()+"informer失败", err)
+			continue
+		}
+		c.synced = append(c.synced, genericInformer.Informer().HasSynced)
+	}
+	factory.Start(c.stopCh)
+	//异步等待同步完成，不阻塞服务启动
+	go func() {
+		if cache.WaitForCacheSync(c.stopCh, c.synced...) {
+			logger.Info("集群" + cluster + "informer缓存同步完成")
+		}
+	}()
+	return c
+}
+
+// 判断所有informer是否已完成首次同步
+func (c *informerCache) HasSynced() bool {
+	for _, synced := range c.synced {
+		if !synced() {
+			return false
+		}
+	}
+	return true
+}
+
+// 根据集群名获取informer工厂，缓存未同步完成时返回错误
+func (k *k8s) GetInformer(cluster string) (informers.SharedInformerFactory, error) {
+	c, ok := k.CacheMap[cluster]
+	if !ok {
+		logger.Error("集群" + cluster + "不存在，无法获取informer缓存")
+		return nil, errors.New("集群" + cluster + "不存在，无法获取informer缓存")
+	}
+	if !c.HasSynced() {
+		return nil, errors.New("集群" + cluster + "缓存尚未同步完成，请稍后重试")
+	}
+	return c.Factory, nil
+}
@@ -1,30 +1,151 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"github.com/wonderivan/logger"
 	"k8s-platform/config"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/tools/clientcmd"
+	"sort"
+	"sync"
 )
 
 var K8s k8s
 
 type k8s struct {
-	ClientSet *kubernetes.Clientset
+	//集群名与clientset的映射，用于多集群调用
+	ClientMap map[string]*kubernetes.Clientset
+	//集群名与kubeconfig文件路径的映射
+	KubeConfMap map[string]string
+	//集群名与dynamic client的映射，用于操作CRD等未内置的资源
+	DynamicMap map[string]dynamic.Interface
+	//集群名与rest配置的映射，exec等需要直接使用rest配置的功能使用
+	RestConfMap map[string]*rest.Config
+	//集群名与informer缓存的映射，列表接口从缓存读取
+	CacheMap map[string]*informerCache
+	//集群名与RESTMapper的映射，用于将清单中的kind转换为资源
+	MapperMap map[string]*restmapper.DeferredDiscoveryRESTMapper
+	//模拟用户访问集群的客户端缓存，key为集群、用户名和组
+	impersonated sync.Map
 }
 
-func (k *k8s) Init() {
-	conf, err := clientcmd.BuildConfigFromFlags("", config.KubeConfig)
-	if err != nil {
-		logger.Error("创建k8s配置失败", err)
+// 根据集群名获取clientset，开启模拟用户时返回以ctx中的用户身份访问的clientset
+func (k *k8s) GetClient(ctx context.Context, cluster string) (*kubernetes.Clientset, error) {
+	client, ok := k.ClientMap[cluster]
+	if !ok {
+		logger.Error("集群" + cluster + "不存在，无法获取client")
+		return nil, errors.New("集群" + cluster + "不存在，无法获取client")
+	}
+	if config.Impersonate {
+		impersonated, err := k.impersonate(ctx, cluster)
+		if err != nil {
+			logger.Error("集群"+cluster+"获取模拟用户的client失败", err)
+			return nil, err
+		}
+		return impersonated.clientset, nil
+	}
+	return client, nil
+}
+
+// 根据集群名获取dynamic client，开启模拟用户时以ctx中的用户身份访问
+func (k *k8s) GetDynamicClient(ctx context.Context, cluster string) (dynamic.Interface, error) {
+	client, ok := k.DynamicMap[cluster]
+	if !ok {
+		logger.Error("集群" + cluster + "不存在，无法获取dynamic client")
+		return nil, errors.New("集群" + cluster + "不存在，无法获取dynamic client")
 	}
-	clientset, err := kubernetes.NewForConfig(conf)
-	if err != nil {
-		logger.Error("创建k8s clientset失败", err)
-	} else {
-		logger.Info("创建k8s clientset成功")
+	if config.Impersonate {
+		impersonated, err := k.impersonate(ctx, cluster)
+		if err != nil {
+			logger.Error("集群"+cluster+"获取模拟用户的dynamic client失败", err)
+			return nil, err
+		}
+		return impersonated.dynamic, nil
 	}
-	//将初始化完成的clientset赋值给k8s结构体属性，用于全局调用
-	k.ClientSet = clientset
+	return client, nil
+}
+
+// 根据集群名获取rest配置，开启模拟用户时返回带有用户身份的配置
+func (k *k8s) GetRestConfig(ctx context.Context, cluster string) (*rest.Config, error) {
+	conf, ok := k.RestConfMap[cluster]
+	if !ok {
+		logger.Error("集群" + cluster + "不存在，无法获取rest配置")
+		return nil, errors.New("集群" + cluster + "不存在，无法获取rest配置")
+	}
+	if config.Impersonate {
+		impersonated, err := k.impersonate(ctx, cluster)
+		if err != nil {
+			logger.Error("集群"+cluster+"获取模拟用户的rest配置失败", err)
+			return nil, err
+		}
+		return impersonated.conf, nil
+	}
+	return conf, nil
+}
 
+// 根据集群名获取RESTMapper
+func (k *k8s) GetRESTMapper(cluster string) (*restmapper.DeferredDiscoveryRESTMapper, error) {
+	mapper, ok := k.MapperMap[cluster]
+	if !ok {
+		logger.Error("集群" + cluster + "不存在，无法获取RESTMapper")
+		return nil, errors.New("集群" + cluster + "不存在，无法获取RESTMapper")
+	}
+	return mapper, nil
+}
+
+// 获取所有已注册的集群名
+func (k *k8s) GetClusters() []string {
+	clusters := make([]string, 0, len(k.ClientMap))
+	for name := range k.ClientMap {
+		clusters = append(clusters, name)
+	}
+	sort.Strings(clusters)
+	return clusters
+}
+
+func (k *k8s) Init() {
+	//反序列化多集群的kubeconfig配置
+	kubeConfMap := map[string]string{}
+	if err := json.Unmarshal([]byte(config.KubeConfigs), &kubeConfMap); err != nil {
+		logger.Error("反序列化kubeconfig配置失败", err)
+	}
+	k.KubeConfMap = kubeConfMap
+	k.ClientMap = map[string]*kubernetes.Clientset{}
+	k.RestConfMap = map[string]*rest.Config{}
+	k.DynamicMap = map[string]dynamic.Interface{}
+	k.CacheMap = map[string]*informerCache{}
+	k.MapperMap = map[string]*restmapper.DeferredDiscoveryRESTMapper{}
+	//逐个集群初始化clientset，单个集群失败不影响其他集群
+	for cluster, kubeConfig := range kubeConfMap {
+		conf, err := clientcmd.BuildConfigFromFlags("", kubeConfig)
+		if err != nil {
+			logger.Error("集群"+cluster+"创建k8s配置失败", err)
+			continue
+		}
+		clientset, err := kubernetes.NewForConfig(conf)
+		if err != nil {
+			logger.Error("集群"+cluster+"创建k8s clientset失败", err)
+			continue
+		}
+		dynamicClient, err := dynamic.NewForConfig(conf)
+		if err != nil {
+			logger.Error("集群"+cluster+"创建dynamic client失败", err)
+			continue
+		}
+		logger.Info("集群" + cluster + "创建k8s clientset成功")
+		//将初始化完成的clientset放入map，用于全局调用
+		k.ClientMap[cluster] = clientset
+		k.RestConfMap[cluster] = conf
+		k.DynamicMap[cluster] = dynamicClient
+		//discovery结果缓存在内存中，遇到未知的kind时再刷新
+		k.MapperMap[cluster] = restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(clientset.Discovery()))
+		//启动该集群的informer缓存
+		k.CacheMap[cluster] = newInformerCache(cluster, clientset)
+	}
 }
@@ -0,0 +1,23 @@
+package service
+
+import (
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// job，列表、详情、删除、更新、创建由通用资源Resource提供
+var Job = newResource[batchv1.Job]("job", batchv1.SchemeGroupVersion.WithResource("jobs"), true,
+	func(clientset kubernetes.Interface, namespace string) typedClient[*batchv1.Job] {
+		return clientset.BatchV1().Jobs(namespace)
+	}).withStatus(jobStatus)
+
+// job状态，Complete或Failed condition为True时取condition类型，否则为Running
+func jobStatus(obj *batchv1.Job) string {
+	for _, condition := range obj.Status.Conditions {
+		if (condition.Type == batchv1.JobComplete || condition.Type == batchv1.JobFailed) && condition.Status == corev1.ConditionTrue {
+			return string(condition.Type)
+		}
+	}
+	return "Running"
+}
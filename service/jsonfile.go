@@ -0,0 +1,61 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/wonderivan/logger"
+	"os"
+	"sync"
+	"time"
+)
+
+// json配置文件，按修改时间缓存，文件修改后下次读取时重新加载
+type jsonFile[T any] struct {
+	path    string
+	lock    sync.Mutex
+	modTime time.Time
+	loaded  bool
+	data    T
+}
+
+// 读取文件内容，文件未修改时返回缓存
+func (f *jsonFile[T]) get() (T, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	info, err := os.Stat(f.path)
+	if err != nil {
+		logger.Error("读取文件"+f.path+"失败", err)
+		return f.data, errors.New("读取文件" + f.path + "失败" + err.Error())
+	}
+	if f.loaded && info.ModTime().Equal(f.modTime) {
+		return f.data, nil
+	}
+	content, err := os.ReadFile(f.path)
+	if err != nil {
+		logger.Error("读取文件"+f.path+"失败", err)
+		return f.data, errors.New("读取文件" + f.path + "失败" + err.Error())
+	}
+	var data T
+	if err = json.Unmarshal(content, &data); err != nil {
+		logger.Error("文件"+f.path+"反序列化失败", err)
+		return f.data, errors.New("文件" + f.path + "反序列化失败" + err.Error())
+	}
+	f.data, f.modTime, f.loaded = data, info.ModTime(), true
+	return f.data, nil
+}
+
+// 文件不存在时写入初始内容，返回是否写入
+func (f *jsonFile[T]) init(data T) (bool, error) {
+	if _, err := os.Stat(f.path); !os.IsNotExist(err) {
+		return false, nil
+	}
+	content, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return false, err
+	}
+	if err = os.WriteFile(f.path, content, 0600); err != nil {
+		logger.Error("创建文件"+f.path+"失败", err)
+		return false, errors.New("创建文件" + f.path + "失败" + err.Error())
+	}
+	return true, nil
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// jwt的签发者
+const jwtIssuer = "k8s-platform"
+
+// HS256算法的header，签发和校验都只使用该算法
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// token中携带的用户信息
+type Claims struct {
+	//用户名
+	Subject string `json:"sub"`
+	//签发token的身份后端
+	Provider  string   `json:"provider"`
+	Groups    []string `json:"groups,omitempty"`
+	Issuer    string   `json:"iss"`
+	IssuedAt  int64    `json:"iat"`
+	ExpiresAt int64    `json:"exp"`
+	//刷新后的token保留首次登录的时间，用于限制刷新期限
+	AuthTime int64 `json:"auth_time"`
+}
+
+var (
+	errTokenInvalid = errors.New("token不合法")
+	errTokenExpired = errors.New("token已过期")
+)
+
+// 使用HS256签发jwt
+func signToken(claims *Claims, secret []byte) (string, error) {
+	if len(secret) == 0 {
+		return "", errors.New("jwt密钥为空")
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	unsigned := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + jwtSignature(unsigned, secret), nil
+}
+
+// 校验jwt的签名和签发者并解析claims，不校验是否过期，密钥为空时拒绝所有token
+func parseToken(token string, secret []byte) (*Claims, error) {
+	if len(secret) == 0 {
+		return nil, errTokenInvalid
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != jwtHeader {
+		return nil, errTokenInvalid
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(jwtSignature(parts[0]+"."+parts[1], secret))) {
+		return nil, errTokenInvalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errTokenInvalid
+	}
+	claims := &Claims{}
+	if err = json.Unmarshal(payload, claims); err != nil || claims.Issuer != jwtIssuer || claims.Subject == "" {
+		return nil, errTokenInvalid
+	}
+	return claims, nil
+}
+
+func jwtSignature(unsigned string, secret []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// token是否已过期
+func (c *Claims) expired(now time.Time) bool {
+	return now.Unix() >= c.ExpiresAt
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 测试用的身份后端，用户固定
+type fakeIdentityProvider struct{}
+
+func (p fakeIdentityProvider) Name() string {
+	return "fake"
+}
+
+func (p fakeIdentityProvider) Authenticate(username, password string) (*User, error) {
+	if username != "alice" || password != "secret" {
+		return nil, errLoginFailed
+	}
+	return p.GetUser(username)
+}
+
+func (p fakeIdentityProvider) GetUser(username string) (*User, error) {
+	switch username {
+	case "alice":
+		return &User{Username: username, Groups: []string{"developer"}}, nil
+	case "bob":
+		return &User{Username: username, Disabled: true}, nil
+	}
+	return nil, errors.New("用户" + username + "不存在")
+}
+
+func testClaims(expiresAt time.Time) *Claims {
+	return &Claims{
+		Subject:   "alice",
+		Provider:  "fake",
+		Groups:    []string{"developer"},
+		Issuer:    jwtIssuer,
+		IssuedAt:  time.Now().Unix(),
+		ExpiresAt: expiresAt.Unix(),
+		AuthTime:  time.Now().Unix(),
+	}
+}
+
+func TestSignToken(t *testing.T) {
+	if _, err := signToken(testClaims(time.Now().Add(time.Hour)), nil); err == nil {
+		t.Error("signToken() with empty secret err = nil")
+	}
+	secret := []byte("test-secret")
+	token, err := signToken(testClaims(time.Now().Add(time.Hour)), secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := parseToken(token, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "alice" || claims.Provider != "fake" || len(claims.Groups) != 1 {
+		t.Errorf("parseToken() = %+v", claims)
+	}
+}
+
+func TestParseToken(t *testing.T) {
+	secret := []byte("test-secret")
+	sign := func(claims *Claims) string {
+		token, err := signToken(claims, secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	valid := sign(testClaims(time.Now().Add(time.Hour)))
+	parts := strings.Split(valid, ".")
+	//修改payload后使用原签名
+	tampered := testClaims(time.Now().Add(time.Hour))
+	tampered.Groups = []string{"admin"}
+	payload, _ := json.Marshal(tampered)
+	wrongIssuer := testClaims(time.Now().Add(time.Hour))
+	wrongIssuer.Issuer = "other"
+	noSubject := testClaims(time.Now().Add(time.Hour))
+	noSubject.Subject = ""
+	otherSecret, _ := signToken(testClaims(time.Now().Add(time.Hour)), []byte("other-secret"))
+	noneHeader := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+
+	tests := []struct {
+		name    string
+		token   string
+		secret  []byte
+		wantErr error
+	}{
+		{name: "合法", token: valid, secret: secret},
+		{name: "已过期的token也可以解析", token: sign(testClaims(time.Now().Add(-time.Hour))), secret: secret},
+		{name: "密钥为空", token: valid, secret: nil, wantErr: errTokenInvalid},
+		{name: "其他密钥签发", token: otherSecret, secret: secret, wantErr: errTokenInvalid},
+		{name: "payload被修改", token: parts[0] + "." + base64.RawURLEncoding.EncodeToString(payload) + "." + parts[2], secret: secret, wantErr: errTokenInvalid},
+		{name: "签名被修改", token: parts[0] + "." + parts[1] + "." + strings.Repeat("A", len(parts[2])), secret: secret, wantErr: errTokenInvalid},
+		{name: "不支持的算法", token: noneHeader + "." + parts[1] + ".", secret: secret, wantErr: errTokenInvalid},
+		{name: "签发者不匹配", token: sign(wrongIssuer), secret: secret, wantErr: errTokenInvalid},
+		{name: "没有用户名", token: sign(noSubject), secret: secret, wantErr: errTokenInvalid},
+		{name: "格式不合法", token: "abc", secret: secret, wantErr: errTokenInvalid},
+		{name: "空token", token: "", secret: secret, wantErr: errTokenInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseToken(tt.token, tt.secret)
+			if err != tt.wantErr {
+				t.Errorf("parseToken() err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthVerify(t *testing.T) {
+	secret := []byte("test-secret")
+	valid, _ := signToken(testClaims(time.Now().Add(time.Hour)), secret)
+	expired, _ := signToken(testClaims(time.Now().Add(-time.Second)), secret)
+	disabledClaims := testClaims(time.Now().Add(time.Hour))
+	disabledClaims.Subject = "bob"
+	disabled, _ := signToken(disabledClaims, secret)
+	otherProviderClaims := testClaims(time.Now().Add(time.Hour))
+	otherProviderClaims.Provider = "ldap"
+	otherProvider, _ := signToken(otherProviderClaims, secret)
+	tests := []struct {
+		name    string
+		auth    auth
+		token   string
+		wantErr error
+	}{
+		{name: "合法", auth: auth{provider: fakeIdentityProvider{}, secret: secret}, token: valid},
+		{name: "已过期", auth: auth{provider: fakeIdentityProvider{}, secret: secret}, token: expired, wantErr: errTokenExpired},
+		{name: "用户已禁用", auth: auth{provider: fakeIdentityProvider{}, secret: secret}, token: disabled, wantErr: errUserDisabled},
+		{name: "其他身份后端签发", auth: auth{provider: fakeIdentityProvider{}, secret: secret}, token: otherProvider, wantErr: errTokenInvalid},
+		{name: "未初始化身份后端", auth: auth{secret: secret}, token: valid, wantErr: errAuthNotReady},
+		{name: "未初始化密钥", auth: auth{provider: fakeIdentityProvider{}}, token: valid, wantErr: errAuthNotReady},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.auth.Verify(tt.token)
+			if err != tt.wantErr {
+				t.Errorf("Verify() err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthLoginAndRefresh(t *testing.T) {
+	a := auth{provider: fakeIdentityProvider{}, secret: []byte("test-secret")}
+	if _, err := a.Login("alice", "wrong"); err != errLoginFailed {
+		t.Errorf("Login() with wrong password err = %v, want %v", err, errLoginFailed)
+	}
+	resp, err := a.Login("alice", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := a.Verify(resp.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "alice" || claims.Provider != "fake" {
+		t.Errorf("Verify() = %+v", claims)
+	}
+	refreshed, err := a.Refresh(resp.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refreshedClaims, err := a.Verify(refreshed.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//刷新后保留首次登录的时间
+	if refreshedClaims.AuthTime != claims.AuthTime {
+		t.Errorf("Refresh() auth_time = %d, want %d", refreshedClaims.AuthTime, claims.AuthTime)
+	}
+	//超过刷新期限
+	old := testClaims(time.Now().Add(-time.Hour))
+	old.AuthTime = time.Now().Add(-48 * time.Hour).Unix()
+	token, _ := signToken(old, a.secret)
+	if _, err = a.Refresh(token); err != errRefreshExpired {
+		t.Errorf("Refresh() err = %v, want %v", err, errRefreshExpired)
+	}
+	//未初始化时拒绝登录
+	if _, err = (&auth{}).Login("alice", "secret"); err != errAuthNotReady {
+		t.Errorf("Login() err = %v, want %v", err, errAuthNotReady)
+	}
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/wonderivan/logger"
+	"golang.org/x/crypto/bcrypt"
+	"k8s-platform/config"
+	"os"
+)
+
+func init() {
+	registerIdentityProvider("local", func() (IdentityProvider, error) {
+		return newLocalProvider(config.UserFile)
+	})
+}
+
+// 本地文件中的用户，password为bcrypt哈希
+type localUser struct {
+	User
+	Password string `json:"password"`
+}
+
+// 本地身份后端，用户保存在json文件中，文件修改后自动重新加载
+type localProvider struct {
+	file *jsonFile[[]*localUser]
+}
+
+// 用户不存在时用于比较的哈希，使校验耗时与用户存在时一致
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("k8s-platform"), bcrypt.DefaultCost)
+
+func newLocalProvider(path string) (*localProvider, error) {
+	p := &localProvider{file: &jsonFile[[]*localUser]{path: path}}
+	if err := p.bootstrap(); err != nil {
+		return nil, err
+	}
+	if _, err := p.file.get(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *localProvider) Name() string {
+	return "local"
+}
+
+func (p *localProvider) Authenticate(username, password string) (*User, error) {
+	user, err := p.lookup(username)
+	if err != nil {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return nil, err
+	}
+	if err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return nil, errors.New("密码错误")
+	}
+	result := user.User
+	return &result, nil
+}
+
+func (p *localProvider) GetUser(username string) (*User, error) {
+	user, err := p.lookup(username)
+	if err != nil {
+		return nil, err
+	}
+	result := user.User
+	return &result, nil
+}
+
+func (p *localProvider) lookup(username string) (*localUser, error) {
+	users, err := p.file.get()
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+	return nil, errors.New("用户" + username + "不存在")
+}
+
+// 用户文件不存在时创建admin用户，随机生成初始密码
+// 初始密码只写入权限为0600的密码文件，不输出到日志，写入失败时输出到标准错误
+func (p *localProvider) bootstrap() error {
+	random := make([]byte, 12)
+	if _, err := rand.Read(random); err != nil {
+		return err
+	}
+	password := base64.RawURLEncoding.EncodeToString(random)
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	created, err := p.file.init([]*localUser{{
+		User: User{
+			Username:    "admin",
+			DisplayName: "管理员",
+			Groups:      []string{"admin"},
+		},
+		Password: string(hash),
+	}})
+	if err != nil || !created {
+		return err
+	}
+	passwordFile := p.file.path + ".admin-password"
+	if writeErr := writeSecretFile(passwordFile, []byte(password+"\n")); writeErr != nil {
+		logger.Error("写入初始密码文件失败，初始密码输出到标准错误", writeErr)
+		fmt.Fprintln(os.Stderr, "admin用户的初始密码: "+password)
+	} else {
+		logger.Warn("用户文件" + p.file.path + "不存在，已创建admin用户，初始密码保存在" + passwordFile + "，登录后请修改密码并删除该文件")
+	}
+	return nil
+}
+
+// 写入只有当前用户可读写的文件，文件已存在时先删除，避免沿用原有的权限
+func writeSecretFile(path string, content []byte) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(content); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
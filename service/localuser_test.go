@@ -0,0 +1,41 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalProviderBootstrap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	p, err := newLocalProvider(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	passwordFile := path + ".admin-password"
+	info, err := os.Stat(passwordFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("初始密码文件权限 = %o, want 600", mode)
+	}
+	content, err := os.ReadFile(passwordFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = p.Authenticate("admin", strings.TrimSpace(string(content))); err != nil {
+		t.Errorf("Authenticate() 使用初始密码失败: %v", err)
+	}
+	//用户文件已存在时不重新生成密码
+	if err = os.Remove(passwordFile); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = newLocalProvider(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(passwordFile); !os.IsNotExist(err) {
+		t.Errorf("用户文件已存在时不应重新写入初始密码文件, err = %v", err)
+	}
+}
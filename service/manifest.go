@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/wonderivan/logger"
+	"io"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	yamlutil "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/restmapper"
+	"strconv"
+	"strings"
+)
+
+var Manifest manifest
+
+type manifest struct{}
+
+// 清单中单个对象的创建结果
+type ManifestResult struct {
+	ApiVersion string `json:"api_version"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace"`
+	Success    bool   `json:"success"`
+	Msg        string `json:"msg"`
+}
+
+// 按顺序创建清单中的所有对象，content为yaml(可包含多个---分隔的文档)或json
+// 清单先全部解析，任一对象解析失败或没有权限时不创建任何对象；创建时单个对象失败不影响后续对象，结果逐个返回
+// 对象未指定namespace时使用传入的namespace，传入的namespace也为空时使用default
+// authorize校验对象的创建权限，参数为资源名和对象所在的命名空间，集群级资源的命名空间为空
+func (m *manifest) CreateManifest(ctx context.Context, cluster, namespace, content string, authorize func(kind, namespace string) error) (results []*ManifestResult, err error) {
+	client, err := K8s.GetDynamicClient(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	mapper, err := K8s.GetRESTMapper(cluster)
+	if err != nil {
+		return nil, err
+	}
+	objects, err := m.decode(content)
+	if err != nil {
+		return nil, err
+	}
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+	//先确定每个对象的资源和命名空间并校验权限，无法识别的类型在创建时记录失败
+	mappings := make([]*meta.RESTMapping, len(objects))
+	mappingErrs := make([]error, len(objects))
+	for i, obj := range objects {
+		mapping, err := m.restMapping(mapper, obj.GroupVersionKind())
+		if err != nil {
+			mappingErrs[i] = err
+			continue
+		}
+		//集群级资源忽略namespace
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			if obj.GetNamespace() == "" {
+				obj.SetNamespace(namespace)
+			}
+		} else {
+			obj.SetNamespace("")
+		}
+		if err = authorize(ResourceKind(mapping.Resource), obj.GetNamespace()); err != nil {
+			return nil, errors.New(obj.GetKind() + " " + obj.GetName() + ": " + err.Error())
+		}
+		mappings[i] = mapping
+	}
+	for i, obj := range objects {
+		result := &ManifestResult{
+			ApiVersion: obj.GetAPIVersion(),
+			Kind:       obj.GetKind(),
+			Name:       obj.GetName(),
+			Namespace:  obj.GetNamespace(),
+		}
+		results = append(results, result)
+		if mappingErrs[i] != nil {
+			result.Msg = mappingErrs[i].Error()
+			continue
+		}
+		created, err := client.Resource(mappings[i].Resource).Namespace(obj.GetNamespace()).Create(ctx, obj, metav1.CreateOptions{FieldManager: fieldManager})
+		if err != nil {
+			logger.Error("创建"+obj.GetKind()+"失败", err)
+			result.Msg = "创建" + obj.GetKind() + "失败" + err.Error()
+			continue
+		}
+		//使用generateName时名称由apiserver生成
+		result.Name = created.GetName()
+		result.Success = true
+		result.Msg = "创建" + obj.GetKind() + "成功"
+	}
+	return results, nil
+}
+
+// 解析清单，内置资源使用scheme的UniversalDeserializer解析并校验，CRD等未注册的类型按unstructured解析
+// kind为List的对象展开为其中的items
+func (m *manifest) decode(content string) ([]*unstructured.Unstructured, error) {
+	var objects []*unstructured.Unstructured
+	decoder := yamlutil.NewYAMLOrJSONDecoder(strings.NewReader(content), 4096)
+	for index := 1; ; index++ {
+		raw := runtime.RawExtension{}
+		if err := decoder.Decode(&raw); err != nil {
+			if err == io.EOF {
+				break
+			}
+			logger.Error("清单解析失败", err)
+			return nil, errors.New("第" + strconv.Itoa(index) + "个文档解析失败" + err.Error())
+		}
+		//跳过空文档
+		if len(raw.Raw) == 0 || string(raw.Raw) == "null" {
+			continue
+		}
+		obj, gvk, err := scheme.Codecs.UniversalDeserializer().Decode(raw.Raw, nil, nil)
+		if runtime.IsNotRegisteredError(err) {
+			obj, gvk, err = unstructured.UnstructuredJSONScheme.Decode(raw.Raw, nil, nil)
+		}
+		if err != nil {
+			logger.Error("清单解析失败", err)
+			return nil, errors.New("第" + strconv.Itoa(index) + "个文档解析失败" + err.Error())
+		}
+		if list, ok := obj.(*unstructured.UnstructuredList); ok {
+			for i := range list.Items {
+				objects = append(objects, &list.Items[i])
+			}
+			continue
+		}
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return nil, errors.New("第" + strconv.Itoa(index) + "个文档转换失败" + err.Error())
+		}
+		item := &unstructured.Unstructured{Object: content}
+		//内置资源解析后TypeMeta可能为空，使用解析得到的gvk
+		item.SetGroupVersionKind(*gvk)
+		if item.IsList() {
+			if err = item.EachListItem(func(o runtime.Object) error {
+				objects = append(objects, o.(*unstructured.Unstructured))
+				return nil
+			}); err != nil {
+				return nil, errors.New("第" + strconv.Itoa(index) + "个文档转换失败" + err.Error())
+			}
+			continue
+		}
+		objects = append(objects, item)
+	}
+	if len(objects) == 0 {
+		return nil, errors.New("清单中没有任何资源")
+	}
+	return objects, nil
+}
+
+// 根据gvk获取资源，找不到时刷新discovery缓存后重试一次，用于新安装的CRD
+func (m *manifest) restMapping(mapper *restmapper.DeferredDiscoveryRESTMapper, gvk schema.GroupVersionKind) (*meta.RESTMapping, error) {
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		mapper.Reset()
+		mapping, err = mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	}
+	if err != nil {
+		return nil, errors.New("获取" + gvk.Kind + "的资源类型失败" + err.Error())
+	}
+	return mapping, nil
+}
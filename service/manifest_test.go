@@ -0,0 +1,88 @@
+package service
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestManifestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		//解析出的对象，格式为kind/name
+		want    []string
+		wantErr string
+	}{
+		{
+			name:    "单个yaml文档",
+			content: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cfg\n",
+			want:    []string{"ConfigMap/cfg"},
+		},
+		{
+			name: "多个文档并跳过空文档",
+			content: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cfg\n---\n---\n" +
+				"apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n",
+			want: []string{"ConfigMap/cfg", "Deployment/web"},
+		},
+		{
+			name:    "json",
+			content: `{"apiVersion":"v1","kind":"Service","metadata":{"name":"web"}}`,
+			want:    []string{"Service/web"},
+		},
+		{
+			name: "List展开为其中的对象",
+			content: "apiVersion: v1\nkind: List\nitems:\n" +
+				"- apiVersion: v1\n  kind: ConfigMap\n  metadata:\n    name: a\n" +
+				"- apiVersion: v1\n  kind: Secret\n  metadata:\n    name: b\n",
+			want: []string{"ConfigMap/a", "Secret/b"},
+		},
+		{
+			name: "CRD的List展开",
+			content: "apiVersion: example.com/v1\nkind: WidgetList\nitems:\n" +
+				"- apiVersion: example.com/v1\n  kind: Widget\n  metadata:\n    name: w1\n",
+			want: []string{"Widget/w1"},
+		},
+		{
+			name:    "未注册的类型按unstructured解析",
+			content: "apiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: w1\nspec:\n  size: 3\n",
+			want:    []string{"Widget/w1"},
+		},
+		{
+			name:    "内置资源字段类型错误",
+			content: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cfg\n---\napiVersion: v1\nkind: Pod\nmetadata:\n  name: [web]\n",
+			wantErr: "第2个文档解析失败",
+		},
+		{
+			name:    "yaml格式错误",
+			content: "apiVersion: v1\nkind: [",
+			wantErr: "第1个文档解析失败",
+		},
+		{
+			name:    "空清单",
+			content: "---\n",
+			wantErr: "清单中没有任何资源",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects, err := Manifest.decode(tt.content)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("decode() err = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, len(objects))
+			for i, obj := range objects {
+				got[i] = obj.GetKind() + "/" + obj.GetName()
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
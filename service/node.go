@@ -6,95 +6,71 @@ import (
 	"github.com/wonderivan/logger"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"strconv"
 )
 
-var Node node
+// node，列表、详情、删除、更新、创建由通用资源Resource提供
+var Node = node{newResource[corev1.Node]("node", corev1.SchemeGroupVersion.WithResource("nodes"), false,
+	func(clientset kubernetes.Interface, namespace string) typedClient[*corev1.Node] {
+		return clientset.CoreV1().Nodes()
+	}).withStatus(nodeStatus).withFields(nodeFields).
+	withProperty("cpu", nodeAllocatableCpu).
+	withProperty("memory", nodeAllocatableMemory)}
 
-type node struct{}
-
-// 定义nodes的返回内容 items是node列表吗total为node元素总数
-type NodesResp struct {
-	Item  []corev1.Node `json:"items"`
-	Total int           `json:"total"`
+type node struct {
+	*Resource[corev1.Node, *corev1.Node]
 }
 
-// 获取node列表
-func (p *node) GetNodes(filterName string, limit, page int) (nodesResp *NodesResp, err error) {
-	//通过clientset获取nodes完整列表
-	nodeList, err := K8s.ClientSet.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		logger.Error("获取node列表失败", err)
-		return nil, errors.New("获取node列表失败" + err.Error())
-	}
-	//实例化DataSelector对象
-	selectableData := &DataSelector{
-		GenericDataList: p.toCells(nodeList.Items),
-		DataSelectQuery: &DataSelect{
-			FilterQuery: &Filter{filterName},
-			PaginateQuery: &Paginate{
-				Limit: limit,
-				Page:  page,
-			},
-		},
-	}
-	//先过滤
-	filtered := selectableData.Filter()
-	//再拿total
-	total := len(filtered.GenericDataList)
-	//在排序和分页
-	data := filtered.Sort().Paginate()
-	//再将datacell切片数据转成原生node切片
-	nodes := p.fromCells(data.GenericDataList)
-	//返回
-	return &NodesResp{
-		Item:  nodes,
-		Total: total,
-	}, nil
+// 禁止调度，新的pod不会调度到该节点，已有的pod不受影响
+func (n *node) CordonNode(ctx context.Context, cluster, nodeName string) (err error) {
+	return n.setUnschedulable(ctx, cluster, nodeName, true)
 }
 
-// 获取node详情
-func (p *node) GetNodeDetail(nodeName string) (node *corev1.Node, err error) {
-	node, err = K8s.ClientSet.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
-	if err != nil {
-		logger.Error("获取Node详情失败" + err.Error())
-		return nil, errors.New("获取Node详情失败" + err.Error())
-	}
-	return node, nil
+// 恢复调度
+func (n *node) UncordonNode(ctx context.Context, cluster, nodeName string) (err error) {
+	return n.setUnschedulable(ctx, cluster, nodeName, false)
 }
 
-// 更新node
-func (p *node) UpdateNode(content string) (err error) {
-	//将content反序列化成为node对象
-	var node = &corev1.Node{}
-	if err = json.Unmarshal([]byte(content), node); err != nil {
-		logger.Error("Content反序列化失败", err)
-		return errors.New("Content反序列化失败" + err.Error())
+func (n *node) setUnschedulable(ctx context.Context, cluster, nodeName string, unschedulable bool) (err error) {
+	client, err := K8s.GetClient(ctx, cluster)
+	if err != nil {
+		return err
 	}
-	//更新node
-	_, err = K8s.ClientSet.CoreV1().Nodes().Update(context.TODO(), node, metav1.UpdateOptions{})
+	patchByte := []byte(`{"spec":{"unschedulable":` + strconv.FormatBool(unschedulable) + `}}`)
+	_, err = client.CoreV1().Nodes().Patch(ctx, nodeName, types.MergePatchType, patchByte, metav1.PatchOptions{FieldManager: fieldManager})
 	if err != nil {
-		logger.Error("更新Node失败" + err.Error())
-		return errors.New("更新Node失败" + err.Error())
+		logger.Error("修改node调度状态失败", err)
+		return errors.New("修改node调度状态失败" + err.Error())
 	}
 	return nil
 }
 
-// 把nodeCell转成corev1 node
-func (p *node) fromCells(cells []DataCell) []corev1.Node {
-	nodes := make([]corev1.Node, len(cells))
-	for i := range cells {
-		nodes[i] = corev1.Node(cells[i].(nodeCell))
+// node状态，Ready condition为True时为Ready，否则为NotReady
+func nodeStatus(obj *corev1.Node) string {
+	for _, condition := range obj.Status.Conditions {
+		if condition.Type == corev1.NodeReady && condition.Status == corev1.ConditionTrue {
+			return "Ready"
+		}
 	}
-	return nodes
+	return "NotReady"
 }
 
-// 把corev1 nodes转成datacell
-func (p *node) toCells(std []corev1.Node) []DataCell {
-	cells := make([]DataCell, len(std))
-	for i := range std {
-		cells[i] = nodeCell(std[i])
-
+// node支持的字段选择器字段
+func nodeFields(obj *corev1.Node) fields.Set {
+	return fields.Set{
+		"spec.unschedulable": strconv.FormatBool(obj.Spec.Unschedulable),
 	}
-	return cells
+}
+
+// node可分配的cpu，单位为毫核
+func nodeAllocatableCpu(obj *corev1.Node) ComparableValue {
+	return StdComparableInt(obj.Status.Allocatable.Cpu().MilliValue())
+}
+
+// node可分配的内存，单位为字节
+func nodeAllocatableMemory(obj *corev1.Node) ComparableValue {
+	return StdComparableInt(obj.Status.Allocatable.Memory().Value())
 }
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/wonderivan/logger"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sort"
+	"time"
+)
+
+// node详情，在node对象基础上增加节点上的pod、资源分配、状态和事件
+type NodeDetail struct {
+	Node       *corev1.Node          `json:"node"`
+	Pods       []*NodePod            `json:"pods"`
+	Allocation []*ResourceAllocation `json:"allocation"`
+	Conditions []*NodeCondition      `json:"conditions"`
+	//按时间倒序
+	Events []corev1.Event `json:"events"`
+}
+
+// 节点上的pod
+type NodePod struct {
+	Name         string              `json:"name"`
+	Namespace    string              `json:"namespace"`
+	Status       string              `json:"status"`
+	Requests     corev1.ResourceList `json:"requests"`
+	Limits       corev1.ResourceList `json:"limits"`
+	CreationTime time.Time           `json:"creation_time"`
+}
+
+// 单项资源的分配情况，包括cpu、内存和gpu等扩展资源，pods为节点上未结束的pod数
+type ResourceAllocation struct {
+	Resource    string            `json:"resource"`
+	Requests    resource.Quantity `json:"requests"`
+	Limits      resource.Quantity `json:"limits"`
+	Allocatable resource.Quantity `json:"allocatable"`
+	//占可分配量的百分比，可分配量为0时为0
+	RequestsPercent float64 `json:"requests_percent"`
+	LimitsPercent   float64 `json:"limits_percent"`
+}
+
+// 节点状态
+type NodeCondition struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+	//Ready为True，其他压力类状态为False时正常
+	Healthy            bool      `json:"healthy"`
+	LastTransitionTime time.Time `json:"last_transition_time"`
+	LastHeartbeatTime  time.Time `json:"last_heartbeat_time"`
+}
+
+// 获取node详情，与kubectl describe node一致，资源分配只统计未结束的pod
+func (n *node) GetNodeDetail(ctx context.Context, cluster, nodeName string) (detail *NodeDetail, err error) {
+	node, err := n.Get(ctx, cluster, nodeName, "")
+	if err != nil {
+		return nil, err
+	}
+	client, err := K8s.GetClient(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	podList, err := client.CoreV1().Pods("").List(ctx, metav1.ListOptions{FieldSelector: "spec.nodeName=" + nodeName})
+	if err != nil {
+		logger.Error("获取node上的pod失败", err)
+		return nil, errors.New("获取node上的pod失败" + err.Error())
+	}
+	//事件只用于辅助排查，获取失败时只记录日志，不影响详情
+	events, err := Event.GetObjectEvents(ctx, cluster, node)
+	if err != nil {
+		logger.Warn("获取node事件失败", err)
+	}
+	detail = &NodeDetail{
+		Node:   node,
+		Events: events,
+	}
+	requests, limits := corev1.ResourceList{}, corev1.ResourceList{}
+	running := int64(0)
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		podRequests, podLimits := podRequestsAndLimits(pod)
+		detail.Pods = append(detail.Pods, &NodePod{
+			Name:         pod.Name,
+			Namespace:    pod.Namespace,
+			Status:       podStatus(pod),
+			Requests:     podRequests,
+			Limits:       podLimits,
+			CreationTime: pod.CreationTimestamp.Time,
+		})
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		running++
+		addResourceList(requests, podRequests)
+		addResourceList(limits, podLimits)
+	}
+	requests[corev1.ResourcePods] = *resource.NewQuantity(running, resource.DecimalSI)
+	detail.Allocation = resourceAllocation(requests, limits, node.Status.Allocatable)
+	for _, condition := range node.Status.Conditions {
+		healthy := condition.Status == corev1.ConditionFalse
+		if condition.Type == corev1.NodeReady {
+			healthy = condition.Status == corev1.ConditionTrue
+		}
+		detail.Conditions = append(detail.Conditions, &NodeCondition{
+			Type:               string(condition.Type),
+			Status:             string(condition.Status),
+			Reason:             condition.Reason,
+			Message:            condition.Message,
+			Healthy:            healthy,
+			LastTransitionTime: condition.LastTransitionTime.Time,
+			LastHeartbeatTime:  condition.LastHeartbeatTime.Time,
+		})
+	}
+	return detail, nil
+}
+
+// 按资源名汇总requests、limits和可分配量，包含所有出现过的资源，按资源名排序
+func resourceAllocation(requests, limits, allocatable corev1.ResourceList) []*ResourceAllocation {
+	names := map[corev1.ResourceName]bool{}
+	for _, list := range []corev1.ResourceList{requests, limits, allocatable} {
+		for name := range list {
+			names[name] = true
+		}
+	}
+	allocations := make([]*ResourceAllocation, 0, len(names))
+	for name := range names {
+		allocation := &ResourceAllocation{
+			Resource:    string(name),
+			Requests:    requests[name],
+			Limits:      limits[name],
+			Allocatable: allocatable[name],
+		}
+		allocation.RequestsPercent = quantityPercent(allocation.Requests, allocation.Allocatable)
+		allocation.LimitsPercent = quantityPercent(allocation.Limits, allocation.Allocatable)
+		allocations = append(allocations, allocation)
+	}
+	sort.Slice(allocations, func(i, j int) bool {
+		return allocations[i].Resource < allocations[j].Resource
+	})
+	return allocations
+}
+
+// used占total的百分比，保留两位小数
+func quantityPercent(used, total resource.Quantity) float64 {
+	if total.IsZero() {
+		return 0
+	}
+	percent := float64(used.MilliValue()) / float64(total.MilliValue()) * 100
+	return float64(int64(percent*100+0.5)) / 100
+}
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/wonderivan/logger"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/util/retry"
+)
+
+// 定义结构体用于批量修改node的标签
+type NodeLabels struct {
+	NodeNames []string `json:"node_names"`
+	//新增或修改的标签
+	Labels map[string]string `json:"labels"`
+	//删除的标签key，不存在时忽略
+	Remove  []string `json:"remove"`
+	Cluster string   `json:"cluster"`
+}
+
+// 定义结构体用于批量修改node的污点
+type NodeTaints struct {
+	NodeNames []string `json:"node_names"`
+	//新增或修改的污点，key和effect都相同时修改value
+	Taints []TaintSpec `json:"taints"`
+	//删除的污点，effect为空时删除该key的所有污点
+	Remove  []TaintSpec `json:"remove"`
+	Cluster string      `json:"cluster"`
+}
+
+type TaintSpec struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	//NoSchedule、PreferNoSchedule、NoExecute
+	Effect string `json:"effect"`
+}
+
+// 单个node的修改结果
+type NodeResult struct {
+	Name    string `json:"name"`
+	Success bool   `json:"success"`
+	Msg     string `json:"msg"`
+}
+
+// 批量修改node的标签，只patch metadata.labels，不影响node的其他字段
+// 参数全部校验通过后逐个node修改，单个node失败不影响其他node
+func (n *node) UpdateNodeLabels(ctx context.Context, cluster string, data NodeLabels) (results []*NodeResult, err error) {
+	errs := validateNodeNames(data.NodeNames)
+	errs = append(errs, validateLabels(data.Labels, field.NewPath("labels"))...)
+	for i, key := range data.Remove {
+		for _, msg := range validation.IsQualifiedName(key) {
+			errs = append(errs, field.Invalid(field.NewPath("remove").Index(i), key, msg))
+		}
+		if _, ok := data.Labels[key]; ok {
+			errs = append(errs, field.Duplicate(field.NewPath("remove").Index(i), key))
+		}
+	}
+	if len(data.Labels) == 0 && len(data.Remove) == 0 {
+		errs = append(errs, field.Required(field.NewPath("labels"), "labels和remove不能同时为空"))
+	}
+	if len(errs) > 0 {
+		return nil, errors.New("参数校验失败" + errs.ToAggregate().Error())
+	}
+	//merge patch中值为null的key会被删除
+	labels := map[string]interface{}{}
+	for key, value := range data.Labels {
+		labels[key] = value
+	}
+	for _, key := range data.Remove {
+		labels[key] = nil
+	}
+	patchByte, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"labels": labels},
+	})
+	if err != nil {
+		logger.Error("patchdata序列化失败", err)
+		return nil, errors.New("patchdata序列化失败" + err.Error())
+	}
+	client, err := K8s.GetClient(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	for _, nodeName := range data.NodeNames {
+		result := &NodeResult{Name: nodeName, Success: true, Msg: "修改标签成功"}
+		_, err = client.CoreV1().Nodes().Patch(ctx, nodeName, types.MergePatchType, patchByte, metav1.PatchOptions{FieldManager: fieldManager})
+		if err != nil {
+			logger.Error("修改node标签失败", err)
+			result.Success, result.Msg = false, "修改node标签失败"+err.Error()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// 批量修改node的污点，只patch spec.taints
+// taints是列表，patch时带上resourceVersion，其他组件同时修改污点时冲突重试，不会覆盖对方的修改
+func (n *node) UpdateNodeTaints(ctx context.Context, cluster string, data NodeTaints) (results []*NodeResult, err error) {
+	errs := validateNodeNames(data.NodeNames)
+	for i, taint := range data.Taints {
+		errs = append(errs, validateTaint(taint, true, field.NewPath("taints").Index(i))...)
+	}
+	for i, taint := range data.Remove {
+		errs = append(errs, validateTaint(taint, false, field.NewPath("remove").Index(i))...)
+	}
+	if len(data.Taints) == 0 && len(data.Remove) == 0 {
+		errs = append(errs, field.Required(field.NewPath("taints"), "taints和remove不能同时为空"))
+	}
+	if len(errs) > 0 {
+		return nil, errors.New("参数校验失败" + errs.ToAggregate().Error())
+	}
+	client, err := K8s.GetClient(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	for _, nodeName := range data.NodeNames {
+		result := &NodeResult{Name: nodeName, Success: true, Msg: "修改污点成功"}
+		err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			node, err := client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			patchByte, err := json.Marshal(map[string]interface{}{
+				"metadata": map[string]interface{}{"resourceVersion": node.ResourceVersion},
+				"spec":     map[string]interface{}{"taints": mergeTaints(node.Spec.Taints, data.Taints, data.Remove)},
+			})
+			if err != nil {
+				return err
+			}
+			_, err = client.CoreV1().Nodes().Patch(ctx, nodeName, types.MergePatchType, patchByte, metav1.PatchOptions{FieldManager: fieldManager})
+			return err
+		})
+		if err != nil {
+			logger.Error("修改node污点失败", err)
+			result.Success, result.Msg = false, "修改node污点失败"+err.Error()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// 在现有污点上删除remove中的污点，再新增或修改taints中的污点，保持原有顺序
+func mergeTaints(current []corev1.Taint, taints, remove []TaintSpec) []corev1.Taint {
+	merged := make([]corev1.Taint, 0, len(current)+len(taints))
+	for _, taint := range current {
+		removed := false
+		for _, r := range remove {
+			removed = removed || (taint.Key == r.Key && (r.Effect == "" || string(taint.Effect) == r.Effect))
+		}
+		if !removed {
+			merged = append(merged, taint)
+		}
+	}
+	now := metav1.Now()
+	for _, spec := range taints {
+		taint := corev1.Taint{Key: spec.Key, Value: spec.Value, Effect: corev1.TaintEffect(spec.Effect)}
+		//NoExecute污点记录添加时间，用于计算toleration的tolerationSeconds，与kubectl taint一致
+		if taint.Effect == corev1.TaintEffectNoExecute {
+			taint.TimeAdded = &now
+		}
+		updated := false
+		for i := range merged {
+			if merged[i].MatchTaint(&taint) {
+				if merged[i].Value != taint.Value {
+					merged[i] = taint
+				}
+				updated = true
+			}
+		}
+		if !updated {
+			merged = append(merged, taint)
+		}
+	}
+	return merged
+}
+
+func validateNodeNames(nodeNames []string) field.ErrorList {
+	if len(nodeNames) == 0 {
+		return field.ErrorList{field.Required(field.NewPath("node_names"), "")}
+	}
+	var errs field.ErrorList
+	for i, nodeName := range nodeNames {
+		errs = append(errs, validateObjectName(nodeName, field.NewPath("node_names").Index(i))...)
+	}
+	return errs
+}
+
+// 校验污点，key与标签key的规则相同，value与标签value的规则相同
+// 删除时effect可以为空
+func validateTaint(taint TaintSpec, requireEffect bool, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for _, msg := range validation.IsQualifiedName(taint.Key) {
+		errs = append(errs, field.Invalid(path.Child("key"), taint.Key, msg))
+	}
+	for _, msg := range validation.IsValidLabelValue(taint.Value) {
+		errs = append(errs, field.Invalid(path.Child("value"), taint.Value, msg))
+	}
+	switch corev1.TaintEffect(taint.Effect) {
+	case corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+	case "":
+		if requireEffect {
+			errs = append(errs, field.Required(path.Child("effect"), ""))
+		}
+	default:
+		errs = append(errs, field.NotSupported(path.Child("effect"), taint.Effect, []string{"NoSchedule", "PreferNoSchedule", "NoExecute"}))
+	}
+	return errs
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"reflect"
+	"testing"
+)
+
+func TestMergeTaints(t *testing.T) {
+	added := metav1.Unix(1000, 0)
+	current := []corev1.Taint{
+		{Key: "dedicated", Value: "db", Effect: corev1.TaintEffectNoSchedule},
+		{Key: "dedicated", Value: "db", Effect: corev1.TaintEffectPreferNoSchedule},
+		{Key: "maintenance", Value: "true", Effect: corev1.TaintEffectNoExecute, TimeAdded: &added},
+	}
+	//污点的key、value和effect，格式为key=value:effect
+	format := func(taints []corev1.Taint) []string {
+		result := make([]string, len(taints))
+		for i, taint := range taints {
+			result[i] = taint.Key + "=" + taint.Value + ":" + string(taint.Effect)
+		}
+		return result
+	}
+	tests := []struct {
+		name   string
+		taints []TaintSpec
+		remove []TaintSpec
+		want   []string
+	}{
+		{
+			name: "没有变更",
+			want: []string{"dedicated=db:NoSchedule", "dedicated=db:PreferNoSchedule", "maintenance=true:NoExecute"},
+		},
+		{
+			name:   "新增污点追加到末尾",
+			taints: []TaintSpec{{Key: "gpu", Value: "true", Effect: "NoSchedule"}},
+			want:   []string{"dedicated=db:NoSchedule", "dedicated=db:PreferNoSchedule", "maintenance=true:NoExecute", "gpu=true:NoSchedule"},
+		},
+		{
+			name:   "相同key和effect时原位修改value",
+			taints: []TaintSpec{{Key: "dedicated", Value: "web", Effect: "NoSchedule"}},
+			want:   []string{"dedicated=web:NoSchedule", "dedicated=db:PreferNoSchedule", "maintenance=true:NoExecute"},
+		},
+		{
+			name:   "按key和effect删除",
+			remove: []TaintSpec{{Key: "dedicated", Effect: "NoSchedule"}},
+			want:   []string{"dedicated=db:PreferNoSchedule", "maintenance=true:NoExecute"},
+		},
+		{
+			name:   "不指定effect时删除该key的所有污点",
+			remove: []TaintSpec{{Key: "dedicated"}},
+			want:   []string{"maintenance=true:NoExecute"},
+		},
+		{
+			name:   "先删除再新增",
+			taints: []TaintSpec{{Key: "dedicated", Value: "web", Effect: "NoSchedule"}},
+			remove: []TaintSpec{{Key: "dedicated"}},
+			want:   []string{"maintenance=true:NoExecute", "dedicated=web:NoSchedule"},
+		},
+		{
+			name:   "删除不存在的污点",
+			remove: []TaintSpec{{Key: "gpu"}},
+			want:   []string{"dedicated=db:NoSchedule", "dedicated=db:PreferNoSchedule", "maintenance=true:NoExecute"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeTaints(current, tt.taints, tt.remove)
+			if !reflect.DeepEqual(format(got), tt.want) {
+				t.Errorf("mergeTaints() = %v, want %v", format(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeTaintsTimeAdded(t *testing.T) {
+	added := metav1.Unix(1000, 0)
+	current := []corev1.Taint{{Key: "maintenance", Value: "true", Effect: corev1.TaintEffectNoExecute, TimeAdded: &added}}
+	//value不变时保留原来的添加时间
+	got := mergeTaints(current, []TaintSpec{{Key: "maintenance", Value: "true", Effect: "NoExecute"}}, nil)
+	if !got[0].TimeAdded.Equal(&added) {
+		t.Errorf("mergeTaints() TimeAdded = %v, want %v", got[0].TimeAdded, added)
+	}
+	//新增的NoExecute污点记录添加时间，其他effect不记录
+	got = mergeTaints(nil, []TaintSpec{{Key: "a", Effect: "NoExecute"}, {Key: "b", Effect: "NoSchedule"}}, nil)
+	if got[0].TimeAdded == nil || got[1].TimeAdded != nil {
+		t.Errorf("mergeTaints() TimeAdded = %v, %v", got[0].TimeAdded, got[1].TimeAdded)
+	}
+}
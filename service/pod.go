@@ -9,17 +9,22 @@ import (
 	"k8s-platform/config"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"time"
 )
 
-var Pod pod
+// pod，列表、详情、删除、更新、创建由通用资源Resource提供
+var Pod = pod{newResource[corev1.Pod]("pod", corev1.SchemeGroupVersion.WithResource("pods"), true,
+	func(clientset kubernetes.Interface, namespace string) typedClient[*corev1.Pod] {
+		return clientset.CoreV1().Pods(namespace)
+	}).withStatus(podStatus).withFields(podFields).
+	withProperty("restarts", podRestarts).
+	withProperty("node", podNode)}
 
-type pod struct{}
-
-// 定义pods的返回内容 items是pod列表吗total为pod元素总数
-type PodsResp struct {
-	Item  []corev1.Pod `json:"items"`
-	Total int          `json:"total"`
+type pod struct {
+	*Resource[corev1.Pod, *corev1.Pod]
 }
 
 type PodsNs struct {
@@ -27,79 +32,31 @@ type PodsNs struct {
 	PodNum    int    `json:"pod_num"`
 }
 
-// 获取pod列表
-func (p *pod) GetPods(filterName, namespace string, limit, page int) (podsResp *PodsResp, err error) {
-	//通过clientset获取pods完整列表
-	podList, err := K8s.ClientSet.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		logger.Error("获取pod列表失败", err)
-		return nil, errors.New("获取pod列表失败" + err.Error())
-	}
-	//实例化DataSelector对象
-	selectableData := &DataSelector{
-		GenericDataList: p.toCells(podList.Items),
-		DataSelectQuery: &DataSelect{
-			FilterQuery: &Filter{filterName},
-			PaginateQuery: &Paginate{
-				Limit: limit,
-				Page:  page,
-			},
-		},
-	}
-	//先过滤
-	filtered := selectableData.Filter()
-	//再拿total
-	total := len(filtered.GenericDataList)
-	//在排序和分页
-	data := filtered.Sort().Paginate()
-	//再将datacell切片数据转成原生pod切片
-	pods := p.fromCells(data.GenericDataList)
-	//返回
-	return &PodsResp{
-		Item:  pods,
-		Total: total,
-	}, nil
-}
-
-// 获取pod详情
-func (p *pod) GetPodDetail(podName, namespace string) (pod *corev1.Pod, err error) {
-	pod, err = K8s.ClientSet.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
-	if err != nil {
-		logger.Error("获取Pod详情失败" + err.Error())
-		return nil, errors.New("获取Pod详情失败" + err.Error())
-	}
-	return pod, nil
-}
-
-// 删除pod
-func (p *pod) DeletePod(podName, namespace string) (err error) {
-	err = K8s.ClientSet.CoreV1().Pods(namespace).Delete(context.TODO(), podName, metav1.DeleteOptions{})
-	if err != nil {
-		logger.Error("删除Pod失败" + err.Error())
-		return errors.New("获取Pod详情失败" + err.Error())
-	}
-	return nil
+// 定义结构体用于流式获取pod日志
+type PodLogStream struct {
+	ContainerName string `form:"container"`
+	PodName       string `form:"pod_name"`
+	Namespace     string `form:"namespace"`
+	//是否持续跟踪日志输出，等同于kubectl logs -f
+	Follow bool `form:"follow"`
+	//返回最后多少行，小于等于0时使用配置中的默认行数
+	TailLines int64 `form:"tail_lines"`
+	//返回最近多少秒的日志
+	SinceSeconds int64 `form:"since_seconds"`
+	//返回该时间之后的日志，RFC3339格式，与SinceSeconds二选一
+	SinceTime  string `form:"since_time"`
+	Timestamps bool   `form:"timestamps"`
+	//获取上一个已终止容器的日志
+	Previous bool   `form:"previous"`
+	Cluster  string `form:"cluster"`
 }
 
-// 更新pod
-func (p *pod) UpdatePod(namespace, content string) (err error) {
-	//将content反序列化成为pod对象
-	var pod = &corev1.Pod{}
-	if err = json.Unmarshal([]byte(content), pod); err != nil {
-		logger.Error("Content反序列化失败", err)
-		return errors.New("Content反序列化失败" + err.Error())
-	}
-	//更新pod
-	_, err = K8s.ClientSet.CoreV1().Pods(namespace).Update(context.TODO(), pod, metav1.UpdateOptions{})
+// 获取pod日志
+func (p *pod) GetPodLog(ctx context.Context, cluster, containerName, podName, namespace string) (log string, err error) {
+	client, err := K8s.GetClient(ctx, cluster)
 	if err != nil {
-		logger.Error("更新Pod失败" + err.Error())
-		return errors.New("更新Pod失败" + err.Error())
+		return "", err
 	}
-	return nil
-}
-
-// 获取pod日志
-func (p *pod) GetPodLog(containerName, podName, namespace string) (log string, err error) {
 	//设置日志的配置。容器名，tail行数
 	lineLimit := int64(config.PodLogTailLine)
 	option := &corev1.PodLogOptions{
@@ -107,9 +64,9 @@ func (p *pod) GetPodLog(containerName, podName, namespace string) (log string, e
 		TailLines: &lineLimit,
 	}
 	//获取request实例
-	req := K8s.ClientSet.CoreV1().Pods(namespace).GetLogs(podName, option)
+	req := client.CoreV1().Pods(namespace).GetLogs(podName, option)
 	//发起request请求。返回一个io.readcloser类型的，等同于response.body
-	podLogs, err := req.Stream(context.TODO())
+	podLogs, err := req.Stream(ctx)
 	if err != nil {
 		logger.Error("获取podlog失败", err)
 		return "", errors.New("获取podlog失败" + err.Error())
@@ -124,10 +81,48 @@ func (p *pod) GetPodLog(containerName, podName, namespace string) (log string, e
 	return buf.String(), nil
 }
 
+// 流式获取pod日志，返回的reader需要调用方关闭
+// ctx取消（如客户端断开连接）时日志流随之结束
+func (p *pod) StreamPodLog(ctx context.Context, cluster string, data PodLogStream) (stream io.ReadCloser, err error) {
+	client, err := K8s.GetClient(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	//组装日志配置
+	tailLines := data.TailLines
+	if tailLines <= 0 {
+		tailLines = int64(config.PodLogTailLine)
+	}
+	option := &corev1.PodLogOptions{
+		Container:  data.ContainerName,
+		Follow:     data.Follow,
+		Previous:   data.Previous,
+		Timestamps: data.Timestamps,
+		TailLines:  &tailLines,
+	}
+	if data.SinceTime != "" {
+		sinceTime, err := time.Parse(time.RFC3339, data.SinceTime)
+		if err != nil {
+			logger.Error("since_time格式错误", err)
+			return nil, errors.New("since_time格式错误" + err.Error())
+		}
+		option.SinceTime = &metav1.Time{Time: sinceTime}
+	} else if data.SinceSeconds > 0 {
+		option.SinceSeconds = &data.SinceSeconds
+	}
+	//发起request请求，返回日志流
+	stream, err = client.CoreV1().Pods(data.Namespace).GetLogs(data.PodName, option).Stream(ctx)
+	if err != nil {
+		logger.Error("获取podlog流失败", err)
+		return nil, errors.New("获取podlog流失败" + err.Error())
+	}
+	return stream, nil
+}
+
 // 获取pod中的容器，日志，终端功能使用
-func (p *pod) GetPodConatiner(podName, namespace string) (containers []string, err error) {
+func (p *pod) GetPodConatiner(ctx context.Context, cluster, podName, namespace string) (containers []string, err error) {
 	//获取pod详情
-	pod, err := p.GetPodDetail(podName, namespace)
+	pod, err := p.Get(ctx, cluster, podName, namespace)
 	if err != nil {
 		return nil, err
 	}
@@ -138,18 +133,21 @@ func (p *pod) GetPodConatiner(podName, namespace string) (containers []string, e
 	return containers, nil
 }
 
-// 获取每个命名空间pod数量
-func (p *pod) GetPodNumPerNs() (podsNss []*PodsNs, err error) {
+// 获取每个命名空间pod数量，allowed不为空时只统计用户可见的命名空间
+func (p *pod) GetPodNumPerNs(ctx context.Context, cluster string, allowed AccessFilter) (podsNss []*PodsNs, err error) {
 	//获取namespace列表
-	namespaceList, err := K8s.ClientSet.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
+	namespaceList, err := Namespace.listFromCache(ctx, cluster, "", labels.Everything())
 	if err != nil {
 		logger.Error("获取namespace列表失败", err)
 		return nil, errors.New("获取namespace列表失败" + err.Error())
 	}
 	//for循环
-	for _, namespace := range namespaceList.Items {
+	for _, namespace := range namespaceList {
+		if allowed != nil && !allowed(namespace.Name, "") {
+			continue
+		}
 		//获取pod列表
-		podList, err := K8s.ClientSet.CoreV1().Pods(namespace.Name).List(context.TODO(), metav1.ListOptions{})
+		podList, err := Pod.listFromCache(ctx, cluster, namespace.Name, labels.Everything())
 		if err != nil {
 			logger.Error("获取pod列表失败", err)
 			return nil, errors.New("获取pod列表失败" + err.Error())
@@ -157,28 +155,41 @@ func (p *pod) GetPodNumPerNs() (podsNss []*PodsNs, err error) {
 		//组装数据
 		podsNs := &PodsNs{
 			Namespace: namespace.Name,
-			PodNum:    len(podList.Items),
+			PodNum:    len(podList),
 		}
 		podsNss = append(podsNss, podsNs)
 	}
 	return podsNss, nil
 }
 
-// 把podCell转成corev1 pod
-func (p *pod) fromCells(cells []DataCell) []corev1.Pod {
-	pods := make([]corev1.Pod, len(cells))
-	for i := range cells {
-		pods[i] = corev1.Pod(cells[i].(podCell))
-	}
-	return pods
+// pod状态，即pod的phase
+func podStatus(obj *corev1.Pod) string {
+	return string(obj.Status.Phase)
 }
 
-// 把corev1 pods转成datacell
-func (p *pod) toCells(std []corev1.Pod) []DataCell {
-	cells := make([]DataCell, len(std))
-	for i := range std {
-		cells[i] = podCell(std[i])
+// pod支持的字段选择器字段，与apiserver支持的pod字段保持一致
+func podFields(obj *corev1.Pod) fields.Set {
+	return fields.Set{
+		"spec.nodeName":            obj.Spec.NodeName,
+		"spec.restartPolicy":       string(obj.Spec.RestartPolicy),
+		"spec.schedulerName":       obj.Spec.SchedulerName,
+		"spec.serviceAccountName":  obj.Spec.ServiceAccountName,
+		"status.phase":             string(obj.Status.Phase),
+		"status.podIP":             obj.Status.PodIP,
+		"status.nominatedNodeName": obj.Status.NominatedNodeName,
+	}
+}
 
+// pod所有容器的重启次数之和，用于按重启次数排序
+func podRestarts(obj *corev1.Pod) ComparableValue {
+	var restarts int32
+	for _, status := range obj.Status.ContainerStatuses {
+		restarts += status.RestartCount
 	}
-	return cells
+	return StdComparableInt(restarts)
+}
+
+// pod所在的节点，用于按节点排序
+func podNode(obj *corev1.Pod) ComparableValue {
+	return StdComparableString(obj.Spec.NodeName)
 }
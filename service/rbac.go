@@ -0,0 +1,270 @@
+package service
+
+import (
+	"errors"
+	"github.com/wonderivan/logger"
+	"k8s-platform/config"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var RBAC rbac
+
+type rbac struct {
+	file *jsonFile[Policy]
+}
+
+// 授权的操作
+const (
+	VerbList   = "list"
+	VerbGet    = "get"
+	VerbCreate = "create"
+	VerbUpdate = "update"
+	VerbDelete = "delete"
+	VerbScale  = "scale"
+	//重启工作负载
+	VerbRestart = "restart"
+	//deployment的回滚、暂停和恢复
+	VerbRollout = "rollout"
+	//进入容器终端
+	VerbExec = "exec"
+	//node的禁止调度、恢复调度
+	VerbCordon = "cordon"
+	VerbDrain  = "drain"
+)
+
+// 匹配所有集群、命名空间、资源类型或操作
+const wildcard = "*"
+
+// 授权策略，用户匹配任一角色的任一规则即可执行操作
+type Policy struct {
+	Roles []*Role `json:"roles"`
+}
+
+// 角色，subjects中的用户和组拥有rules中的权限
+type Role struct {
+	Name     string    `json:"name"`
+	Subjects []Subject `json:"subjects"`
+	Rules    []Rule    `json:"rules"`
+}
+
+type Subject struct {
+	//User或Group
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// 授权规则，各字段为*时匹配所有
+type Rule struct {
+	Clusters []string `json:"clusters"`
+	//集群级资源只匹配*
+	Namespaces []string `json:"namespaces"`
+	//资源类型，即资源名，如pod、deployment、svc，crd表示通过crd接口操作的任意资源
+	Kinds []string `json:"kinds"`
+	Verbs []string `json:"verbs"`
+}
+
+// 列表的访问权限，返回false的资源不可见
+type AccessFilter func(namespace, name string) bool
+
+// 初始化授权策略，策略文件不存在时创建默认策略
+// 默认admin和sre组拥有所有权限，developer组只能查看dev命名空间的资源并执行重启、扩缩容等操作，不能删除
+func (r *rbac) Init() {
+	r.file = &jsonFile[Policy]{path: config.RBACFile}
+	all := []string{wildcard}
+	created, err := r.file.init(Policy{Roles: []*Role{
+		{
+			Name:     "admin",
+			Subjects: []Subject{{Kind: "Group", Name: "admin"}, {Kind: "Group", Name: "sre"}},
+			Rules:    []Rule{{Clusters: all, Namespaces: all, Kinds: all, Verbs: all}},
+		},
+		{
+			Name:     "developer",
+			Subjects: []Subject{{Kind: "Group", Name: "developer"}},
+			Rules: []Rule{{
+				Clusters:   all,
+				Namespaces: []string{"dev"},
+				Kinds:      []string{"dashboard", "namespace", "pod", "deployment", "daemonset", "statefulset", "job", "cronjob", "svc", "ingress", "configmap", "pvc", "event"},
+				Verbs:      []string{VerbList, VerbGet, VerbScale, VerbRestart, VerbRollout, VerbExec},
+			}},
+		},
+	}})
+	if err != nil {
+		logger.Error("初始化授权策略失败", err)
+		return
+	}
+	if created {
+		logger.Warn("授权策略文件" + config.RBACFile + "不存在，已创建默认策略")
+	}
+}
+
+// 校验用户是否可以对资源执行操作，namespace为资源所在的命名空间，集群级资源为空
+// 列出所有命名空间的资源时，只要在任一命名空间有权限即可，结果再通过Filter过滤
+func (r *rbac) Authorize(claims *Claims, cluster, namespace, kind, verb string) error {
+	return r.AuthorizeScoped(claims, cluster, namespace, kind, verb, IsNamespacedKind(kind))
+}
+
+// 与Authorize相同，namespaced指定资源是否为命名空间级，用于crd等不在注册表中的资源
+// 集群级资源的列表不适用任一命名空间即可的规则，只能被*匹配
+func (r *rbac) AuthorizeScoped(claims *Claims, cluster, namespace, kind, verb string, namespaced bool) error {
+	rules, err := r.rules(claims)
+	if err != nil {
+		return err
+	}
+	if !namespaced {
+		namespace = ""
+	}
+	anyNamespace := namespaced && verb == VerbList && namespace == ""
+	for _, rule := range rules {
+		if rule.allows(cluster, kind, verb) && (anyNamespace || matchRule(rule.Namespaces, namespace)) {
+			return nil
+		}
+	}
+	return errors.New("用户" + claims.Subject + "没有权限" + verb + " " + kind)
+}
+
+// 获取用户可见的资源，namespace资源按名称判断，其他资源按所在命名空间判断
+func (r *rbac) Filter(claims *Claims, cluster, kind, verb string) (AccessFilter, error) {
+	rules, err := r.rules(claims)
+	if err != nil {
+		return nil, err
+	}
+	var namespaces [][]string
+	for _, rule := range rules {
+		if rule.allows(cluster, kind, verb) {
+			namespaces = append(namespaces, rule.Namespaces)
+		}
+	}
+	return func(namespace, name string) bool {
+		if kind == Namespace.Name {
+			namespace = name
+		}
+		for _, list := range namespaces {
+			if matchRule(list, namespace) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// 用户所属角色的所有规则
+func (r *rbac) rules(claims *Claims) ([]Rule, error) {
+	if r.file == nil {
+		return nil, errors.New("授权策略未初始化")
+	}
+	if claims == nil {
+		return nil, errors.New("未登录")
+	}
+	policy, err := r.file.get()
+	if err != nil {
+		return nil, err
+	}
+	var rules []Rule
+	for _, role := range policy.Roles {
+		if role.bound(claims) {
+			rules = append(rules, role.Rules...)
+		}
+	}
+	return rules, nil
+}
+
+// 用户本身或所属的组是否为角色的subject
+func (r *Role) bound(claims *Claims) bool {
+	for _, subject := range r.Subjects {
+		switch subject.Kind {
+		case "User":
+			if subject.Name == claims.Subject {
+				return true
+			}
+		case "Group":
+			if containsString(claims.Groups, subject.Name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 规则是否允许在集群中对该类型的资源执行操作，不判断命名空间
+func (r *Rule) allows(cluster, kind, verb string) bool {
+	return matchRule(r.Clusters, cluster) && matchRule(r.Kinds, kind) && matchRule(r.Verbs, verb)
+}
+
+// 集群级资源的namespace为空，只能被*匹配
+func matchRule(list []string, value string) bool {
+	for _, item := range list {
+		if item == wildcard || (value != "" && item == value) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResourceKind 按group和resource查找已注册资源的资源名，不区分版本，未注册的资源为crd
+func ResourceKind(gvr schema.GroupVersionResource) string {
+	for name, info := range resourceRegistry {
+		if info.GetGVR().GroupResource() == gvr.GroupResource() {
+			return name
+		}
+	}
+	return "crd"
+}
+
+// IsNamespacedKind 资源类型是否为命名空间级，未注册的类型如dashboard、event按命名空间级处理
+func IsNamespacedKind(kind string) bool {
+	info, ok := resourceRegistry[kind]
+	return !ok || info.IsNamespaced()
+}
+
+// ResourceNamespaced 判断资源是否为命名空间级，已注册的资源使用注册表，其他资源通过RESTMapper查询
+func ResourceNamespaced(cluster string, gvr schema.GroupVersionResource) (bool, error) {
+	for _, info := range resourceRegistry {
+		if info.GetGVR().GroupResource() == gvr.GroupResource() {
+			return info.IsNamespaced(), nil
+		}
+	}
+	_, namespaced, err := ResolveResource(cluster, gvr)
+	return namespaced, err
+}
+
+// 资源类型在集群中不存在或不唯一时返回的错误
+type UnknownResourceError struct {
+	Resource string
+	Err      error
+}
+
+func (e *UnknownResourceError) Error() string {
+	if e.Resource == "" {
+		return "资源类型不能为空"
+	}
+	return "资源类型" + e.Resource + "不存在" + e.Err.Error()
+}
+
+// ResolveResource 通过RESTMapper获取资源完整的gvr及是否为命名空间级，version为空时使用首选版本
+// 找不到时刷新discovery缓存后重试一次，仍找不到时返回UnknownResourceError
+func ResolveResource(cluster string, gvr schema.GroupVersionResource) (schema.GroupVersionResource, bool, error) {
+	if gvr.Resource == "" {
+		return gvr, false, &UnknownResourceError{}
+	}
+	mapper, err := K8s.GetRESTMapper(cluster)
+	if err != nil {
+		return gvr, false, err
+	}
+	gvk, err := mapper.KindFor(gvr)
+	if meta.IsNoMatchError(err) {
+		mapper.Reset()
+		gvk, err = mapper.KindFor(gvr)
+	}
+	if meta.IsNoMatchError(err) || meta.IsAmbiguousError(err) {
+		return gvr, false, &UnknownResourceError{Resource: gvr.Resource, Err: err}
+	}
+	if err != nil {
+		return gvr, false, errors.New("获取" + gvr.Resource + "的资源类型失败" + err.Error())
+	}
+	mapping, err := Manifest.restMapping(mapper, gvk)
+	if err != nil {
+		return gvr, false, err
+	}
+	return mapping.Resource, mapping.Scope.Name() == meta.RESTScopeNameNamespace, nil
+}
@@ -0,0 +1,253 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery/cached/memory"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/restmapper"
+	clienttesting "k8s.io/client-go/testing"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// 使用临时策略文件的授权
+func newTestRBAC(t *testing.T, policy Policy) *rbac {
+	t.Helper()
+	content, err := json.Marshal(policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "rbac.json")
+	if err = os.WriteFile(path, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return &rbac{file: &jsonFile[Policy]{path: path}}
+}
+
+// 测试策略，admin拥有所有权限，developer可以查看dev和test的pod、deployment并在dev中扩缩容，alice可以查看node
+func testPolicy() Policy {
+	all := []string{wildcard}
+	return Policy{Roles: []*Role{
+		{
+			Name:     "admin",
+			Subjects: []Subject{{Kind: "Group", Name: "admin"}},
+			Rules:    []Rule{{Clusters: all, Namespaces: all, Kinds: all, Verbs: all}},
+		},
+		{
+			Name:     "developer",
+			Subjects: []Subject{{Kind: "Group", Name: "developer"}},
+			Rules: []Rule{
+				{Clusters: []string{"TST-1"}, Namespaces: []string{"dev", "test"}, Kinds: []string{"pod", "deployment"}, Verbs: []string{VerbList, VerbGet}},
+				{Clusters: []string{"TST-1"}, Namespaces: []string{"dev"}, Kinds: []string{"deployment"}, Verbs: []string{VerbScale}},
+			},
+		},
+		{
+			Name:     "node-viewer",
+			Subjects: []Subject{{Kind: "User", Name: "alice"}},
+			Rules:    []Rule{{Clusters: all, Namespaces: all, Kinds: []string{"node"}, Verbs: []string{VerbList}}},
+		},
+		{
+			Name:     "configmap-editor",
+			Subjects: []Subject{{Kind: "User", Name: "carol"}},
+			Rules:    []Rule{{Clusters: all, Namespaces: []string{"dev"}, Kinds: []string{"configmap"}, Verbs: []string{VerbUpdate}}},
+		},
+	}}
+}
+
+func TestMatchRule(t *testing.T) {
+	tests := []struct {
+		name  string
+		list  []string
+		value string
+		want  bool
+	}{
+		{name: "精确匹配", list: []string{"dev", "test"}, value: "test", want: true},
+		{name: "不匹配", list: []string{"dev"}, value: "prod", want: false},
+		{name: "通配符", list: []string{wildcard}, value: "prod", want: true},
+		{name: "空值只能被通配符匹配", list: []string{"dev", ""}, value: "", want: false},
+		{name: "空值匹配通配符", list: []string{"dev", wildcard}, value: "", want: true},
+		{name: "空列表", list: nil, value: "dev", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchRule(tt.list, tt.value); got != tt.want {
+				t.Errorf("matchRule(%v, %q) = %v, want %v", tt.list, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRBACAuthorize(t *testing.T) {
+	r := newTestRBAC(t, testPolicy())
+	admin := &Claims{Subject: "root", Groups: []string{"admin"}}
+	developer := &Claims{Subject: "bob", Groups: []string{"developer"}}
+	alice := &Claims{Subject: "alice", Groups: []string{"developer"}}
+	carol := &Claims{Subject: "carol"}
+	tests := []struct {
+		name      string
+		claims    *Claims
+		cluster   string
+		namespace string
+		kind      string
+		verb      string
+		want      bool
+	}{
+		{name: "admin所有权限", claims: admin, cluster: "PRD-1", namespace: "prod", kind: "secret", verb: VerbDelete, want: true},
+		{name: "admin集群级资源", claims: admin, cluster: "PRD-1", kind: "node", verb: VerbDrain, want: true},
+		{name: "developer查看dev的pod", claims: developer, cluster: "TST-1", namespace: "dev", kind: "pod", verb: VerbGet, want: true},
+		{name: "developer不能查看prod", claims: developer, cluster: "TST-1", namespace: "prod", kind: "pod", verb: VerbGet, want: false},
+		{name: "developer不能访问其他集群", claims: developer, cluster: "PRD-1", namespace: "dev", kind: "pod", verb: VerbGet, want: false},
+		{name: "developer不能删除", claims: developer, cluster: "TST-1", namespace: "dev", kind: "pod", verb: VerbDelete, want: false},
+		{name: "developer在dev扩缩容", claims: developer, cluster: "TST-1", namespace: "dev", kind: "deployment", verb: VerbScale, want: true},
+		{name: "developer不能在test扩缩容", claims: developer, cluster: "TST-1", namespace: "test", kind: "deployment", verb: VerbScale, want: false},
+		{name: "列出所有命名空间时任一命名空间有权限即可", claims: developer, cluster: "TST-1", kind: "pod", verb: VerbList, want: true},
+		{name: "获取时命名空间不能为空", claims: developer, cluster: "TST-1", kind: "pod", verb: VerbGet, want: false},
+		{name: "集群级资源不适用任一命名空间", claims: developer, cluster: "TST-1", kind: "node", verb: VerbList, want: false},
+		{name: "集群级资源忽略请求的命名空间", claims: developer, cluster: "TST-1", namespace: "dev", kind: "pv", verb: VerbList, want: false},
+		{name: "用户subject", claims: alice, cluster: "TST-1", kind: "node", verb: VerbList, want: true},
+		{name: "用户subject带命名空间", claims: alice, cluster: "TST-1", namespace: "dev", kind: "node", verb: VerbList, want: true},
+		{name: "按规则的操作授权", claims: carol, cluster: "TST-1", namespace: "dev", kind: "configmap", verb: VerbUpdate, want: true},
+		{name: "没有授权的操作", claims: carol, cluster: "TST-1", namespace: "dev", kind: "configmap", verb: VerbList, want: false},
+		{name: "事件需要在规则中单独授权", claims: developer, cluster: "TST-1", namespace: "dev", kind: "event", verb: VerbList, want: false},
+		{name: "没有绑定角色", claims: &Claims{Subject: "eve"}, cluster: "TST-1", namespace: "dev", kind: "pod", verb: VerbList, want: false},
+		{name: "未登录", claims: nil, cluster: "TST-1", namespace: "dev", kind: "pod", verb: VerbList, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := r.Authorize(tt.claims, tt.cluster, tt.namespace, tt.kind, tt.verb)
+			if (err == nil) != tt.want {
+				t.Errorf("Authorize() err = %v, want allowed %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRBACAuthorizeScoped(t *testing.T) {
+	r := newTestRBAC(t, testPolicy())
+	admin := &Claims{Subject: "root", Groups: []string{"admin"}}
+	developer := &Claims{Subject: "bob", Groups: []string{"developer"}}
+	//crd接口操作的集群级资源只能被*匹配
+	if err := r.AuthorizeScoped(developer, "TST-1", "dev", "pod", VerbGet, false); err == nil {
+		t.Error("AuthorizeScoped() 集群级资源 err = nil")
+	}
+	if err := r.AuthorizeScoped(admin, "TST-1", "dev", "crd", VerbGet, false); err != nil {
+		t.Errorf("AuthorizeScoped() admin err = %v", err)
+	}
+	if err := r.AuthorizeScoped(developer, "TST-1", "", "pod", VerbList, true); err != nil {
+		t.Errorf("AuthorizeScoped() 命名空间级列表 err = %v", err)
+	}
+}
+
+func TestRBACFilter(t *testing.T) {
+	r := newTestRBAC(t, testPolicy())
+	developer := &Claims{Subject: "bob", Groups: []string{"developer"}}
+	tests := []struct {
+		name      string
+		claims    *Claims
+		kind      string
+		namespace string
+		object    string
+		want      bool
+	}{
+		{name: "可见命名空间", claims: developer, kind: "pod", namespace: "dev", object: "web", want: true},
+		{name: "多条规则合并", claims: developer, kind: "deployment", namespace: "test", object: "web", want: true},
+		{name: "不可见命名空间", claims: developer, kind: "pod", namespace: "prod", object: "web", want: false},
+		{name: "没有权限的资源类型", claims: developer, kind: "secret", namespace: "dev", object: "web", want: false},
+		{name: "namespace按名称判断", claims: &Claims{Subject: "root", Groups: []string{"admin"}}, kind: Namespace.Name, object: "prod", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := r.Filter(tt.claims, "TST-1", tt.kind, VerbList)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := filter(tt.namespace, tt.object); got != tt.want {
+				t.Errorf("Filter()(%q, %q) = %v, want %v", tt.namespace, tt.object, got, tt.want)
+			}
+		})
+	}
+	if _, err := r.Filter(nil, "TST-1", "pod", VerbList); err == nil {
+		t.Error("Filter() 未登录 err = nil")
+	}
+}
+
+func TestIsNamespacedKind(t *testing.T) {
+	tests := []struct {
+		kind string
+		want bool
+	}{
+		{kind: "pod", want: true},
+		{kind: "deployment", want: true},
+		{kind: "node", want: false},
+		{kind: "pv", want: false},
+		{kind: Namespace.Name, want: false},
+		{kind: "dashboard", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			if got := IsNamespacedKind(tt.kind); got != tt.want {
+				t.Errorf("IsNamespacedKind(%q) = %v, want %v", tt.kind, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveResource(t *testing.T) {
+	discovery := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{Resources: []*metav1.APIResourceList{
+		{
+			GroupVersion: "example.com/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "widgets", Kind: "Widget", Namespaced: true},
+				{Name: "clusterwidgets", Kind: "ClusterWidget"},
+			},
+		},
+	}}}
+	mapperMap := K8s.MapperMap
+	K8s.MapperMap = map[string]*restmapper.DeferredDiscoveryRESTMapper{
+		"test": restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discovery)),
+	}
+	defer func() { K8s.MapperMap = mapperMap }()
+	tests := []struct {
+		name           string
+		gvr            schema.GroupVersionResource
+		want           schema.GroupVersionResource
+		wantNamespaced bool
+		wantUnknown    bool
+	}{
+		{
+			name:           "命名空间级资源",
+			gvr:            schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"},
+			want:           schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"},
+			wantNamespaced: true,
+		},
+		{
+			name: "未指定版本时补全",
+			gvr:  schema.GroupVersionResource{Group: "example.com", Resource: "clusterwidgets"},
+			want: schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "clusterwidgets"},
+		},
+		{name: "资源不存在", gvr: schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "gadgets"}, wantUnknown: true},
+		{name: "资源为空", gvr: schema.GroupVersionResource{Group: "example.com", Version: "v1"}, wantUnknown: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, namespaced, err := ResolveResource("test", tt.gvr)
+			var unknown *UnknownResourceError
+			if errors.As(err, &unknown) != tt.wantUnknown {
+				t.Fatalf("ResolveResource() err = %v, wantUnknown %v", err, tt.wantUnknown)
+			}
+			if tt.wantUnknown {
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want || namespaced != tt.wantNamespaced {
+				t.Errorf("ResolveResource() = %v %v, want %v %v", got, namespaced, tt.want, tt.wantNamespaced)
+			}
+		})
+	}
+}
@@ -0,0 +1,592 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/wonderivan/logger"
+	"k8s-platform/config"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sort"
+	"time"
+)
+
+// Object 通用资源的类型约束，T为资源结构体如corev1.Pod，Object[T]为其指针类型
+type Object[T any] interface {
+	*T
+	metav1.Object
+	runtime.Object
+}
+
+// typed client的通用方法，client-go中各资源的XxxInterface都满足该接口
+type typedClient[PT any] interface {
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (PT, error)
+	Create(ctx context.Context, obj PT, opts metav1.CreateOptions) (PT, error)
+	Update(ctx context.Context, obj PT, opts metav1.UpdateOptions) (PT, error)
+	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (PT, error)
+}
+
+// kubectl rollout restart使用的pod模板注解
+const restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+// ListResp 定义列表的返回内容 items是资源列表 total为过滤后的元素总数
+// 服务端分页时total为当前页的元素数，continue为下一页的游标，remaining为apiserver估计的剩余元素数
+type ListResp[T any] struct {
+	Item      []T    `json:"items"`
+	Total     int    `json:"total"`
+	Continue  string `json:"continue,omitempty"`
+	Remaining *int64 `json:"remaining,omitempty"`
+}
+
+// DetailResp 定义详情的内容 item是资源对象 events为资源相关的事件，按时间倒序
+// 接口中item仍作为data返回，events放在单独的key中，不改变原有详情的结构
+type DetailResp[T any] struct {
+	Item   *T             `json:"item"`
+	Events []corev1.Event `json:"events"`
+}
+
+// Resource 通用资源，注册后自动具备列表(过滤、排序、分页)、详情、删除、更新、创建功能
+type Resource[T any, PT Object[T]] struct {
+	//资源名，如pod、deployment，用于注册表和错误信息
+	Name       string
+	GVR        schema.GroupVersionResource
+	Namespaced bool
+	//获取资源的typed client，集群级资源忽略namespace
+	client func(clientset kubernetes.Interface, namespace string) typedClient[PT]
+	//资源状态，用于按状态过滤
+	status func(obj PT) string
+	//字段选择器可匹配的额外字段，metadata.name和metadata.namespace默认支持
+	fields func(obj PT) fields.Set
+	//额外的排序字段，key为sort_by中使用的字段名
+	properties map[string]func(obj PT) ComparableValue
+	//不放入informer缓存，列表直接请求apiserver，用于secret等不适合常驻内存的资源
+	uncached bool
+}
+
+// 已注册资源的公共信息
+type ResourceInfo interface {
+	GetName() string
+	GetGVR() schema.GroupVersionResource
+	IsNamespaced() bool
+	//是否放入informer缓存
+	IsCached() bool
+}
+
+// 资源注册表，key为资源名，informer缓存根据注册表启动
+var resourceRegistry = map[string]ResourceInfo{}
+
+// 注册通用资源
+func newResource[T any, PT Object[T]](name string, gvr schema.GroupVersionResource, namespaced bool,
+	client func(clientset kubernetes.Interface, namespace string) typedClient[PT]) *Resource[T, PT] {
+	r := &Resource[T, PT]{
+		Name:       name,
+		GVR:        gvr,
+		Namespaced: namespaced,
+		client:     client,
+	}
+	resourceRegistry[name] = r
+	return r
+}
+
+// 设置资源状态的获取方法
+func (r *Resource[T, PT]) withStatus(status func(obj PT) string) *Resource[T, PT] {
+	r.status = status
+	return r
+}
+
+// 设置字段选择器可匹配的额外字段
+func (r *Resource[T, PT]) withFields(fields func(obj PT) fields.Set) *Resource[T, PT] {
+	r.fields = fields
+	return r
+}
+
+// 不放入informer缓存，每次列表都请求apiserver
+func (r *Resource[T, PT]) withoutCache() *Resource[T, PT] {
+	r.uncached = true
+	return r
+}
+
+// 声明额外的排序字段，name、namespace、creationTimestamp、status默认支持
+func (r *Resource[T, PT]) withProperty(name string, property func(obj PT) ComparableValue) *Resource[T, PT] {
+	if r.properties == nil {
+		r.properties = map[string]func(obj PT) ComparableValue{}
+	}
+	r.properties[name] = property
+	return r
+}
+
+// 获取资源声明的额外排序字段，按字段名排序
+func (r *Resource[T, PT]) Properties() []string {
+	names := make([]string, 0, len(r.properties))
+	for name := range r.properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// 根据资源名获取已注册的资源
+func GetResourceInfo(name string) (ResourceInfo, error) {
+	r, ok := resourceRegistry[name]
+	if !ok {
+		return nil, errors.New("资源类型" + name + "未注册")
+	}
+	return r, nil
+}
+
+// 获取所有已注册的资源，按资源名排序
+func GetResourceInfos() []ResourceInfo {
+	names := make([]string, 0, len(resourceRegistry))
+	for name := range resourceRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	infos := make([]ResourceInfo, len(names))
+	for i, name := range names {
+		infos[i] = resourceRegistry[name]
+	}
+	return infos
+}
+
+func (r *Resource[T, PT]) GetName() string {
+	return r.Name
+}
+
+func (r *Resource[T, PT]) GetGVR() schema.GroupVersionResource {
+	return r.GVR
+}
+
+func (r *Resource[T, PT]) IsCached() bool {
+	return !r.uncached
+}
+
+func (r *Resource[T, PT]) IsNamespaced() bool {
+	return r.Namespaced
+}
+
+// 获取指定集群的typed client
+func (r *Resource[T, PT]) getClient(ctx context.Context, cluster, namespace string) (typedClient[PT], error) {
+	clientset, err := K8s.GetClient(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	return r.client(clientset, namespace), nil
+}
+
+// 从informer缓存中获取列表，selector下推到缓存的lister
+// 缓存以管理员身份同步，开启模拟用户时改为以用户身份直接请求apiserver，由k8s鉴权
+func (r *Resource[T, PT]) listFromCache(ctx context.Context, cluster, namespace string, selector labels.Selector) ([]PT, error) {
+	return r.listSelected(ctx, cluster, namespace, selector, fields.Everything())
+}
+
+// 按标签和字段选择器获取列表，开启模拟用户或资源不缓存时两个选择器都下推到apiserver
+// 使用缓存时标签选择器下推到lister，字段选择器按资源声明的字段在内存中匹配
+func (r *Resource[T, PT]) listSelected(ctx context.Context, cluster, namespace string, labelSelector labels.Selector, fieldSelector fields.Selector) ([]PT, error) {
+	if config.Impersonate || r.uncached {
+		items, _, err := r.listFromServer(ctx, cluster, namespace, metav1.ListOptions{
+			LabelSelector: labelSelector.String(),
+			FieldSelector: fieldSelector.String(),
+		})
+		return items, err
+	}
+	items, err := r.listFromInformer(cluster, namespace, labelSelector)
+	if err != nil || fieldSelector.Empty() {
+		return items, err
+	}
+	matched := make([]PT, 0, len(items))
+	for _, item := range items {
+		if fieldSelector.Matches(r.fieldSet(item)) {
+			matched = append(matched, item)
+		}
+	}
+	return matched, nil
+}
+
+// 从informer缓存中获取列表，不论是否开启模拟用户，只用于不返回对象内容的统计等场景
+func (r *Resource[T, PT]) listFromInformer(cluster, namespace string, selector labels.Selector) ([]PT, error) {
+	if r.uncached {
+		return nil, errors.New(r.Name + "不在informer缓存中")
+	}
+	informer, err := K8s.GetInformer(cluster)
+	if err != nil {
+		return nil, err
+	}
+	genericInformer, err := informer.ForResource(r.GVR)
+	if err != nil {
+		return nil, err
+	}
+	var objects []runtime.Object
+	if r.Namespaced {
+		objects, err = genericInformer.Lister().ByNamespace(namespace).List(selector)
+	} else {
+		objects, err = genericInformer.Lister().List(selector)
+	}
+	if err != nil {
+		return nil, err
+	}
+	items := make([]PT, 0, len(objects))
+	for _, obj := range objects {
+		if item, ok := obj.(PT); ok {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// List 获取资源列表，支持过滤、排序、分页，namespace为空时获取所有命名空间
+func (r *Resource[T, PT]) List(ctx context.Context, cluster, namespace string, query *DataSelect) (resp *ListResp[T], err error) {
+	if err = query.FilterQuery.Parse(); err != nil {
+		return nil, err
+	}
+	if err = query.SortQuery.Parse(r.Properties()); err != nil {
+		return nil, err
+	}
+	if query.PaginateQuery.ServerSide {
+		return r.listChunk(ctx, cluster, namespace, query)
+	}
+	//从informer缓存中获取列表，选择器下推到缓存
+	labelSelector, fieldSelector := query.FilterQuery.TakeSelectors()
+	items, err := r.listSelected(ctx, cluster, namespace, labelSelector, fieldSelector)
+	if err != nil {
+		logger.Error("获取"+r.Name+"列表失败", err)
+		return nil, errors.New("获取" + r.Name + "列表失败" + err.Error())
+	}
+	//实例化DataSelector对象
+	selectableData := &DataSelector{
+		GenericDataList: r.toCells(items),
+		DataSelectQuery: query,
+	}
+	//先过滤
+	filtered := selectableData.Filter()
+	//再拿total
+	total := len(filtered.GenericDataList)
+	//在排序和分页
+	data := filtered.Sort().Paginate()
+	//再将datacell切片数据转成原生资源切片
+	return &ListResp[T]{
+		Item:  r.fromCells(data.GenericDataList),
+		Total: total,
+	}, nil
+}
+
+// 服务端分页获取资源列表，不经过informer缓存，直接使用dynamic client分批请求apiserver
+// 名称、状态等不能下推的过滤条件只作用于当前页，因此一页的元素数可能少于limit
+func (r *Resource[T, PT]) listChunk(ctx context.Context, cluster, namespace string, query *DataSelect) (resp *ListResp[T], err error) {
+	options, err := query.ChunkListOptions()
+	if err != nil {
+		return nil, err
+	}
+	items, list, err := r.listFromServer(ctx, cluster, namespace, options)
+	if err != nil {
+		logger.Error("获取"+r.Name+"列表失败", err)
+		return nil, errors.New("获取" + r.Name + "列表失败" + err.Error())
+	}
+	selectableData := &DataSelector{
+		GenericDataList: r.toCells(items),
+		DataSelectQuery: query,
+	}
+	data := selectableData.Filter()
+	return &ListResp[T]{
+		Item:      r.fromCells(data.GenericDataList),
+		Total:     len(data.GenericDataList),
+		Continue:  list.GetContinue(),
+		Remaining: list.GetRemainingItemCount(),
+	}, nil
+}
+
+// 使用dynamic client直接从apiserver获取列表并转换为资源类型，同时返回原始列表用于获取分页游标
+func (r *Resource[T, PT]) listFromServer(ctx context.Context, cluster, namespace string, options metav1.ListOptions) ([]PT, *unstructured.UnstructuredList, error) {
+	client, err := K8s.GetDynamicClient(ctx, cluster)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !r.Namespaced {
+		namespace = ""
+	}
+	list, err := client.Resource(r.GVR).Namespace(namespace).List(ctx, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	items := make([]PT, len(list.Items))
+	for i := range list.Items {
+		items[i] = new(T)
+		if err = runtime.DefaultUnstructuredConverter.FromUnstructured(list.Items[i].Object, items[i]); err != nil {
+			return nil, nil, errors.New("转换" + r.Name + "失败" + err.Error())
+		}
+	}
+	return items, list, nil
+}
+
+// Get 获取资源详情
+func (r *Resource[T, PT]) Get(ctx context.Context, cluster, name, namespace string) (obj PT, err error) {
+	client, err := r.getClient(ctx, cluster, namespace)
+	if err != nil {
+		return nil, err
+	}
+	obj, err = client.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		logger.Error("获取" + r.Name + "详情失败" + err.Error())
+		return nil, errors.New("获取" + r.Name + "详情失败" + err.Error())
+	}
+	return obj, nil
+}
+
+// GetDetail 获取资源详情和资源相关的事件，withEvents为false时不获取事件，用于没有事件查看权限的用户
+// 事件只用于辅助排查，获取失败时只记录日志，不影响详情
+func (r *Resource[T, PT]) GetDetail(ctx context.Context, cluster, name, namespace string, withEvents bool) (detail *DetailResp[T], err error) {
+	obj, err := r.Get(ctx, cluster, name, namespace)
+	if err != nil {
+		return nil, err
+	}
+	var events []corev1.Event
+	if withEvents {
+		if events, err = Event.GetObjectEvents(ctx, cluster, obj); err != nil {
+			logger.Warn("获取"+r.Name+"事件失败", err)
+		}
+	}
+	return &DetailResp[T]{
+		Item:   obj,
+		Events: events,
+	}, nil
+}
+
+// Delete 删除资源
+func (r *Resource[T, PT]) Delete(ctx context.Context, cluster, name, namespace string) (err error) {
+	client, err := r.getClient(ctx, cluster, namespace)
+	if err != nil {
+		return err
+	}
+	err = client.Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil {
+		logger.Error("删除" + r.Name + "失败" + err.Error())
+		return errors.New("删除" + r.Name + "失败" + err.Error())
+	}
+	return nil
+}
+
+// Update 更新资源，content为资源的yaml或json，返回服务端保存后的对象
+// options.Apply为true时使用server-side apply，只提交content中声明的字段；options.DryRun为true时不持久化
+func (r *Resource[T, PT]) Update(ctx context.Context, cluster, namespace, content string, options UpdateOptions) (obj PT, err error) {
+	//将content反序列化成为资源对象，同时校验内容
+	obj, err = r.decodeIn(content, namespace)
+	if err != nil {
+		return nil, err
+	}
+	client, err := r.getClient(ctx, cluster, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if options.Apply {
+		var patch []byte
+		if patch, err = applyPatch(content, r.gvk()); err != nil {
+			return nil, err
+		}
+		obj, err = client.Patch(ctx, obj.GetName(), types.ApplyPatchType, patch, options.patchOptions())
+	} else {
+		obj, err = client.Update(ctx, obj, options.updateOptions())
+	}
+	if err != nil {
+		logger.Error("更新" + r.Name + "失败" + err.Error())
+		return nil, errors.New("更新" + r.Name + "失败" + err.Error())
+	}
+	return obj, nil
+}
+
+// Diff 对比线上对象和编辑后的content，content与Update的参数相同
+func (r *Resource[T, PT]) Diff(ctx context.Context, cluster, namespace, content string) (result *DiffResult, err error) {
+	edited, err := r.decodeIn(content, namespace)
+	if err != nil {
+		return nil, err
+	}
+	live, err := r.Get(ctx, cluster, edited.GetName(), namespace)
+	if err != nil {
+		return nil, err
+	}
+	liveMap, err := r.toUnstructured(live)
+	if err != nil {
+		return nil, err
+	}
+	editedMap, err := r.toUnstructured(edited)
+	if err != nil {
+		return nil, err
+	}
+	result, err = diffObjects(liveMap, editedMap)
+	if errors.Is(err, ErrDiffTooLarge) {
+		return nil, err
+	}
+	if err != nil {
+		logger.Error("对比"+r.Name+"失败", err)
+		return nil, errors.New("对比" + r.Name + "失败" + err.Error())
+	}
+	return result, nil
+}
+
+// 转成unstructured的内容，typed client返回的对象没有apiVersion和kind，统一补全避免产生差异
+func (r *Resource[T, PT]) toUnstructured(obj PT) (map[string]interface{}, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, errors.New("转换" + r.Name + "失败" + err.Error())
+	}
+	item := &unstructured.Unstructured{Object: content}
+	item.SetGroupVersionKind(r.gvk())
+	return item.Object, nil
+}
+
+// 重启工作负载，修改pod模板的restartedAt注解触发滚动更新，与kubectl rollout restart一致
+// 只适用于带pod模板的资源，如deployment、statefulset、daemonset
+func (r *Resource[T, PT]) restart(ctx context.Context, cluster, name, namespace string) (err error) {
+	client, err := r.getClient(ctx, cluster, namespace)
+	if err != nil {
+		return err
+	}
+	patchData := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]string{
+						restartedAtAnnotation: time.Now().Format(time.RFC3339),
+					},
+				},
+			},
+		},
+	}
+	patchByte, err := json.Marshal(patchData)
+	if err != nil {
+		logger.Error("patchdata序列化失败", err)
+		return errors.New("patchdata序列化失败" + err.Error())
+	}
+	_, err = client.Patch(ctx, name, types.StrategicMergePatchType, patchByte, metav1.PatchOptions{FieldManager: fieldManager})
+	if err != nil {
+		logger.Error("重启"+r.Name+"失败", err)
+		return errors.New("重启" + r.Name + "失败" + err.Error())
+	}
+	return nil
+}
+
+// Create 创建资源，content为资源的yaml或json，content中未指定namespace时使用传入的namespace
+func (r *Resource[T, PT]) Create(ctx context.Context, cluster, namespace, content string) (err error) {
+	obj, err := r.decodeIn(content, namespace)
+	if err != nil {
+		return err
+	}
+	_, err = r.create(ctx, cluster, obj)
+	return err
+}
+
+// 创建资源对象，返回服务端保存后的对象
+func (r *Resource[T, PT]) create(ctx context.Context, cluster string, obj PT) (created PT, err error) {
+	client, err := r.getClient(ctx, cluster, obj.GetNamespace())
+	if err != nil {
+		return nil, err
+	}
+	created, err = client.Create(ctx, obj, metav1.CreateOptions{FieldManager: fieldManager})
+	if err != nil {
+		logger.Error("创建" + r.Name + "失败" + err.Error())
+		return nil, errors.New("创建" + r.Name + "失败" + err.Error())
+	}
+	return created, nil
+}
+
+// 资源的GroupVersionKind，kind从client-go的scheme中获取
+func (r *Resource[T, PT]) gvk() schema.GroupVersionKind {
+	gvks, _, err := scheme.Scheme.ObjectKinds(PT(new(T)))
+	if err != nil || len(gvks) == 0 {
+		return r.GVR.GroupVersion().WithKind("")
+	}
+	return gvks[0]
+}
+
+// 将content反序列化成为资源对象，支持yaml和json，content中没有apiVersion和kind时按当前资源类型解析
+func (r *Resource[T, PT]) decode(content string) (PT, error) {
+	obj, gvk, err := scheme.Codecs.UniversalDeserializer().Decode([]byte(content), nil, PT(new(T)))
+	if err != nil {
+		logger.Error("Content反序列化失败", err)
+		return nil, errors.New("Content反序列化失败" + err.Error())
+	}
+	item, ok := obj.(PT)
+	if !ok {
+		return nil, errors.New("Content的类型" + gvk.Kind + "与" + r.Name + "不匹配")
+	}
+	return item, nil
+}
+
+// 反序列化content并校验namespace，集群级资源不校验
+func (r *Resource[T, PT]) decodeIn(content, namespace string) (PT, error) {
+	obj, err := r.decode(content)
+	if err != nil {
+		return nil, err
+	}
+	if r.Namespaced {
+		if err = bindNamespace(obj, namespace); err != nil {
+			return nil, err
+		}
+	}
+	return obj, nil
+}
+
+// content中未指定namespace时使用请求的namespace，指定时必须与请求的一致
+// 授权只校验请求中的namespace，避免通过content操作无权限的命名空间
+func bindNamespace(obj metav1.Object, namespace string) error {
+	if obj.GetNamespace() == "" {
+		obj.SetNamespace(namespace)
+		return nil
+	}
+	if obj.GetNamespace() != namespace {
+		return errors.New("content中的namespace " + obj.GetNamespace() + "与请求的namespace " + namespace + "不一致")
+	}
+	return nil
+}
+
+// 把资源转成datacell
+func (r *Resource[T, PT]) toCells(std []PT) []DataCell {
+	cells := make([]DataCell, len(std))
+	for i := range std {
+		cells[i] = r.toCell(std[i])
+	}
+	return cells
+}
+
+// 资源支持的字段选择器字段，包括通用的metadata.name、metadata.namespace和资源声明的字段
+func (r *Resource[T, PT]) fieldSet(obj PT) fields.Set {
+	set := objectFields(obj)
+	if r.fields != nil {
+		for key, value := range r.fields(obj) {
+			set[key] = value
+		}
+	}
+	return set
+}
+
+// 把单个资源转成datacell，同时计算状态、字段和排序字段
+func (r *Resource[T, PT]) toCell(obj PT) objectCell {
+	cell := objectCell{Object: obj, fieldsSet: r.fieldSet(obj)}
+	if r.status != nil {
+		cell.status = r.status(obj)
+	}
+	if len(r.properties) > 0 {
+		cell.properties = make(map[string]ComparableValue, len(r.properties))
+		for name, property := range r.properties {
+			cell.properties[name] = property(obj)
+		}
+	}
+	return cell
+}
+
+// 把datacell转成原生资源
+func (r *Resource[T, PT]) fromCells(cells []DataCell) []T {
+	items := make([]T, len(cells))
+	for i := range cells {
+		items[i] = *cells[i].(objectCell).Object.(PT)
+	}
+	return items
+}
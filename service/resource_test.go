@@ -0,0 +1,121 @@
+package service
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func TestResourceDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{
+			name:    "yaml",
+			content: "apiVersion: v1\nkind: Pod\nmetadata:\n  name: web\n  namespace: dev\n",
+		},
+		{
+			name:    "json",
+			content: `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"web","namespace":"dev"}}`,
+		},
+		{
+			name:    "没有apiVersion和kind时按当前资源类型解析",
+			content: "metadata:\n  name: web\n  namespace: dev\n",
+		},
+		{
+			name:    "类型不匹配",
+			content: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: web\n",
+			wantErr: true,
+		},
+		{
+			name:    "格式不合法",
+			content: "metadata: [",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj, err := Pod.decode(tt.content)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decode() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && obj.Name != "web" {
+				t.Errorf("decode() name = %q, want web", obj.Name)
+			}
+		})
+	}
+}
+
+func TestResourceCells(t *testing.T) {
+	pods := []*corev1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "dev"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "prod"},
+			Status:     corev1.PodStatus{Phase: corev1.PodPending},
+		},
+	}
+	cells := Pod.toCells(pods)
+	if len(cells) != len(pods) {
+		t.Fatalf("toCells() len = %d, want %d", len(cells), len(pods))
+	}
+	for i, cell := range cells {
+		if cell.GetName() != pods[i].Name || cell.GetNamespace() != pods[i].Namespace {
+			t.Errorf("cell %d = %s/%s, want %s/%s", i, cell.GetNamespace(), cell.GetName(), pods[i].Namespace, pods[i].Name)
+		}
+		if cell.GetStatus() != string(pods[i].Status.Phase) {
+			t.Errorf("cell %d status = %q, want %q", i, cell.GetStatus(), pods[i].Status.Phase)
+		}
+		if got := cell.GetFields()["status.phase"]; got != string(pods[i].Status.Phase) {
+			t.Errorf("cell %d status.phase = %q, want %q", i, got, pods[i].Status.Phase)
+		}
+	}
+	items := Pod.fromCells(cells)
+	for i := range items {
+		if items[i].Name != pods[i].Name || items[i].Namespace != pods[i].Namespace {
+			t.Errorf("fromCells() %d = %s/%s, want %s/%s", i, items[i].Namespace, items[i].Name, pods[i].Namespace, pods[i].Name)
+		}
+	}
+}
+
+func TestBindNamespace(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		namespace string
+		want      string
+		wantErr   bool
+	}{
+		{name: "未指定时使用请求的namespace", content: "metadata:\n  name: web\n", namespace: "dev", want: "dev"},
+		{name: "与请求一致", content: "metadata:\n  name: web\n  namespace: dev\n", namespace: "dev", want: "dev"},
+		{name: "与请求不一致", content: "metadata:\n  name: web\n  namespace: prod\n", namespace: "dev", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj, err := Pod.decodeIn(tt.content, tt.namespace)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeIn() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && obj.Namespace != tt.want {
+				t.Errorf("decodeIn() namespace = %q, want %q", obj.Namespace, tt.want)
+			}
+		})
+	}
+	//集群级资源不校验namespace
+	if _, err := Node.decodeIn("metadata:\n  name: node-1\n", "dev"); err != nil {
+		t.Errorf("Node.decodeIn() err = %v", err)
+	}
+}
+
+func TestResourceCached(t *testing.T) {
+	//secret不放入informer缓存
+	for _, info := range GetResourceInfos() {
+		if want := info.GetName() != Secret.Name; info.IsCached() != want {
+			t.Errorf("%s IsCached() = %v, want %v", info.GetName(), info.IsCached(), want)
+		}
+	}
+}
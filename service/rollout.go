@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/wonderivan/logger"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/json"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	//deployment和replicaset上记录版本号的注解
+	revisionAnnotation = "deployment.kubernetes.io/revision"
+	//记录变更原因的注解，kubectl --record和kubectl annotate设置
+	changeCauseAnnotation = "kubernetes.io/change-cause"
+)
+
+// deployment的一个历史版本，对应一个replicaset
+type RolloutRevision struct {
+	Revision     int64     `json:"revision"`
+	ReplicaSet   string    `json:"replicaset"`
+	Images       []string  `json:"images"`
+	ChangeCause  string    `json:"change_cause"`
+	Replicas     int32     `json:"replicas"`
+	CreationTime time.Time `json:"creation_time"`
+	//是否为deployment当前使用的版本
+	Current bool `json:"current"`
+}
+
+// deployment的滚动更新状态
+type RolloutStatus struct {
+	Revision          int64 `json:"revision"`
+	Replicas          int32 `json:"replicas"`
+	UpdatedReplicas   int32 `json:"updated_replicas"`
+	ReadyReplicas     int32 `json:"ready_replicas"`
+	AvailableReplicas int32 `json:"available_replicas"`
+	Paused            bool  `json:"paused"`
+	//滚动更新已完成
+	Done bool `json:"done"`
+	//超过progressDeadlineSeconds仍未完成
+	DeadlineExceeded bool   `json:"deadline_exceeded"`
+	Message          string `json:"message"`
+}
+
+// 获取deployment的历史版本，按版本号倒序
+func (p *deployment) GetRolloutHistory(ctx context.Context, cluster, deploymentName, namespace string) (revisions []*RolloutRevision, err error) {
+	deployment, replicaSets, err := p.getReplicaSets(ctx, cluster, deploymentName, namespace)
+	if err != nil {
+		return nil, err
+	}
+	current := parseRevision(deployment.Annotations)
+	for _, rs := range replicaSets {
+		revision := &RolloutRevision{
+			Revision:     parseRevision(rs.Annotations),
+			ReplicaSet:   rs.Name,
+			ChangeCause:  rs.Annotations[changeCauseAnnotation],
+			CreationTime: rs.CreationTimestamp.Time,
+		}
+		if rs.Spec.Replicas != nil {
+			revision.Replicas = *rs.Spec.Replicas
+		}
+		for _, container := range rs.Spec.Template.Spec.Containers {
+			revision.Images = append(revision.Images, container.Image)
+		}
+		revision.Current = revision.Revision == current
+		revisions = append(revisions, revision)
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Revision > revisions[j].Revision
+	})
+	return revisions, nil
+}
+
+// 回滚deployment到指定版本，revision为0时回滚到上一个版本，与kubectl rollout undo一致
+func (p *deployment) RollbackDeployment(ctx context.Context, cluster, deploymentName, namespace string, revision int64) (err error) {
+	deployment, replicaSets, err := p.getReplicaSets(ctx, cluster, deploymentName, namespace)
+	if err != nil {
+		return err
+	}
+	if deployment.Spec.Paused {
+		return errors.New("deployment已暂停，请先恢复后再回滚")
+	}
+	target, err := findRevision(replicaSets, parseRevision(deployment.Annotations), revision)
+	if err != nil {
+		return err
+	}
+	patchByte, err := rollbackPatch(deployment, target)
+	if err != nil {
+		logger.Error("patchdata序列化失败", err)
+		return errors.New("patchdata序列化失败" + err.Error())
+	}
+	client, err := K8s.GetClient(ctx, cluster)
+	if err != nil {
+		return err
+	}
+	_, err = client.AppsV1().Deployments(namespace).Patch(ctx, deploymentName, types.JSONPatchType, patchByte, metav1.PatchOptions{FieldManager: fieldManager})
+	if err != nil {
+		logger.Error("回滚deployment失败", err)
+		return errors.New("回滚deployment失败" + err.Error())
+	}
+	return nil
+}
+
+// 暂停deployment的滚动更新
+func (p *deployment) PauseDeployment(ctx context.Context, cluster, deploymentName, namespace string) (err error) {
+	return p.setPaused(ctx, cluster, deploymentName, namespace, true)
+}
+
+// 恢复deployment的滚动更新
+func (p *deployment) ResumeDeployment(ctx context.Context, cluster, deploymentName, namespace string) (err error) {
+	return p.setPaused(ctx, cluster, deploymentName, namespace, false)
+}
+
+func (p *deployment) setPaused(ctx context.Context, cluster, deploymentName, namespace string, paused bool) (err error) {
+	client, err := K8s.GetClient(ctx, cluster)
+	if err != nil {
+		return err
+	}
+	patchByte := []byte(`{"spec":{"paused":` + strconv.FormatBool(paused) + `}}`)
+	_, err = client.AppsV1().Deployments(namespace).Patch(ctx, deploymentName, types.MergePatchType, patchByte, metav1.PatchOptions{FieldManager: fieldManager})
+	if err != nil {
+		logger.Error("修改deployment暂停状态失败", err)
+		return errors.New("修改deployment暂停状态失败" + err.Error())
+	}
+	return nil
+}
+
+// 获取deployment的滚动更新状态，判断逻辑与kubectl rollout status一致
+func (p *deployment) GetRolloutStatus(ctx context.Context, cluster, deploymentName, namespace string) (status *RolloutStatus, err error) {
+	deployment, err := p.Get(ctx, cluster, deploymentName, namespace)
+	if err != nil {
+		return nil, err
+	}
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	status = &RolloutStatus{
+		Revision:          parseRevision(deployment.Annotations),
+		Replicas:          replicas,
+		UpdatedReplicas:   deployment.Status.UpdatedReplicas,
+		ReadyReplicas:     deployment.Status.ReadyReplicas,
+		AvailableReplicas: deployment.Status.AvailableReplicas,
+		Paused:            deployment.Spec.Paused,
+	}
+	if deployment.Generation > deployment.Status.ObservedGeneration {
+		status.Message = "等待deployment的更新被控制器处理"
+		return status, nil
+	}
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentProgressing && condition.Reason == "ProgressDeadlineExceeded" {
+			status.DeadlineExceeded = true
+			status.Message = "滚动更新超过期限: " + condition.Message
+			return status, nil
+		}
+	}
+	switch {
+	case deployment.Status.UpdatedReplicas < replicas:
+		status.Message = fmt.Sprintf("等待滚动更新完成: %d/%d个副本已更新", deployment.Status.UpdatedReplicas, replicas)
+	case deployment.Status.Replicas > deployment.Status.UpdatedReplicas:
+		status.Message = fmt.Sprintf("等待滚动更新完成: %d个旧副本等待终止", deployment.Status.Replicas-deployment.Status.UpdatedReplicas)
+	case deployment.Status.AvailableReplicas < deployment.Status.UpdatedReplicas:
+		status.Message = fmt.Sprintf("等待滚动更新完成: %d/%d个已更新副本可用", deployment.Status.AvailableReplicas, deployment.Status.UpdatedReplicas)
+	default:
+		status.Done = true
+		status.Message = "滚动更新已完成"
+	}
+	if deployment.Spec.Paused && !status.Done {
+		status.Message += "(已暂停)"
+	}
+	return status, nil
+}
+
+// 获取deployment及其拥有的replicaset
+func (p *deployment) getReplicaSets(ctx context.Context, cluster, deploymentName, namespace string) (*appsv1.Deployment, []appsv1.ReplicaSet, error) {
+	deployment, err := p.Get(ctx, cluster, deploymentName, namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := K8s.GetClient(ctx, cluster)
+	if err != nil {
+		return nil, nil, err
+	}
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return nil, nil, errors.New("deployment的selector不合法" + err.Error())
+	}
+	list, err := client.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		logger.Error("获取replicaset列表失败", err)
+		return nil, nil, errors.New("获取replicaset列表失败" + err.Error())
+	}
+	//selector可能匹配到其他deployment的replicaset，只保留owner为该deployment的
+	replicaSets := make([]appsv1.ReplicaSet, 0, len(list.Items))
+	for _, rs := range list.Items {
+		if metav1.IsControlledBy(&rs, deployment) {
+			replicaSets = append(replicaSets, rs)
+		}
+	}
+	return deployment, replicaSets, nil
+}
+
+// 查找回滚的目标版本，revision为0时取当前版本之前的最新版本
+func findRevision(replicaSets []appsv1.ReplicaSet, current, revision int64) (*appsv1.ReplicaSet, error) {
+	var target *appsv1.ReplicaSet
+	var targetRevision int64
+	for i := range replicaSets {
+		r := parseRevision(replicaSets[i].Annotations)
+		if revision > 0 {
+			if r == revision {
+				return &replicaSets[i], nil
+			}
+			continue
+		}
+		if r < current && r > targetRevision {
+			target, targetRevision = &replicaSets[i], r
+		}
+	}
+	if target == nil {
+		if revision > 0 {
+			return nil, errors.New("版本" + strconv.FormatInt(revision, 10) + "不存在")
+		}
+		return nil, errors.New("没有可回滚的历史版本")
+	}
+	return target, nil
+}
+
+// 回滚使用的json patch，使用目标版本的pod模板替换当前模板，去掉replicaset自动添加的pod-template-hash标签
+// 变更原因与目标版本一致，目标版本没有时去掉
+func rollbackPatch(deployment *appsv1.Deployment, target *appsv1.ReplicaSet) ([]byte, error) {
+	template := target.Spec.Template.DeepCopy()
+	delete(template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+	annotations := map[string]string{}
+	for key, value := range deployment.Annotations {
+		annotations[key] = value
+	}
+	if cause, ok := target.Annotations[changeCauseAnnotation]; ok {
+		annotations[changeCauseAnnotation] = cause
+	} else {
+		delete(annotations, changeCauseAnnotation)
+	}
+	patchData := []map[string]interface{}{
+		{"op": "replace", "path": "/spec/template", "value": template},
+		{"op": "replace", "path": "/metadata/annotations", "value": annotations},
+	}
+	return json.Marshal(patchData)
+}
+
+// 从注解中解析版本号，没有或不合法时为0
+func parseRevision(annotations map[string]string) int64 {
+	revision, _ := strconv.ParseInt(annotations[revisionAnnotation], 10, 64)
+	return revision
+}
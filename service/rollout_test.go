@@ -0,0 +1,144 @@
+package service
+
+import (
+	"encoding/json"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strconv"
+	"testing"
+)
+
+func testReplicaSet(name string, revision int64, annotations map[string]string) appsv1.ReplicaSet {
+	rs := appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+		Name:        name,
+		Annotations: map[string]string{revisionAnnotation: strconv.FormatInt(revision, 10)},
+	}}
+	for key, value := range annotations {
+		rs.Annotations[key] = value
+	}
+	return rs
+}
+
+func TestFindRevision(t *testing.T) {
+	replicaSets := []appsv1.ReplicaSet{
+		testReplicaSet("web-1", 1, nil),
+		testReplicaSet("web-3", 3, nil),
+		testReplicaSet("web-2", 2, nil),
+		testReplicaSet("web-5", 5, nil),
+	}
+	tests := []struct {
+		name     string
+		current  int64
+		revision int64
+		want     string
+		wantErr  bool
+	}{
+		{name: "上一个版本", current: 5, revision: 0, want: "web-3"},
+		{name: "跳过比当前新的版本", current: 3, revision: 0, want: "web-2"},
+		{name: "指定版本", current: 5, revision: 2, want: "web-2"},
+		{name: "指定当前版本", current: 5, revision: 5, want: "web-5"},
+		{name: "指定的版本不存在", current: 5, revision: 4, wantErr: true},
+		{name: "没有历史版本", current: 1, revision: 0, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := findRevision(replicaSets, tt.current, tt.revision)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("findRevision() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.Name != tt.want {
+				t.Errorf("findRevision() = %s, want %s", got.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRevision(t *testing.T) {
+	tests := []struct {
+		annotations map[string]string
+		want        int64
+	}{
+		{annotations: map[string]string{revisionAnnotation: "7"}, want: 7},
+		{annotations: map[string]string{revisionAnnotation: "abc"}, want: 0},
+		{annotations: nil, want: 0},
+	}
+	for _, tt := range tests {
+		if got := parseRevision(tt.annotations); got != tt.want {
+			t.Errorf("parseRevision(%v) = %d, want %d", tt.annotations, got, tt.want)
+		}
+	}
+}
+
+func TestRollbackPatch(t *testing.T) {
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+		Name: "web",
+		Annotations: map[string]string{
+			revisionAnnotation:    "5",
+			changeCauseAnnotation: "update image to v5",
+			"owner":               "team-a",
+		},
+	}}
+	newTarget := func(annotations map[string]string) *appsv1.ReplicaSet {
+		rs := testReplicaSet("web-3", 3, annotations)
+		rs.Spec.Template = corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{
+				"app":                                  "web",
+				appsv1.DefaultDeploymentUniqueLabelKey: "abc123",
+			}},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "web", Image: "web:v3"}}},
+		}
+		return &rs
+	}
+	tests := []struct {
+		name      string
+		target    *appsv1.ReplicaSet
+		wantCause string
+	}{
+		{name: "使用目标版本的变更原因", target: newTarget(map[string]string{changeCauseAnnotation: "update image to v3"}), wantCause: "update image to v3"},
+		{name: "目标版本没有变更原因时去掉", target: newTarget(nil), wantCause: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, err := rollbackPatch(deployment, tt.target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ops []struct {
+				Op    string          `json:"op"`
+				Path  string          `json:"path"`
+				Value json.RawMessage `json:"value"`
+			}
+			if err = json.Unmarshal(content, &ops); err != nil {
+				t.Fatal(err)
+			}
+			if len(ops) != 2 || ops[0].Path != "/spec/template" || ops[1].Path != "/metadata/annotations" {
+				t.Fatalf("rollbackPatch() = %s", content)
+			}
+			template := corev1.PodTemplateSpec{}
+			if err = json.Unmarshal(ops[0].Value, &template); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := template.Labels[appsv1.DefaultDeploymentUniqueLabelKey]; ok {
+				t.Error("rollbackPatch() 模板中保留了pod-template-hash标签")
+			}
+			if template.Labels["app"] != "web" || template.Spec.Containers[0].Image != "web:v3" {
+				t.Errorf("rollbackPatch() template = %+v", template)
+			}
+			annotations := map[string]string{}
+			if err = json.Unmarshal(ops[1].Value, &annotations); err != nil {
+				t.Fatal(err)
+			}
+			if annotations[changeCauseAnnotation] != tt.wantCause {
+				t.Errorf("rollbackPatch() change-cause = %q, want %q", annotations[changeCauseAnnotation], tt.wantCause)
+			}
+			if annotations["owner"] != "team-a" || annotations[revisionAnnotation] != "5" {
+				t.Errorf("rollbackPatch() 应保留deployment的其他注解，got %v", annotations)
+			}
+			//不修改传入的对象
+			if tt.target.Spec.Template.Labels[appsv1.DefaultDeploymentUniqueLabelKey] != "abc123" {
+				t.Error("rollbackPatch() modified target")
+			}
+		})
+	}
+}
@@ -2,109 +2,39 @@ package service
 
 import (
 	"context"
-	"errors"
-	"github.com/wonderivan/logger"
 	appsv1 "k8s.io/api/apps/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/client-go/kubernetes"
 )
 
-var StatefulSet statefulSet
+// statefulset，列表、详情、删除、更新、创建由通用资源Resource提供
+var StatefulSet = statefulSet{newResource[appsv1.StatefulSet]("statefulset", appsv1.SchemeGroupVersion.WithResource("statefulsets"), true,
+	func(clientset kubernetes.Interface, namespace string) typedClient[*appsv1.StatefulSet] {
+		return clientset.AppsV1().StatefulSets(namespace)
+	}).withStatus(statefulSetStatus).
+	withProperty("ready", statefulSetReady)}
 
-type statefulSet struct{}
-
-// 定义statefulSets的返回内容 items是statefulSet列表吗total为statefulSet元素总数
-type StatefulSetsResp struct {
-	Item  []appsv1.StatefulSet `json:"items"`
-	Total int                  `json:"total"`
-}
-
-// 获取statefulSet列表
-func (p *statefulSet) GetStatefulSets(filterName, namespace string, limit, page int) (statefulSetsResp *StatefulSetsResp, err error) {
-	//通过clientset获取statefulSets完整列表
-	statefulSetList, err := K8s.ClientSet.AppsV1().StatefulSets(namespace).List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		logger.Error("获取statefulSet列表失败", err)
-		return nil, errors.New("获取statefulSet列表失败" + err.Error())
-	}
-	//实例化DataSelector对象
-	selectableData := &DataSelector{
-		GenericDataList: p.toCells(statefulSetList.Items),
-		DataSelectQuery: &DataSelect{
-			FilterQuery: &Filter{filterName},
-			PaginateQuery: &Paginate{
-				Limit: limit,
-				Page:  page,
-			},
-		},
-	}
-	//先过滤
-	filtered := selectableData.Filter()
-	//再拿total
-	total := len(filtered.GenericDataList)
-	//在排序和分页
-	data := filtered.Sort().Paginate()
-	//再将datacell切片数据转成原生statefulSet切片
-	statefulSets := p.fromCells(data.GenericDataList)
-	//返回
-	return &StatefulSetsResp{
-		Item:  statefulSets,
-		Total: total,
-	}, nil
-}
-
-// 获取statefulSet详情
-func (p *statefulSet) GetStatefulSetDetail(statefulSetName, namespace string) (statefulSet *appsv1.StatefulSet, err error) {
-	statefulSet, err = K8s.ClientSet.AppsV1().StatefulSets(namespace).Get(context.TODO(), statefulSetName, metav1.GetOptions{})
-	if err != nil {
-		logger.Error("获取StatefulSet详情失败" + err.Error())
-		return nil, errors.New("获取StatefulSet详情失败" + err.Error())
-	}
-	return statefulSet, nil
+type statefulSet struct {
+	*Resource[appsv1.StatefulSet, *appsv1.StatefulSet]
 }
 
-// 删除statefulSet
-func (p *statefulSet) DeleteStatefulSet(statefulSetName, namespace string) (err error) {
-	err = K8s.ClientSet.AppsV1().StatefulSets(namespace).Delete(context.TODO(), statefulSetName, metav1.DeleteOptions{})
-	if err != nil {
-		logger.Error("删除StatefulSet失败" + err.Error())
-		return errors.New("获取StatefulSet详情失败" + err.Error())
-	}
-	return nil
+// 重启statefulset
+func (s *statefulSet) RestartStatefulSet(ctx context.Context, cluster, statefulSetName, namespace string) (err error) {
+	return s.restart(ctx, cluster, statefulSetName, namespace)
 }
 
-// 更新statefulSet
-func (p *statefulSet) UpdateStatefulSet(namespace, content string) (err error) {
-	//将content反序列化成为statefulSet对象
-	var statefulSet = &appsv1.StatefulSet{}
-	if err = json.Unmarshal([]byte(content), statefulSet); err != nil {
-		logger.Error("Content反序列化失败", err)
-		return errors.New("Content反序列化失败" + err.Error())
+// statefulset状态，就绪副本数达到期望副本数时为Available，否则为Unavailable
+func statefulSetStatus(obj *appsv1.StatefulSet) string {
+	replicas := int32(1)
+	if obj.Spec.Replicas != nil {
+		replicas = *obj.Spec.Replicas
 	}
-	//更新statefulSet
-	_, err = K8s.ClientSet.AppsV1().StatefulSets(namespace).Update(context.TODO(), statefulSet, metav1.UpdateOptions{})
-	if err != nil {
-		logger.Error("更新StatefulSet失败" + err.Error())
-		return errors.New("更新StatefulSet失败" + err.Error())
+	if obj.Status.ReadyReplicas >= replicas {
+		return "Available"
 	}
-	return nil
+	return "Unavailable"
 }
 
-// 把statefulSetCell转成appsv1 statefulSet
-func (p *statefulSet) fromCells(cells []DataCell) []appsv1.StatefulSet {
-	statefulSets := make([]appsv1.StatefulSet, len(cells))
-	for i := range cells {
-		statefulSets[i] = appsv1.StatefulSet(cells[i].(statefulsetCell))
-	}
-	return statefulSets
-}
-
-// 把appsv1 statefulSets转成datacell
-func (p *statefulSet) toCells(std []appsv1.StatefulSet) []DataCell {
-	cells := make([]DataCell, len(std))
-	for i := range std {
-		cells[i] = statefulsetCell(std[i])
-
-	}
-	return cells
+// statefulset的就绪副本数
+func statefulSetReady(obj *appsv1.StatefulSet) ComparableValue {
+	return StdComparableInt(obj.Status.ReadyReplicas)
 }
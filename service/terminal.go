@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/websocket"
+	"github.com/wonderivan/logger"
+	"io"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/util/exec"
+	"strings"
+)
+
+var Terminal terminal
+
+type terminal struct{}
+
+// 进入容器时依次尝试的shell，前一个不存在时使用下一个
+var validShells = []string{"bash", "sh"}
+
+// 终端消息，浏览器与后端通过websocket传输json格式的消息
+// operation为stdin时data为用户输入，为resize时rows和cols为终端大小，为stdout时data为容器输出
+type TerminalMessage struct {
+	Operation string `json:"operation"`
+	Data      string `json:"data"`
+	Rows      uint16 `json:"rows"`
+	Cols      uint16 `json:"cols"`
+}
+
+// 终端会话，实现io.Reader、io.Writer和remotecommand.TerminalSizeQueue
+// 作为exec的stdin、stdout、stderr，把容器的输入输出桥接到websocket
+type TerminalSession struct {
+	wsConn   *websocket.Conn
+	sizeChan chan remotecommand.TerminalSize
+	doneChan chan struct{}
+	//上一条stdin消息超出读取缓冲区的部分，下次读取时先返回
+	pending []byte
+}
+
+// 基于websocket连接创建终端会话
+func NewTerminalSession(conn *websocket.Conn) *TerminalSession {
+	return &TerminalSession{
+		wsConn:   conn,
+		sizeChan: make(chan remotecommand.TerminalSize),
+		doneChan: make(chan struct{}),
+	}
+}
+
+// 读取浏览器发来的消息，stdin写入容器，resize放入终端大小队列
+func (t *TerminalSession) Read(p []byte) (int, error) {
+	if len(t.pending) > 0 {
+		n := copy(p, t.pending)
+		t.pending = t.pending[n:]
+		return n, nil
+	}
+	_, message, err := t.wsConn.ReadMessage()
+	if err != nil {
+		logger.Error("读取websocket消息失败", err)
+		return 0, err
+	}
+	var msg TerminalMessage
+	if err := json.Unmarshal(message, &msg); err != nil {
+		logger.Error("终端消息反序列化失败", err)
+		return 0, err
+	}
+	switch msg.Operation {
+	case "stdin":
+		n := copy(p, msg.Data)
+		t.pending = []byte(msg.Data[n:])
+		return n, nil
+	case "resize":
+		select {
+		case t.sizeChan <- remotecommand.TerminalSize{Width: msg.Cols, Height: msg.Rows}:
+		case <-t.doneChan:
+		}
+		return 0, nil
+	default:
+		logger.Error("未知的终端消息类型" + msg.Operation)
+		return 0, errors.New("未知的终端消息类型" + msg.Operation)
+	}
+}
+
+// 把容器的输出以stdout消息写回浏览器
+func (t *TerminalSession) Write(p []byte) (int, error) {
+	msg, err := json.Marshal(TerminalMessage{
+		Operation: "stdout",
+		Data:      string(p),
+	})
+	if err != nil {
+		return 0, err
+	}
+	if err := t.wsConn.WriteMessage(websocket.TextMessage, msg); err != nil {
+		logger.Error("写入websocket消息失败", err)
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// 返回下一次终端大小变化，会话关闭后返回nil结束队列
+func (t *TerminalSession) Next() *remotecommand.TerminalSize {
+	select {
+	case size := <-t.sizeChan:
+		return &size
+	case <-t.doneChan:
+		return nil
+	}
+}
+
+// 关闭会话和websocket连接
+func (t *TerminalSession) Close() error {
+	close(t.doneChan)
+	return t.wsConn.Close()
+}
+
+// 进入容器终端，按validShells的顺序选择可用的shell
+// 先以非tty方式确认shell可以执行，只对选中的shell建立交互会话，避免多次读取websocket丢失用户输入
+func (t *terminal) Exec(ctx context.Context, cluster, podName, containerName, namespace string, session *TerminalSession) (err error) {
+	for _, shell := range validShells {
+		err = t.probeShell(ctx, cluster, podName, containerName, namespace, shell)
+		if err == nil {
+			return t.startProcess(ctx, cluster, podName, containerName, namespace, []string{shell}, session)
+		}
+		//shell不存在时尝试下一个，其他情况直接返回
+		if !isShellNotFound(err) {
+			return err
+		}
+		logger.Info("容器中不存在" + shell + "，尝试下一个shell")
+	}
+	return err
+}
+
+// 在容器中执行shell -c "exit 0"，确认shell存在且可以执行
+func (t *terminal) probeShell(ctx context.Context, cluster, podName, containerName, namespace, shell string) error {
+	executor, err := t.executor(ctx, cluster, podName, containerName, namespace, []string{shell, "-c", "exit 0"}, false)
+	if err != nil {
+		return err
+	}
+	return executor.Stream(remotecommand.StreamOptions{
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+	})
+}
+
+// 通过SPDY执行器在容器中启动命令，并把会话作为tty的输入输出
+func (t *terminal) startProcess(ctx context.Context, cluster, podName, containerName, namespace string, cmd []string, session *TerminalSession) error {
+	executor, err := t.executor(ctx, cluster, podName, containerName, namespace, cmd, true)
+	if err != nil {
+		return err
+	}
+	//tty模式下stderr合并到stdout
+	return executor.Stream(remotecommand.StreamOptions{
+		Stdin:             session,
+		Stdout:            session,
+		Stderr:            session,
+		Tty:               true,
+		TerminalSizeQueue: session,
+	})
+}
+
+// 创建在容器中执行命令的SPDY执行器，tty为true时同时打开stdin
+func (t *terminal) executor(ctx context.Context, cluster, podName, containerName, namespace string, cmd []string, tty bool) (remotecommand.Executor, error) {
+	client, err := K8s.GetClient(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	restConf, err := K8s.GetRestConfig(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	req := client.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(podName).
+		Namespace(namespace).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: containerName,
+			Command:   cmd,
+			Stdin:     tty,
+			Stdout:    true,
+			Stderr:    true,
+			TTY:       tty,
+		}, scheme.ParameterCodec)
+	executor, err := remotecommand.NewSPDYExecutor(restConf, "POST", req.URL())
+	if err != nil {
+		logger.Error("创建SPDY执行器失败", err)
+		return nil, errors.New("创建SPDY执行器失败" + err.Error())
+	}
+	return executor, nil
+}
+
+// 判断exec失败是否因为shell不存在，126和127分别对应命令不可执行和命令不存在
+func isShellNotFound(err error) bool {
+	var exitErr exec.CodeExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code == 126 || exitErr.Code == 127
+	}
+	return strings.Contains(err.Error(), "executable file not found") ||
+		strings.Contains(err.Error(), "no such file or directory")
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTerminalSessionRead(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for _, data := range []string{"hello world", "ls"} {
+			msg, _ := json.Marshal(TerminalMessage{Operation: "stdin", Data: data})
+			conn.WriteMessage(websocket.TextMessage, msg)
+		}
+		//等待客户端关闭
+		conn.ReadMessage()
+	}))
+	defer server.Close()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := NewTerminalSession(conn)
+	defer session.Close()
+	//缓冲区小于消息时剩余部分在后续读取中返回，不丢失输入
+	var got []string
+	buf := make([]byte, 4)
+	for len(strings.Join(got, "")) < len("hello worldls") {
+		n, err := session.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, string(buf[:n]))
+	}
+	want := []string{"hell", "o wo", "rld", "ls"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Read() = %q, want %q", got, want)
+	}
+}